@@ -0,0 +1,182 @@
+// Package authlog 通过有界队列异步写入认证日志，数据库变慢时丢弃新日志而不是拖慢 RADIUS 响应。
+package authlog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/tracing"
+)
+
+// 每次写入最多合并的日志条数
+const maxBatch = 200
+
+// entry 记录入队时所在的 span，写入时作为链接，便于从请求追踪到对应的批量写入
+type entry struct {
+	log  *models.AuthLog
+	link trace.Link
+}
+
+var (
+	// mu 保护队列的关闭，Record 持读锁入队，Stop 持写锁关闭
+	mu      sync.RWMutex
+	queue   chan entry
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Int64
+	failed  atomic.Int64
+)
+
+func init() {
+	metrics.NewGaugeFunc("radius_manager_auth_log_queue_depth", "Auth log entries waiting to be written.", func() []metrics.Sample {
+		return metrics.Value(float64(len(queue)))
+	})
+	metrics.NewGaugeFunc("radius_manager_auth_log_queue_capacity", "Capacity of the auth log write queue.", func() []metrics.Sample {
+		return metrics.Value(float64(cap(queue)))
+	})
+	metrics.NewCounterFunc("radius_manager_auth_log_dropped_total", "Auth log entries dropped because the queue was full.", func() []metrics.Sample {
+		return metrics.Value(float64(dropped.Load()))
+	})
+	metrics.NewCounterFunc("radius_manager_auth_log_write_failures_total", "Auth log entries lost because the database write failed.", func() []metrics.Sample {
+		return metrics.Value(float64(failed.Load()))
+	})
+}
+
+// QueueStats 队列当前状态，dropped 与 failed 为启动以来的累计值
+type QueueStats struct {
+	Started  bool  `json:"started"`
+	Depth    int   `json:"depth"`
+	Capacity int   `json:"capacity"`
+	Dropped  int64 `json:"dropped"`
+	Failed   int64 `json:"failed"`
+}
+
+func Stats() QueueStats {
+	mu.RLock()
+	defer mu.RUnlock()
+	return QueueStats{
+		Started:  queue != nil && !closed,
+		Depth:    len(queue),
+		Capacity: cap(queue),
+		Dropped:  dropped.Load(),
+		Failed:   failed.Load(),
+	}
+}
+
+// Start 创建队列并启动写入协程，容量由 AUTH_LOG_QUEUE_SIZE 决定
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	queue = make(chan entry, config.AppConfig.AuthLogQueueSize)
+	closed = false
+	done = make(chan struct{})
+	go func(queue <-chan entry, done chan<- struct{}) {
+		defer close(done)
+		write(queue)
+	}(queue, done)
+}
+
+// Stop 关闭队列并等待已入队的日志全部写入；之后的 Record 改为直接写入，退出期间的日志不会丢失
+func Stop(ctx context.Context) error {
+	mu.Lock()
+	if queue == nil || closed {
+		mu.Unlock()
+		return nil
+	}
+	closed = true
+	close(queue)
+	mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d auth logs not written: %w", len(queue), ctx.Err())
+	}
+}
+
+// Record 不阻塞地加入队列，队列已满时丢弃并计数；队列未启动（如命令行工具）或已关闭时直接写入
+func Record(ctx context.Context, authLog *models.AuthLog) {
+	mu.RLock()
+	if queue != nil && !closed {
+		select {
+		case queue <- entry{log: authLog, link: trace.LinkFromContext(ctx)}:
+		default:
+			if dropped.Add(1)%1000 == 1 {
+				log.Printf("Auth log queue is full, %d entries dropped so far", dropped.Load())
+			}
+		}
+		mu.RUnlock()
+		return
+	}
+	mu.RUnlock()
+
+	if err := database.DAO.AuthLog.Create(ctx, authLog); err != nil {
+		log.Printf("Failed to write auth log: %v", err)
+	}
+}
+
+// write 取出队列中已有的日志合并为一次插入，按入队顺序写入以保持 ID 与时间大致同序
+func write(queue <-chan entry) {
+	batch := make([]*models.AuthLog, 0, maxBatch)
+	links := make([]trace.Link, 0, maxBatch)
+	for e := range queue {
+		batch = append(batch[:0], e.log)
+		links = append(links[:0], e.link)
+	fill:
+		for len(batch) < maxBatch {
+			select {
+			case next, ok := <-queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next.log)
+				links = append(links, next.link)
+			default:
+				break fill
+			}
+		}
+		flush(batch, links)
+	}
+}
+
+func flush(batch []*models.AuthLog, links []trace.Link) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var valid []trace.Link
+	for _, link := range links {
+		if link.SpanContext.IsValid() {
+			valid = append(valid, link)
+		}
+	}
+	if len(valid) > 0 {
+		var span trace.Span
+		ctx, span = tracing.Tracer().Start(ctx, "authlog.write",
+			trace.WithLinks(valid...),
+			trace.WithAttributes(attribute.Int("auth_log.batch_size", len(batch))),
+		)
+		defer span.End()
+	}
+
+	if err := database.DAO.AuthLog.CreateBatch(ctx, batch); err != nil {
+		failed.Add(int64(len(batch)))
+		log.Printf("Failed to write %d auth logs: %v", len(batch), err)
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
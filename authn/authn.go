@@ -0,0 +1,99 @@
+// Package authn 提供可插拔的密码认证后端，RADIUS 与 Web 登录按 AUTH_BACKENDS 配置的顺序依次尝试。
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/tracing"
+)
+
+var (
+	// ErrUnknownUser 后端不认识该用户，继续尝试下一个后端
+	ErrUnknownUser = errors.New("user not found")
+	// ErrInvalidCredentials 后端认识该用户但密码错误，或账号已被禁用，链路到此终止
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Backend 认证后端
+type Backend interface {
+	Name() string
+	// Authenticate 成功时返回本地用户记录（外部目录用户会即时创建影子记录）
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+}
+
+var chain = []Backend{&LocalBackend{}}
+
+// Init 按配置组装认证后端链
+func Init() error {
+	backends := make([]Backend, 0, len(config.AppConfig.AuthBackends))
+	for _, name := range config.AppConfig.AuthBackends {
+		switch name {
+		case models.AuthSourceLocal:
+			backends = append(backends, &LocalBackend{})
+		case models.AuthSourceLDAP:
+			backend, err := NewLDAPBackend()
+			if err != nil {
+				return err
+			}
+			backends = append(backends, backend)
+		default:
+			return fmt.Errorf("unknown auth backend %q", name)
+		}
+	}
+	Use(backends...)
+	return nil
+}
+
+// Use 直接替换后端链，例如指向测试用的 LDAP 服务
+func Use(backends ...Backend) {
+	chain = backends
+}
+
+// Authenticate 依次尝试各后端，返回第一个明确的结果
+func Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	for _, backend := range chain {
+		user, err := authenticate(ctx, backend, username, password)
+		if errors.Is(err, ErrUnknownUser) {
+			continue
+		}
+		return user, err
+	}
+	return nil, ErrUnknownUser
+}
+
+// authenticate 每个后端一个 span，便于区分目录服务与本地哈希校验的耗时
+func authenticate(ctx context.Context, backend Backend, username, password string) (*models.User, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "authn."+backend.Name())
+	defer span.End()
+
+	user, err := backend.Authenticate(ctx, username, password)
+	outcome := "success"
+	switch {
+	case errors.Is(err, ErrUnknownUser):
+		outcome = "unknown_user"
+	case err != nil:
+		outcome = "failure"
+	}
+	span.SetAttributes(attribute.String("authn.outcome", outcome))
+	return user, err
+}
+
+// HasExternal 链路中是否包含外部目录后端，此时本地尚不存在的用户也可能认证成功
+func HasExternal() bool {
+	for _, backend := range chain {
+		if backend.Name() != models.AuthSourceLocal {
+			return true
+		}
+	}
+	return false
+}
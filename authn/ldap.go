@@ -0,0 +1,188 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// LDAPBackend 通过 LDAP 简单绑定校验密码，适用于 Active Directory 与 OpenLDAP。
+// 先用服务账号按 LDAP_USER_FILTER 查找用户 DN，再以用户 DN 和密码绑定。
+type LDAPBackend struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string
+	EmailAttribute     string
+	GroupAttribute     string
+	Roles              RoleMapping
+}
+
+// NewLDAPBackend 根据配置创建 LDAP 后端
+func NewLDAPBackend() (*LDAPBackend, error) {
+	cfg := config.AppConfig
+	if cfg.LDAPURL == "" || cfg.LDAPBaseDN == "" {
+		return nil, errors.New("LDAP_URL and LDAP_BASE_DN are required for the ldap auth backend")
+	}
+	if !strings.Contains(cfg.LDAPUserFilter, "%s") {
+		return nil, errors.New("LDAP_USER_FILTER must contain %s for the username")
+	}
+
+	mapping, err := ParseGroupMapping("LDAP_GROUP_MAPPING", cfg.LDAPGroupMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LDAPBackend{
+		URL:                cfg.LDAPURL,
+		StartTLS:           cfg.LDAPStartTLS,
+		InsecureSkipVerify: cfg.LDAPInsecureSkipVerify,
+		BindDN:             cfg.LDAPBindDN,
+		BindPassword:       cfg.LDAPBindPassword,
+		BaseDN:             cfg.LDAPBaseDN,
+		UserFilter:         cfg.LDAPUserFilter,
+		EmailAttribute:     cfg.LDAPEmailAttribute,
+		GroupAttribute:     cfg.LDAPGroupAttribute,
+		Roles: RoleMapping{
+			AdminGroups:  cfg.LDAPAdminGroups,
+			GroupMapping: mapping,
+			Match:        matchDirectoryGroup,
+		},
+	}, nil
+}
+
+func (b *LDAPBackend) Name() string {
+	return models.AuthSourceLDAP
+}
+
+type directoryEntry struct {
+	email  string
+	groups []string
+}
+
+func (b *LDAPBackend) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	existing, err := LookupShadow(ctx, models.AuthSourceLDAP, username)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := b.bind(ctx, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return Provision(ctx, existing, ExternalIdentity{
+		Source:   models.AuthSourceLDAP,
+		Username: username,
+		Email:    entry.email,
+		Groups:   entry.groups,
+	}, b.Roles)
+}
+
+func (b *LDAPBackend) dial(ctx context.Context) (*ldap.Conn, error) {
+	var opts []ldap.DialOpt
+	tlsConfig := &tls.Config{InsecureSkipVerify: b.InsecureSkipVerify}
+	if u, err := url.Parse(b.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+	if strings.HasPrefix(strings.ToLower(b.URL), "ldaps://") {
+		opts = append(opts, ldap.DialWithTLSConfig(tlsConfig))
+	}
+
+	conn, err := ldap.DialURL(b.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetTimeout(time.Until(deadline))
+	} else {
+		conn.SetTimeout(config.AppConfig.LDAPTimeout)
+	}
+
+	if b.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// bind 查找用户并以其身份绑定，目录中不存在该用户时返回 ErrUnknownUser
+func (b *LDAPBackend) bind(ctx context.Context, username, password string) (*directoryEntry, error) {
+	conn, err := b.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ldap connect: %w", err)
+	}
+	defer conn.Close()
+
+	if b.BindDN != "" {
+		err = conn.Bind(b.BindDN, b.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap service bind: %w", err)
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		b.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(b.UserFilter, ldap.EscapeFilter(username)),
+		[]string{b.EmailAttribute, b.GroupAttribute},
+		nil,
+	))
+	// 只取 2 条即可判断是否唯一，匹配更多时服务器返回 sizeLimitExceeded，同样按多条匹配处理
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		log.Printf("LDAP filter matched more than 2 entries for %s, refusing ambiguous login", username)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap search: %w", err)
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrUnknownUser
+	}
+	if len(result.Entries) > 1 {
+		log.Printf("LDAP filter matched %d entries for %s, refusing ambiguous login", len(result.Entries), username)
+		return nil, ErrInvalidCredentials
+	}
+
+	found := result.Entries[0]
+	if err := conn.Bind(found.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind: %w", err)
+	}
+
+	return &directoryEntry{
+		email:  found.GetAttributeValue(b.EmailAttribute),
+		groups: found.GetAttributeValues(b.GroupAttribute),
+	}, nil
+}
+
+// matchDirectoryGroup 配置项可写完整 DN 或仅写 CN，均不区分大小写
+func matchDirectoryGroup(group, wanted string) bool {
+	if strings.EqualFold(group, wanted) {
+		return true
+	}
+	dn, err := ldap.ParseDN(group)
+	if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+		return false
+	}
+	return strings.EqualFold(dn.RDNs[0].Attributes[0].Value, wanted)
+}
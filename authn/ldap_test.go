@@ -0,0 +1,207 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// fakeEntry 测试目录中的一条记录
+type fakeEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// fakeDirectory 进程内的最小 LDAP 服务器，只实现简单绑定与搜索。
+// 搜索按过滤器的字符串形式匹配，并像真实服务器一样遵守 sizeLimit
+type fakeDirectory struct {
+	entries  []fakeEntry
+	byFilter map[string][]int
+}
+
+func (d *fakeDirectory) serve(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go d.handle(conn)
+		}
+	}()
+	return "ldap://" + ln.Addr().String()
+}
+
+func (d *fakeDirectory) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			name := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if name == "" && password == "" {
+				code = ldap.LDAPResultSuccess
+			}
+			for _, entry := range d.entries {
+				if entry.dn == name && entry.password == password {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			d.write(conn, id, result(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			sizeLimit := int(op.Children[3].Value.(int64))
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				d.write(conn, id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError))
+				continue
+			}
+			code := uint16(ldap.LDAPResultSuccess)
+			for i, idx := range d.byFilter[filter] {
+				if sizeLimit > 0 && i >= sizeLimit {
+					code = ldap.LDAPResultSizeLimitExceeded
+					break
+				}
+				d.write(conn, id, searchEntry(d.entries[idx]))
+			}
+			d.write(conn, id, result(ldap.ApplicationSearchResultDone, code))
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (d *fakeDirectory) write(conn net.Conn, id int64, op *ber.Packet) {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	envelope.AppendChild(op)
+	conn.Write(envelope.Bytes())
+}
+
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return op
+}
+
+func searchEntry(entry fakeEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attr.AppendChild(vals)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+func newFakeLDAPBackend(t *testing.T) *LDAPBackend {
+	person := func(uid string) fakeEntry {
+		return fakeEntry{
+			dn:       "uid=" + uid + ",ou=people,dc=example,dc=com",
+			password: uid + "-secret",
+			attrs: map[string][]string{
+				"mail":     {uid + "@example.com"},
+				"memberOf": {"cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		}
+	}
+	dir := &fakeDirectory{
+		entries: []fakeEntry{
+			{dn: "cn=reader,dc=example,dc=com", password: "reader-secret"},
+			person("alice"),
+			person("dup1"), person("dup2"),
+			person("many1"), person("many2"), person("many3"),
+		},
+		byFilter: map[string][]int{
+			"(uid=alice)": {1},
+			"(uid=dup)":   {2, 3},
+			"(uid=many)":  {4, 5, 6},
+		},
+	}
+	return &LDAPBackend{
+		URL:            dir.serve(t),
+		BindDN:         "cn=reader,dc=example,dc=com",
+		BindPassword:   "reader-secret",
+		BaseDN:         "dc=example,dc=com",
+		UserFilter:     "(uid=%s)",
+		EmailAttribute: "mail",
+		GroupAttribute: "memberOf",
+	}
+}
+
+func TestLDAPBind(t *testing.T) {
+	backend := newFakeLDAPBackend(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	entry, err := backend.bind(ctx, "alice", "alice-secret")
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if entry.email != "alice@example.com" {
+		t.Errorf("email = %q, want alice@example.com", entry.email)
+	}
+	if len(entry.groups) != 1 || !matchDirectoryGroup(entry.groups[0], "staff") {
+		t.Errorf("groups = %v, want the staff group", entry.groups)
+	}
+
+	cases := []struct {
+		name     string
+		username string
+		password string
+		want     error
+	}{
+		{"wrong password", "alice", "wrong", ErrInvalidCredentials},
+		{"unknown user", "bob", "bob-secret", ErrUnknownUser},
+		{"two matches", "dup", "dup1-secret", ErrInvalidCredentials},
+		{"size limit exceeded", "many", "many1-secret", ErrInvalidCredentials},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := backend.bind(ctx, tc.username, tc.password); !errors.Is(err, tc.want) {
+				t.Fatalf("bind(%q) error = %v, want %v", tc.username, err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLDAPServiceBindFailure(t *testing.T) {
+	backend := newFakeLDAPBackend(t)
+	backend.BindPassword = "wrong"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := backend.bind(ctx, "alice", "alice-secret")
+	if err == nil || errors.Is(err, ErrInvalidCredentials) || !strings.Contains(err.Error(), "ldap service bind") {
+		t.Fatalf("bind error = %v, want a service bind error", err)
+	}
+}
@@ -0,0 +1,43 @@
+package authn
+
+import (
+	"context"
+	"log"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/tracing"
+)
+
+// LocalBackend 使用本地数据库中的密码哈希认证
+type LocalBackend struct{}
+
+func (b *LocalBackend) Name() string {
+	return models.AuthSourceLocal
+}
+
+func (b *LocalBackend) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := database.DAO.User.GetByUsernameForAuth(ctx, username)
+	if err != nil {
+		return nil, ErrUnknownUser
+	}
+	// 外部目录的影子账号交给对应后端
+	if !user.IsLocal() {
+		return nil, ErrUnknownUser
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "passhash.verify")
+	ok := user.CheckPassword(password)
+	span.End()
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	// 哈希方案升级后写回数据库，写回失败不影响本次认证
+	if user.PasswordRehashed() {
+		if err := database.DAO.User.UpdatePassword(ctx, user.ID, user.Password, user.Salt); err != nil {
+			log.Printf("Failed to persist rehashed password for %s: %v", user.Username, err)
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,125 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// ExternalIdentity 外部身份源（LDAP、OIDC 等）认证成功后返回的用户信息
+type ExternalIdentity struct {
+	Source   string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// RoleMapping 外部组到管理员身份与本地组的映射
+type RoleMapping struct {
+	// AdminGroups 非空时每次登录都按外部组同步管理员身份
+	AdminGroups []string
+	// GroupMapping 外部组到本地组，本地组成员关系在每次登录时同步
+	GroupMapping map[string]string
+	// Match 判断外部组是否命中配置项，为空时不区分大小写全等比较
+	Match func(group, wanted string) bool
+}
+
+func (m RoleMapping) memberOfAny(groups, wanted []string) bool {
+	match := m.Match
+	if match == nil {
+		match = strings.EqualFold
+	}
+
+	for _, group := range groups {
+		for _, w := range wanted {
+			if match(group, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseGroupMapping 解析 "<外部组>:<本地组>" 列表，外部组可能含有 ":" 以外的任意字符，因此按最后一个 ":" 分隔
+func ParseGroupMapping(setting string, entries []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 || idx == len(entry)-1 {
+			return nil, fmt.Errorf("invalid %s entry %q, expected <external group>:<local group>", setting, entry)
+		}
+		mapping[entry[:idx]] = entry[idx+1:]
+	}
+	return mapping, nil
+}
+
+// LookupShadow 查找外部身份对应的本地影子账号，不存在时返回 nil。
+// 同名的本地账号或其他来源的账号不会被接管，返回 ErrUnknownUser；已禁用的影子账号返回 ErrInvalidCredentials。
+func LookupShadow(ctx context.Context, source, username string) (*models.User, error) {
+	user, err := database.DAO.User.GetByUsername(ctx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.AuthSource != source {
+		return nil, ErrUnknownUser
+	}
+	if user.Banned || user.Status != models.UserStatusActive || user.IsExpired() {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Provision 即时创建或更新影子账号，并同步管理员身份与映射组；user 为 LookupShadow 的结果
+func Provision(ctx context.Context, user *models.User, identity ExternalIdentity, roles RoleMapping) (*models.User, error) {
+	email := identity.Email
+	if email == "" {
+		// Email 列唯一，外部身份没有邮箱时使用不可投递的占位地址
+		email = identity.Username + "@" + identity.Source + ".invalid"
+	}
+	syncAdmin := len(roles.AdminGroups) > 0
+	isAdmin := syncAdmin && roles.memberOfAny(identity.Groups, roles.AdminGroups)
+
+	if user == nil {
+		user = &models.User{
+			Username:   identity.Username,
+			Email:      email,
+			IsAdmin:    isAdmin,
+			AuthSource: identity.Source,
+		}
+		if err := database.DAO.User.Create(ctx, user); err != nil {
+			return nil, err
+		}
+	} else if user.Email != email || (syncAdmin && user.IsAdmin != isAdmin) {
+		user.Email = email
+		if syncAdmin {
+			user.IsAdmin = isAdmin
+		}
+		if err := database.DAO.User.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	managed := make([]string, 0, len(roles.GroupMapping))
+	var memberOf []string
+	for externalGroup, localGroup := range roles.GroupMapping {
+		managed = append(managed, localGroup)
+		if roles.memberOfAny(identity.Groups, []string{externalGroup}) {
+			memberOf = append(memberOf, localGroup)
+		}
+	}
+	if err := database.DAO.Group.SyncMembership(ctx, user.ID, managed, memberOf); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
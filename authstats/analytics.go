@@ -0,0 +1,369 @@
+package authstats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+const (
+	IntervalMinute = "minute"
+	IntervalHour   = "hour"
+	IntervalDay    = "day"
+)
+
+// 各粒度允许的最大查询范围，分钟粒度直接扫描原始日志
+var maxRange = map[string]time.Duration{
+	IntervalMinute: 24 * time.Hour,
+	IntervalHour:   90 * 24 * time.Hour,
+	IntervalDay:    3 * 366 * 24 * time.Hour,
+}
+
+// Query 统计范围为 [From, To)，时间桶按 Location 划分
+type Query struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Location *time.Location
+	Top      int
+}
+
+type Point struct {
+	Time    time.Time `json:"time"`
+	Success int64     `json:"success"`
+	Failure int64     `json:"failure"`
+}
+
+type Breakdown struct {
+	Key     string `json:"key"`
+	Success int64  `json:"success"`
+	Failure int64  `json:"failure"`
+}
+
+type Totals struct {
+	Success int64 `json:"success"`
+	Failure int64 `json:"failure"`
+	Total   int64 `json:"total"`
+	// SuccessRatio 没有认证记录时为 nil
+	SuccessRatio *float64 `json:"success_ratio"`
+}
+
+type Report struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Interval string    `json:"interval"`
+	Timezone string    `json:"timezone"`
+	// Source 数据来源：raw（原始日志）、hourly 或 daily（汇总）
+	Source          string                 `json:"source"`
+	Totals          Totals                 `json:"totals"`
+	Series          []Point                `json:"series"`
+	Breakdowns      map[string][]Breakdown `json:"breakdowns"`
+	TopFailedUsers  []dao.UserCount        `json:"top_failed_users"`
+	DistinctDevices int64                  `json:"distinct_devices"`
+	// DevicesFrom 设备数的统计起点，原始日志保留期短于查询范围时晚于 From
+	DevicesFrom time.Time `json:"devices_from"`
+}
+
+// Validate 检查粒度与范围，返回的错误可直接展示给调用方
+func (q *Query) Validate() error {
+	limit, ok := maxRange[q.Interval]
+	if !ok {
+		return errors.New("interval must be minute, hour or day")
+	}
+	if !q.From.Before(q.To) {
+		return errors.New("from must be before to")
+	}
+	if q.To.Sub(q.From) > limit {
+		return fmt.Errorf("range is too large for interval %s, at most %s", q.Interval, limit)
+	}
+	if q.Top < 1 || q.Top > 100 {
+		return errors.New("top must be between 1 and 100")
+	}
+	return nil
+}
+
+// bucket 返回 t 在 loc 时区下所属时间桶的起点
+func (q *Query) bucket(t time.Time) time.Time {
+	t = t.In(q.Location)
+	switch q.Interval {
+	case IntervalMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, q.Location)
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, q.Location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, q.Location)
+}
+
+func (q *Query) next(t time.Time) time.Time {
+	switch q.Interval {
+	case IntervalMinute:
+		return q.bucket(t.Add(time.Minute))
+	case IntervalHour:
+		return q.bucket(t.Add(time.Hour))
+	}
+	return q.bucket(t.AddDate(0, 0, 1))
+}
+
+// period 读取的汇总粒度：分钟粒度读原始日志，返回空；按天统计且超出小时汇总保留期时读按天汇总（按 UTC 日期划分）
+func (q *Query) period() string {
+	switch {
+	case q.Interval == IntervalMinute:
+		return ""
+	case q.Interval == IntervalDay && q.From.Before(time.Now().Add(-config.AppConfig.AuthStatsHourlyRetention)):
+		return models.RollupDay
+	}
+	return models.RollupHour
+}
+
+// align 把范围扩展到时间桶边界，读小时汇总时再扩展到 UTC 整点。
+// 序列、合计、分组、排行与设备数都使用对齐后的同一范围，首尾的桶不会只统计了一部分
+func (q *Query) align(period string) {
+	from, to := q.bucket(q.From), q.bucket(q.To)
+	if to.Before(q.To) {
+		to = q.next(to)
+	}
+	if period == models.RollupHour {
+		from = from.UTC().Truncate(time.Hour)
+		if end := to.UTC().Truncate(time.Hour); end.Before(to) {
+			to = end.Add(time.Hour)
+		}
+	}
+	q.From, q.To = from.In(q.Location), to.In(q.Location)
+}
+
+// devicesFrom 设备数只能从原始日志统计，起点不早于原始日志的保留期，并对齐到时间桶边界
+func (q *Query) devicesFrom(now time.Time) time.Time {
+	maxAge := config.AppConfig.AuthLogMaxAge
+	if maxAge <= 0 {
+		return q.From
+	}
+	cutoff := now.Add(-maxAge)
+	if !cutoff.After(q.From) {
+		return q.From
+	}
+	start := q.bucket(cutoff)
+	if start.Before(cutoff) {
+		start = q.next(start)
+	}
+	return start
+}
+
+var sourceNames = map[string]string{"": "raw", models.RollupHour: "hourly", models.RollupDay: "daily"}
+
+// Analyze 汇总表只按整点 UTC 小时划分，非整小时偏移的时区按所在 UTC 小时归入时间桶；
+// 尚未汇总的最新日志从原始日志补齐
+func Analyze(ctx context.Context, q Query) (*Report, error) {
+	period := q.period()
+	if period == models.RollupDay {
+		q.Location = time.UTC
+	}
+	q.align(period)
+	report := &Report{
+		From:       q.From,
+		To:         q.To,
+		Interval:   q.Interval,
+		Timezone:   q.Location.String(),
+		Source:     sourceNames[period],
+		Breakdowns: make(map[string][]Breakdown),
+	}
+
+	if period == "" {
+		return analyzeRaw(ctx, &q, report)
+	}
+
+	watermark, err := database.DAO.AuthStats.Watermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	points, err := database.DAO.AuthStats.Series(ctx, period, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := database.DAO.AuthLog.MinuteSeries(ctx, q.From, q.To, watermark)
+	if err != nil {
+		return nil, err
+	}
+	report.Series, report.Totals = fillSeries(&q, append(points, recent...))
+
+	for dimension := range dao.AnalyticsDimensions {
+		rows, err := database.DAO.AuthStats.Breakdown(ctx, period, dimension, q.From, q.To)
+		if err != nil {
+			return nil, err
+		}
+		recent, err := database.DAO.AuthLog.Breakdown(ctx, dimension, q.From, q.To, watermark)
+		if err != nil {
+			return nil, err
+		}
+		report.Breakdowns[dimension] = mergeBreakdown(append(rows, recent...))
+	}
+
+	if report.TopFailedUsers, err = topFailedUsers(ctx, &q, period, watermark); err != nil {
+		return nil, err
+	}
+	if err := countDevices(ctx, &q, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func analyzeRaw(ctx context.Context, q *Query, report *Report) (*Report, error) {
+	points, err := database.DAO.AuthLog.MinuteSeries(ctx, q.From, q.To, 0)
+	if err != nil {
+		return nil, err
+	}
+	report.Series, report.Totals = fillSeries(q, points)
+
+	for dimension := range dao.AnalyticsDimensions {
+		rows, err := database.DAO.AuthLog.Breakdown(ctx, dimension, q.From, q.To, 0)
+		if err != nil {
+			return nil, err
+		}
+		report.Breakdowns[dimension] = mergeBreakdown(rows)
+	}
+
+	users, err := database.DAO.AuthLog.TopFailures(ctx, q.From, q.To, 0, q.Top)
+	if err != nil {
+		return nil, err
+	}
+	report.TopFailedUsers = mergeTopUsers(users, q.Top)
+
+	if err := countDevices(ctx, q, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// topFailedUsers 合并汇总与尚未汇总的失败次数后取前 top 名。尚未汇总的部分只有最近几分钟，全部读取；
+// 汇总部分多取 len(recent) 名，排在其后的用户即使加上近期失败也进不了前 top 名；
+// 近期失败但不在汇总前列的用户另行查询其汇总次数
+func topFailedUsers(ctx context.Context, q *Query, period string, watermark uint) ([]dao.UserCount, error) {
+	recent, err := database.DAO.AuthLog.TopFailures(ctx, q.From, q.To, watermark, 0)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Top + len(recent)
+	rolled, err := database.DAO.AuthStats.TopFailures(ctx, period, q.From, q.To, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rolled) == limit {
+		listed := make(map[string]bool, len(rolled))
+		for _, u := range rolled {
+			listed[u.Username] = true
+		}
+		var missing []string
+		for _, u := range recent {
+			if !listed[u.Username] {
+				missing = append(missing, u.Username)
+			}
+		}
+		if len(missing) > 0 {
+			counts, err := database.DAO.AuthStats.FailureCounts(ctx, period, q.From, q.To, missing)
+			if err != nil {
+				return nil, err
+			}
+			rolled = append(rolled, counts...)
+		}
+	}
+	return mergeTopUsers(append(rolled, recent...), q.Top), nil
+}
+
+func countDevices(ctx context.Context, q *Query, report *Report) error {
+	report.DevicesFrom = q.devicesFrom(time.Now())
+	if !report.DevicesFrom.Before(q.To) {
+		return nil
+	}
+	var err error
+	report.DistinctDevices, err = database.DAO.AuthLog.DistinctDevices(ctx, report.DevicesFrom, q.To)
+	return err
+}
+
+// fillSeries 把数据点归入时间桶，没有数据的桶补零
+func fillSeries(q *Query, points []dao.SeriesPoint) ([]Point, Totals) {
+	var series []Point
+	index := make(map[time.Time]int)
+	for t := q.bucket(q.From); t.Before(q.To); t = q.next(t) {
+		index[t] = len(series)
+		series = append(series, Point{Time: t})
+	}
+
+	var totals Totals
+	for _, p := range points {
+		i, ok := index[q.bucket(p.Bucket)]
+		if !ok {
+			continue
+		}
+		if p.Success {
+			series[i].Success += p.Count
+			totals.Success += p.Count
+		} else {
+			series[i].Failure += p.Count
+			totals.Failure += p.Count
+		}
+	}
+
+	totals.Total = totals.Success + totals.Failure
+	if totals.Total > 0 {
+		ratio := float64(totals.Success) / float64(totals.Total)
+		totals.SuccessRatio = &ratio
+	}
+	return series, totals
+}
+
+// mergeBreakdown 合并同一维度值的成功与失败计数，按总次数降序
+func mergeBreakdown(rows []dao.BreakdownRow) []Breakdown {
+	index := make(map[string]int)
+	result := []Breakdown{}
+	for _, row := range rows {
+		i, ok := index[row.Key]
+		if !ok {
+			i = len(result)
+			index[row.Key] = i
+			result = append(result, Breakdown{Key: row.Key})
+		}
+		if row.Success {
+			result[i].Success += row.Count
+		} else {
+			result[i].Failure += row.Count
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ti, tj := result[i].Success+result[i].Failure, result[j].Success+result[j].Failure
+		if ti != tj {
+			return ti > tj
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// mergeTopUsers 合并各来源中同一用户的失败次数，取前 top 名
+func mergeTopUsers(lists []dao.UserCount, top int) []dao.UserCount {
+	counts := make(map[string]int64)
+	for _, u := range lists {
+		counts[u.Username] += u.Count
+	}
+	users := make([]dao.UserCount, 0, len(counts))
+	for username, count := range counts {
+		users = append(users, dao.UserCount{Username: username, Count: count})
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Count != users[j].Count {
+			return users[i].Count > users[j].Count
+		}
+		return users[i].Username < users[j].Username
+	})
+	if len(users) > top {
+		users = users[:top]
+	}
+	return users
+}
@@ -0,0 +1,104 @@
+package authstats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+func TestAlignExtendsToBucketBounds(t *testing.T) {
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	cases := []struct {
+		name     string
+		q        Query
+		period   string
+		from, to time.Time
+	}{
+		{"minute", Query{Interval: IntervalMinute, Location: time.UTC,
+			From: time.Date(2026, 3, 1, 10, 20, 30, 0, time.UTC), To: time.Date(2026, 3, 1, 10, 25, 0, 0, time.UTC)},
+			"", time.Date(2026, 3, 1, 10, 20, 0, 0, time.UTC), time.Date(2026, 3, 1, 10, 25, 0, 0, time.UTC)},
+		{"hour", Query{Interval: IntervalHour, Location: time.UTC,
+			From: time.Date(2026, 3, 1, 10, 20, 0, 0, time.UTC), To: time.Date(2026, 3, 1, 11, 10, 0, 0, time.UTC)},
+			models.RollupHour, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)},
+		// 半小时偏移的时区再扩展到 UTC 整点，与汇总桶一致
+		{"half-hour zone", Query{Interval: IntervalHour, Location: kolkata,
+			From: time.Date(2026, 3, 1, 10, 20, 0, 0, kolkata), To: time.Date(2026, 3, 1, 11, 0, 0, 0, kolkata)},
+			models.RollupHour, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		tc.q.align(tc.period)
+		if !tc.q.From.Equal(tc.from) || !tc.q.To.Equal(tc.to) {
+			t.Errorf("%s: aligned to [%s, %s), want [%s, %s)", tc.name, tc.q.From, tc.q.To, tc.from, tc.to)
+		}
+	}
+}
+
+func TestDevicesFromStaysWithinRetention(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	q := Query{Interval: IntervalHour, Location: time.UTC, From: now.Add(-72 * time.Hour), To: now}
+
+	config.AppConfig = &config.Config{}
+	if got := q.devicesFrom(now); !got.Equal(q.From) {
+		t.Errorf("without retention devicesFrom = %s, want %s", got, q.From)
+	}
+	config.AppConfig = &config.Config{AuthLogMaxAge: 24 * time.Hour}
+	if got, want := q.devicesFrom(now), time.Date(2026, 3, 9, 13, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("with 24h retention devicesFrom = %s, want %s", got, want)
+	}
+}
+
+func insertFailures(t *testing.T, counts map[string]int) {
+	t.Helper()
+	for username, n := range counts {
+		for i := 0; i < n; i++ {
+			err := database.DB.Create(&models.AuthLog{
+				Username:  username,
+				AuthType:  "authenticate",
+				IPAddress: "10.0.0.1",
+				CreatedAt: time.Now().Add(-time.Hour),
+			}).Error
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// 只看各来源的前 top 名会漏掉汇总与近期失败都不在前列、合计却最多的用户
+func TestAnalyzeMergesTopUsersAcrossSources(t *testing.T) {
+	useTestDatabase(t)
+	config.AppConfig.AuthStatsHourlyRetention = 90 * 24 * time.Hour
+	ctx := context.Background()
+
+	insertFailures(t, map[string]int{"alice": 5, "bob": 4, "dave": 3, "carol": 2})
+	if err := Aggregate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	insertFailures(t, map[string]int{"carol": 4})
+
+	report, err := Analyze(ctx, Query{
+		From:     time.Now().Add(-3 * time.Hour),
+		To:       time.Now().Add(time.Minute),
+		Interval: IntervalHour,
+		Location: time.UTC,
+		Top:      1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.TopFailedUsers) != 1 || report.TopFailedUsers[0].Username != "carol" || report.TopFailedUsers[0].Count != 6 {
+		t.Fatalf("top failed users = %+v, want carol with 6", report.TopFailedUsers)
+	}
+
+	var byType int64
+	for _, b := range report.Breakdowns["auth_type"] {
+		byType += b.Success + b.Failure
+	}
+	if report.Totals.Total != 18 || byType != report.Totals.Total {
+		t.Fatalf("totals %d, auth_type breakdown %d, want both 18", report.Totals.Total, byType)
+	}
+}
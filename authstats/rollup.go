@@ -0,0 +1,187 @@
+// Package authstats 维护认证日志的小时 / 天汇总，统计接口读取汇总而不是扫描 auth_logs。
+package authstats
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// 每个事务汇总的日志条数
+const rollupBatchSize = 10000
+
+// 日志异步写入，较小的 ID 可能晚于较大的 ID 提交。进度只沿连续的 ID 推进，遇到缺失的 ID 时停下，
+// 等它提交后再继续；缺失超过 gapTimeout 的 ID 视为事务已回滚（或自增值被跳过），不再等待
+const gapTimeout = 5 * time.Minute
+
+// gap 一段缺失的 ID 及首次发现的时间。只记录在内存中，重启后重新计时
+type gap struct {
+	from, to uint
+	since    time.Time
+}
+
+var (
+	gapsMu sync.Mutex
+	gaps   []gap
+)
+
+// gapSince 返回 [from, to] 这段缺失 ID 首次被发现的时间。缺失的 ID 只会被补上，
+// 以 from 开头的缺口总落在之前发现的某个缺口之内
+func gapSince(from, to uint, now time.Time) time.Time {
+	for _, g := range gaps {
+		if g.from <= from && from <= g.to {
+			return g.since
+		}
+	}
+	gaps = append(gaps, gap{from: from, to: to, since: now})
+	return now
+}
+
+// forgetGaps 丢弃进度之前的缺口
+func forgetGaps(watermark uint) {
+	kept := gaps[:0]
+	for _, g := range gaps {
+		if g.to > watermark {
+			kept = append(kept, g)
+		}
+	}
+	gaps = kept
+}
+
+// settled 返回 logs 中可以计入汇总的前缀：从 watermark 起 ID 连续，或中间的缺口已超过 gapTimeout
+func settled(logs []models.AuthLog, watermark uint, now time.Time) []models.AuthLog {
+	prev := watermark
+	for i := range logs {
+		id := logs[i].ID
+		if id != prev+1 {
+			if now.Sub(gapSince(prev+1, id-1, now)) < gapTimeout {
+				return logs[:i]
+			}
+			log.Printf("Auth stats rollup: skipping log IDs %d-%d that were never committed", prev+1, id-1)
+		}
+		prev = id
+	}
+	return logs
+}
+
+type rollupKey struct {
+	period      string
+	bucketStart time.Time
+	username    string
+	ipAddress   string
+	targetSSID  string
+	authType    string
+	success     bool
+	reason      string
+}
+
+// Aggregate 把上次进度之后已提交的日志计入汇总，并清理过期的小时汇总。
+// 多个实例可以同时运行，进度以比较并交换的方式推进，落后的一方放弃本次汇总
+func Aggregate(ctx context.Context) error {
+	gapsMu.Lock()
+	defer gapsMu.Unlock()
+
+	watermark, err := database.DAO.AuthStats.Watermark(ctx)
+	if err != nil {
+		return err
+	}
+
+	processed := 0
+	for {
+		logs, err := database.DAO.AuthLog.ListAfter(ctx, watermark, rollupBatchSize)
+		if err != nil {
+			return err
+		}
+		batch := settled(logs, watermark, time.Now())
+		if len(batch) == 0 {
+			break
+		}
+
+		next := batch[len(batch)-1].ID
+		err = database.DAO.AuthStats.Apply(ctx, rollup(batch), watermark, next)
+		if errors.Is(err, dao.ErrWatermarkMoved) {
+			log.Printf("Auth stats rollup: watermark %d was advanced by another instance, skipping this run", watermark)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		watermark = next
+		processed += len(batch)
+
+		if len(batch) < rollupBatchSize {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	forgetGaps(watermark)
+	if processed > 0 {
+		log.Printf("Auth stats rollup: %d logs aggregated, watermark %d", processed, watermark)
+	}
+
+	if retain := config.AppConfig.AuthStatsHourlyRetention; retain > 0 {
+		if _, err := database.DAO.AuthStats.PruneHourly(ctx, time.Now().Add(-retain)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollup 把一批日志按维度合并为小时与天两种粒度的计数
+func rollup(logs []models.AuthLog) []models.AuthLogRollup {
+	counts := make(map[rollupKey]int64)
+	for i := range logs {
+		l := &logs[i]
+		at := l.CreatedAt.UTC()
+		key := rollupKey{
+			username:   truncate(l.Username, 191),
+			ipAddress:  truncate(l.IPAddress, 64),
+			targetSSID: truncate(l.TargetSSID, 128),
+			authType:   truncate(l.AuthType, 16),
+			success:    l.Success,
+			reason:     l.Reason,
+		}
+
+		key.period, key.bucketStart = models.RollupHour, at.Truncate(time.Hour)
+		counts[key]++
+		key.period, key.bucketStart = models.RollupDay, time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		counts[key]++
+	}
+
+	rollups := make([]models.AuthLogRollup, 0, len(counts))
+	for key, count := range counts {
+		rollups = append(rollups, models.AuthLogRollup{
+			Period:      key.period,
+			BucketStart: key.bucketStart,
+			Username:    key.username,
+			IPAddress:   key.ipAddress,
+			TargetSSID:  key.targetSSID,
+			AuthType:    key.authType,
+			Success:     key.success,
+			Reason:      key.reason,
+			Count:       count,
+		})
+	}
+	return rollups
+}
+
+// truncate 按汇总表的列宽截断，超长的值合并到同一行；按字节截断并退回到完整字符处
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,107 @@
+package authstats
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+func useTestDatabase(t *testing.T) {
+	t.Helper()
+	config.AppConfig = &config.Config{}
+	dbtest.Open(t)
+	gaps = nil
+}
+
+func insertLogs(t *testing.T, ids ...uint) {
+	t.Helper()
+	for _, id := range ids {
+		err := database.DB.Create(&models.AuthLog{
+			ID:        id,
+			Username:  "alice",
+			AuthType:  "authenticate",
+			Success:   true,
+			CreatedAt: time.Now().Add(-time.Hour),
+		}).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func successCount(t *testing.T) (uint, int64) {
+	t.Helper()
+	watermark, err := database.DAO.AuthStats.Watermark(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rolled int64
+	err = database.DB.Model(&models.AuthLogRollup{}).
+		Where("period = ?", models.RollupDay).
+		Select("COALESCE(SUM(count), 0)").Scan(&rolled).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return watermark, rolled
+}
+
+// 较小的 ID 晚提交时不能被跳过
+func TestAggregateWaitsForLateCommits(t *testing.T) {
+	useTestDatabase(t)
+	ctx := context.Background()
+
+	insertLogs(t, 1, 2, 4)
+	if err := Aggregate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if watermark, rolled := successCount(t); watermark != 2 || rolled != 2 {
+		t.Fatalf("watermark %d with %d rolled up, want 2 and 2", watermark, rolled)
+	}
+
+	insertLogs(t, 3)
+	if err := Aggregate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if watermark, rolled := successCount(t); watermark != 4 || rolled != 4 {
+		t.Fatalf("watermark %d with %d rolled up, want 4 and 4", watermark, rolled)
+	}
+}
+
+func TestSettledSkipsExpiredGaps(t *testing.T) {
+	gaps = nil
+	logs := []models.AuthLog{{ID: 1}, {ID: 2}, {ID: 5}, {ID: 6}}
+	now := time.Now()
+
+	if got := settled(logs, 0, now); len(got) != 2 {
+		t.Fatalf("settled %d logs while IDs 3-4 are missing, want 2", len(got))
+	}
+	if got := settled(logs[2:], 2, now.Add(gapTimeout/2)); len(got) != 0 {
+		t.Fatalf("settled %d logs before the gap timed out, want 0", len(got))
+	}
+	if got := settled(logs[2:], 2, now.Add(gapTimeout)); len(got) != 2 {
+		t.Fatalf("settled %d logs after the gap timed out, want 2", len(got))
+	}
+}
+
+func TestApplyRejectsStaleWatermark(t *testing.T) {
+	useTestDatabase(t)
+	ctx := context.Background()
+
+	if err := database.DAO.AuthStats.Apply(ctx, nil, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	rollups := rollup([]models.AuthLog{{ID: 5, Username: "alice", Success: true, CreatedAt: time.Now()}})
+	if err := database.DAO.AuthStats.Apply(ctx, rollups, 0, 5); !errors.Is(err, dao.ErrWatermarkMoved) {
+		t.Fatalf("Apply with a stale watermark error = %v, want %v", err, dao.ErrWatermarkMoved)
+	}
+	if watermark, rolled := successCount(t); watermark != 10 || rolled != 0 {
+		t.Fatalf("watermark %d with %d rolled up, want 10 and 0", watermark, rolled)
+	}
+}
@@ -0,0 +1,158 @@
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// 每批从数据库读取的用户数，导出时内存占用与总用户数无关
+const exportBatchSize = 500
+
+var csvHeader = []string{"username", "email", "password_hash", "is_admin", "groups", "expires_at", "banned", "status", "auth_source", "created_at"}
+
+// exportRecord 在导入字段之外附带只读的状态信息，导入时这些字段被忽略
+type exportRecord struct {
+	jsonRecord
+	Banned     bool      `json:"banned"`
+	Status     string    `json:"status"`
+	AuthSource string    `json:"auth_source"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newExportRecord(u *models.User, includeHashes bool) exportRecord {
+	isAdmin := u.IsAdmin
+	groups := make([]string, 0, len(u.Groups))
+	for _, group := range u.Groups {
+		groups = append(groups, group.Name)
+	}
+	rec := exportRecord{
+		jsonRecord: jsonRecord{
+			Username: u.Username,
+			Email:    u.Email,
+			IsAdmin:  &isAdmin,
+			Groups:   &groups,
+		},
+		Banned:     u.Banned,
+		Status:     u.Status,
+		AuthSource: u.AuthSource,
+		CreatedAt:  u.CreatedAt,
+	}
+	if includeHashes && u.IsLocal() {
+		rec.PasswordHash = u.PasswordHash()
+	}
+	if u.ExpiresAt != nil {
+		expiresAt := u.ExpiresAt.Format(time.RFC3339)
+		rec.ExpiresAt = &expiresAt
+	}
+	return rec
+}
+
+// Export 分批读取全部用户并写入 w，输出可直接再次导入；includeHashes 为 true 时附带本地账号的密码哈希
+func Export(ctx context.Context, w io.Writer, format string, includeHashes bool) error {
+	buffered := bufio.NewWriter(w)
+	var err error
+	if format == FormatJSON {
+		err = exportJSON(ctx, buffered, includeHashes)
+	} else {
+		err = exportCSV(ctx, buffered, includeHashes)
+	}
+	if err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func eachBatch(ctx context.Context, fn func(users []models.User) error) error {
+	var afterID uint
+	for {
+		users, err := database.DAO.User.ListAfter(ctx, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+		if err := fn(users); err != nil {
+			return err
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
+func exportCSV(ctx context.Context, w *bufio.Writer, includeHashes bool) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	err := eachBatch(ctx, func(users []models.User) error {
+		for i := range users {
+			rec := newExportRecord(&users[i], includeHashes)
+			expiresAt := ""
+			if rec.ExpiresAt != nil {
+				expiresAt = *rec.ExpiresAt
+			}
+			if err := writer.Write([]string{
+				rec.Username,
+				rec.Email,
+				rec.PasswordHash,
+				strconv.FormatBool(*rec.IsAdmin),
+				strings.Join(*rec.Groups, ";"),
+				expiresAt,
+				strconv.FormatBool(rec.Banned),
+				rec.Status,
+				rec.AuthSource,
+				rec.CreatedAt.Format(time.RFC3339),
+			}); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportJSON(ctx context.Context, w *bufio.Writer, includeHashes bool) error {
+	if _, err := w.WriteString("["); err != nil {
+		return err
+	}
+
+	first := true
+	err := eachBatch(ctx, func(users []models.User) error {
+		for i := range users {
+			data, err := json.Marshal(newExportRecord(&users[i], includeHashes))
+			if err != nil {
+				return err
+			}
+			if !first {
+				w.WriteString(",")
+			}
+			first = false
+			w.WriteString("\n")
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.WriteString("\n]\n")
+	return err
+}
@@ -0,0 +1,249 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passhash"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+)
+
+// maxGroupNameLength 与 models.Group.Name 的列宽一致，超长的组名在 MySQL 与 PostgreSQL 上会写入失败
+const maxGroupNameLength = 100
+
+type Options struct {
+	// DryRun 只校验并报告结果，不写入数据库
+	DryRun bool
+	// Upsert 用户名已存在时更新该用户，否则该行报错
+	Upsert bool
+}
+
+type RowError struct {
+	Row      int    `json:"row"`
+	Username string `json:"username,omitempty"`
+	Error    string `json:"error"`
+}
+
+type Report struct {
+	DryRun  bool       `json:"dry_run"`
+	Total   int        `json:"total"`
+	Created int        `json:"created"`
+	Updated int        `json:"updated"`
+	Failed  int        `json:"failed"`
+	Errors  []RowError `json:"errors"`
+}
+
+// Summary 写入审计日志的摘要
+func (r *Report) Summary() string {
+	return fmt.Sprintf("dry_run=%t, total=%d, created=%d, updated=%d, failed=%d", r.DryRun, r.Total, r.Created, r.Updated, r.Failed)
+}
+
+// rowError 可以报告给调用方的单行错误，其余错误（如数据库故障）会终止导入
+type rowError struct {
+	msg string
+}
+
+func (e *rowError) Error() string {
+	return e.msg
+}
+
+func rowErrorf(format string, args ...interface{}) error {
+	return &rowError{msg: fmt.Sprintf(format, args...)}
+}
+
+// Import 逐行导入，单行错误记入报告后继续；progress 在每行处理完后调用，可为 nil
+func Import(ctx context.Context, records []Record, opts Options, progress func(done int)) (*Report, error) {
+	report := &Report{DryRun: opts.DryRun, Total: len(records), Errors: []RowError{}}
+	// 文件内的重复用户名与邮箱，数据库唯一索引不区分大小写
+	seenUsernames := make(map[string]int, len(records))
+	seenEmails := make(map[string]int, len(records))
+
+	for i := range records {
+		rec := &records[i]
+		created, err := importRecord(ctx, rec, opts, seenUsernames, seenEmails)
+
+		var rowErr *rowError
+		switch {
+		case errors.As(err, &rowErr):
+			report.Failed++
+			report.Errors = append(report.Errors, RowError{Row: rec.Row, Username: rec.Username, Error: rowErr.msg})
+		case err != nil:
+			return report, fmt.Errorf("row %d: %w", rec.Row, err)
+		case created:
+			report.Created++
+		default:
+			report.Updated++
+		}
+
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+	return report, nil
+}
+
+func importRecord(ctx context.Context, rec *Record, opts Options, seenUsernames, seenEmails map[string]int) (created bool, err error) {
+	if rec.err != nil {
+		return false, rowErrorf("%v", rec.err)
+	}
+	if len(rec.Username) < 3 || len(rec.Username) > 50 {
+		return false, rowErrorf("username must be 3-50 characters")
+	}
+	if addr, err := mail.ParseAddress(rec.Email); err != nil || addr.Address != rec.Email {
+		return false, rowErrorf("invalid email %q", rec.Email)
+	}
+	if rec.Password != "" && rec.PasswordHash != "" {
+		return false, rowErrorf("password and password_hash are mutually exclusive")
+	}
+	// 组名在试运行时同样校验，使试运行与实际导入报告相同的行错误
+	for _, name := range rec.Groups {
+		if utf8.RuneCountInString(name) > maxGroupNameLength {
+			return false, rowErrorf("group name %q exceeds %d characters", name, maxGroupNameLength)
+		}
+	}
+
+	usernameKey, emailKey := strings.ToLower(rec.Username), strings.ToLower(rec.Email)
+	if row, ok := seenUsernames[usernameKey]; ok {
+		return false, rowErrorf("duplicate username, first seen on row %d", row)
+	}
+	if row, ok := seenEmails[emailKey]; ok {
+		return false, rowErrorf("duplicate email, first seen on row %d", row)
+	}
+	seenUsernames[usernameKey] = rec.Row
+	seenEmails[emailKey] = rec.Row
+
+	user, err := database.DAO.User.GetByUsername(ctx, rec.Username)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		user = nil
+	case err != nil:
+		return false, err
+	case !opts.Upsert:
+		return false, rowErrorf("user already exists")
+	}
+
+	other, err := database.DAO.User.GetByEmail(ctx, rec.Email)
+	if err == nil && (user == nil || other.ID != user.ID) {
+		return false, rowErrorf("email is already used by %s", other.Username)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	if user == nil {
+		return true, createUser(ctx, rec, opts)
+	}
+	return false, updateUser(ctx, user, rec, opts)
+}
+
+func createUser(ctx context.Context, rec *Record, opts Options) error {
+	user := &models.User{
+		Username:  rec.Username,
+		Email:     rec.Email,
+		IsAdmin:   rec.IsAdmin != nil && *rec.IsAdmin,
+		ExpiresAt: rec.ExpiresAt,
+	}
+
+	switch {
+	case rec.PasswordHash != "":
+		if err := user.SetPasswordHash(rec.PasswordHash); err != nil {
+			return rowErrorf("unsupported password_hash format")
+		}
+	case rec.Password != "":
+		if err := passpolicy.Validate(rec.Username, rec.Password); err != nil {
+			return policyError(err)
+		}
+		user.Password = rec.Password
+	default:
+		return rowErrorf("password or password_hash is required for new users")
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	return groupError(database.DAO.User.CreateWithGroups(ctx, user, rec.Groups))
+}
+
+// updateUser 只修改文件中提供的字段
+func updateUser(ctx context.Context, user *models.User, rec *Record, opts Options) error {
+	if rec.Password != "" {
+		if err := passpolicy.Check(ctx, user, rec.Password); err != nil {
+			return policyError(err)
+		}
+	}
+	if rec.PasswordHash != "" {
+		if !user.IsLocal() {
+			return policyError(passpolicy.ErrExternalAccount)
+		}
+		if _, ok := passhash.Identify(rec.PasswordHash); !ok {
+			return rowErrorf("unsupported password_hash format")
+		}
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	user.Email = rec.Email
+	if rec.IsAdmin != nil {
+		user.IsAdmin = *rec.IsAdmin
+	}
+	if rec.HasExpiry {
+		user.ExpiresAt = rec.ExpiresAt
+		// 延长有效期后恢复已过期的账号
+		if user.Status == models.UserStatusExpired && !user.IsExpired() {
+			user.Status = models.UserStatusActive
+		}
+	}
+	if err := database.DAO.User.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if rec.Password != "" {
+		if err := passpolicy.SetPassword(ctx, user, rec.Password, false); err != nil {
+			return err
+		}
+	}
+	if rec.PasswordHash != "" {
+		if err := user.SetPasswordHash(rec.PasswordHash); err != nil {
+			return err
+		}
+		if err := database.DAO.User.UpdatePassword(ctx, user.ID, user.Password, user.Salt); err != nil {
+			return err
+		}
+	}
+	if rec.HasGroups {
+		if err := database.DAO.Group.ReplaceUserGroups(ctx, user.ID, rec.Groups); err != nil {
+			return rowErrorf("user updated, but groups were not: %v", err)
+		}
+	}
+	return nil
+}
+
+// groupError 组成员关系写入失败只影响当前行，事务已回滚，不会留下没有组的新用户
+func groupError(err error) error {
+	if errors.Is(err, dao.ErrGroupMembership) {
+		return rowErrorf("%v", err)
+	}
+	return err
+}
+
+func policyError(err error) error {
+	var violation *passpolicy.ViolationError
+	if errors.As(err, &violation) {
+		return rowErrorf("password does not meet policy: %s", strings.Join(violation.Violations, "; "))
+	}
+	if errors.Is(err, passpolicy.ErrExternalAccount) {
+		return rowErrorf("password is managed by an external directory")
+	}
+	return err
+}
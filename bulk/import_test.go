@@ -0,0 +1,90 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+)
+
+// sha1Hash 预先计算的 {SHA} 哈希，避免测试中执行 Argon2
+const sha1Hash = "{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="
+
+func record(row int, username string, groups ...string) Record {
+	return Record{Row: row, Username: username, Email: username + "@example.com", PasswordHash: sha1Hash, Groups: groups, HasGroups: true}
+}
+
+// breakGroup 让加入 name 组的成员关系写入失败
+func breakGroup(t *testing.T, db *gorm.DB, name string) {
+	t.Helper()
+	if err := db.Exec("INSERT INTO radius_groups (name, description, created_at, updated_at) VALUES (?, '', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)", name).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := db.Exec(`CREATE TRIGGER break_group BEFORE INSERT ON user_groups
+		WHEN NEW.group_id = (SELECT id FROM radius_groups WHERE name = '` + name + `')
+		BEGIN SELECT RAISE(ABORT, 'membership rejected'); END`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportCreatesUserAndGroupsTogether(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	breakGroup(t, db, "broken")
+
+	report, err := Import(ctx, []Record{record(2, "alice", "staff", "broken"), record(3, "bob", "staff")}, Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Created != 1 || report.Failed != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 2 {
+		t.Fatalf("report = %+v, want bob created and row 2 failed", report)
+	}
+	if _, err := database.DAO.User.GetByUsername(ctx, "alice"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("alice exists after her groups failed (err %v)", err)
+	}
+	bob, err := database.DAO.User.GetByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if member, _ := database.DAO.Group.IsMemberOfAny(ctx, bob.ID, []string{"staff"}); !member {
+		t.Fatal("bob was not added to staff")
+	}
+}
+
+func TestImportReportsGroupUpdateFailureAsRowError(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	if _, err := Import(ctx, []Record{record(2, "alice", "staff")}, Options{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	breakGroup(t, db, "broken")
+
+	report, err := Import(ctx, []Record{record(2, "alice", "broken"), record(3, "bob")}, Options{Upsert: true}, nil)
+	if err != nil {
+		t.Fatalf("import aborted: %v", err)
+	}
+	if report.Failed != 1 || report.Created != 1 || !strings.Contains(report.Errors[0].Error, "groups were not") {
+		t.Fatalf("report = %+v, want the alice row reported and bob created", report)
+	}
+}
+
+func TestDryRunValidatesGroupNames(t *testing.T) {
+	dbtest.Open(t)
+	long := strings.Repeat("g", maxGroupNameLength+1)
+
+	for _, dryRun := range []bool{true, false} {
+		report, err := Import(context.Background(), []Record{record(2, "alice", "staff", long)}, Options{DryRun: dryRun}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Failed != 1 || !strings.Contains(report.Errors[0].Error, "exceeds") {
+			t.Fatalf("dry_run=%t: report = %+v, want the long group name rejected", dryRun, report)
+		}
+	}
+}
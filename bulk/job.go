@@ -0,0 +1,102 @@
+package bulk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// 已结束的任务保留这么久供查询
+const jobRetention = 24 * time.Hour
+
+// Job 后台导入任务的状态。任务只保存在当前进程内存中，重启或多实例部署时需向发起请求的实例查询
+type Job struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Report     *Report    `json:"report,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+var (
+	jobsMu sync.Mutex
+	jobs   = make(map[string]*Job)
+)
+
+// StartImport 在后台执行导入并立即返回任务快照，onDone 在任务结束后调用，可为 nil
+func StartImport(records []Record, opts Options, onDone func(*Report, error)) Job {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	job := &Job{
+		ID:        hex.EncodeToString(buf),
+		Status:    JobRunning,
+		Total:     len(records),
+		CreatedAt: time.Now(),
+	}
+
+	jobsMu.Lock()
+	pruneJobs()
+	jobs[job.ID] = job
+	snapshot := *job
+	jobsMu.Unlock()
+
+	go func() {
+		report, err := Import(context.Background(), records, opts, func(done int) {
+			jobsMu.Lock()
+			job.Processed = done
+			jobsMu.Unlock()
+		})
+
+		jobsMu.Lock()
+		now := time.Now()
+		job.FinishedAt = &now
+		job.Report = report
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+			log.Printf("User import job %s failed: %v", job.ID, err)
+		} else {
+			job.Status = JobCompleted
+		}
+		jobsMu.Unlock()
+
+		if onDone != nil {
+			onDone(report, err)
+		}
+	}()
+
+	return snapshot
+}
+
+// GetJob 返回任务快照
+func GetJob(id string) (Job, bool) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+
+	job, ok := jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+func pruneJobs() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, job := range jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(jobs, id)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package bulk 实现用户的批量导入与导出，HTTP 接口与 admin_tool 命令共用。
+package bulk
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+var ErrTooManyRows = errors.New("too many rows")
+
+// Record 导入文件中的一行。IsAdmin 为 nil 表示不修改；
+// HasGroups / HasExpiry 表示文件中提供了该字段，空值会清除组成员关系或有效期
+type Record struct {
+	Row          int
+	Username     string
+	Email        string
+	Password     string
+	PasswordHash string
+	IsAdmin      *bool
+	Groups       []string
+	HasGroups    bool
+	ExpiresAt    *time.Time
+	HasExpiry    bool
+
+	// err 字段格式错误，该行直接计为失败
+	err error
+}
+
+// jsonRecord JSON 导入与导出共用的字段名，与 CSV 表头一致
+type jsonRecord struct {
+	Username     string    `json:"username"`
+	Email        string    `json:"email"`
+	Password     string    `json:"password,omitempty"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	IsAdmin      *bool     `json:"is_admin,omitempty"`
+	Groups       *[]string `json:"groups,omitempty"`
+	ExpiresAt    *string   `json:"expires_at,omitempty"`
+}
+
+// ParseFormat 未指定格式时按文件名或 Content-Type 判断
+func ParseFormat(format, filename, contentType string) (string, error) {
+	format = strings.ToLower(format)
+	switch {
+	case format == FormatCSV || format == FormatJSON:
+		return format, nil
+	case format != "":
+		return "", fmt.Errorf("unsupported format %q", format)
+	case strings.HasSuffix(strings.ToLower(filename), ".json"), strings.Contains(contentType, "json"):
+		return FormatJSON, nil
+	}
+	return FormatCSV, nil
+}
+
+// Parse 读取全部记录，结构性错误（缺少表头、JSON 语法错误）直接返回，单行字段错误留到导入时报告
+func Parse(r io.Reader, format string, maxRows int) ([]Record, error) {
+	if format == FormatJSON {
+		return parseJSON(r, maxRows)
+	}
+	return parseCSV(r, maxRows)
+}
+
+func parseCSV(r io.Reader, maxRows int) ([]Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel 导出的 CSV 可能带 BOM
+		name = strings.TrimSpace(strings.TrimPrefix(strings.ToLower(name), "\ufeff"))
+		columns[name] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, errors.New("missing username column")
+	}
+
+	var records []Record
+	for line := 2; ; line++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(records) >= maxRows {
+			return nil, fmt.Errorf("%w: at most %d rows per import", ErrTooManyRows, maxRows)
+		}
+
+		get := func(name string) (string, bool) {
+			i, ok := columns[name]
+			if !ok || i >= len(fields) {
+				return "", ok
+			}
+			return strings.TrimSpace(fields[i]), true
+		}
+
+		rec := Record{Row: line}
+		rec.Username, _ = get("username")
+		rec.Email, _ = get("email")
+		rec.Password, _ = get("password")
+		rec.PasswordHash, _ = get("password_hash")
+		if value, _ := get("is_admin"); value != "" {
+			isAdmin, err := strconv.ParseBool(value)
+			if err != nil {
+				rec.err = fmt.Errorf("invalid is_admin %q", value)
+			}
+			rec.IsAdmin = &isAdmin
+		}
+		if value, ok := get("groups"); ok {
+			rec.HasGroups = true
+			rec.Groups = splitGroups(strings.Split(value, ";"))
+		}
+		if value, ok := get("expires_at"); ok {
+			rec.HasExpiry = true
+			if rec.ExpiresAt, err = parseExpiry(value); err != nil && rec.err == nil {
+				rec.err = err
+			}
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func parseJSON(r io.Reader, maxRows int) ([]Record, error) {
+	decoder := json.NewDecoder(r)
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		return nil, errors.New("expected a JSON array of users")
+	}
+
+	var records []Record
+	for row := 1; decoder.More(); row++ {
+		if len(records) >= maxRows {
+			return nil, fmt.Errorf("%w: at most %d rows per import", ErrTooManyRows, maxRows)
+		}
+
+		var item jsonRecord
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		rec := Record{
+			Row:          row,
+			Username:     strings.TrimSpace(item.Username),
+			Email:        strings.TrimSpace(item.Email),
+			Password:     item.Password,
+			PasswordHash: item.PasswordHash,
+			IsAdmin:      item.IsAdmin,
+		}
+		if item.Groups != nil {
+			rec.HasGroups = true
+			rec.Groups = splitGroups(*item.Groups)
+		}
+		if item.ExpiresAt != nil {
+			rec.HasExpiry = true
+			rec.ExpiresAt, rec.err = parseExpiry(strings.TrimSpace(*item.ExpiresAt))
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+func splitGroups(names []string) []string {
+	var groups []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			groups = append(groups, name)
+		}
+	}
+	return groups
+}
+
+// parseExpiry 接受 RFC 3339 时间或 YYYY-MM-DD 日期，仅写日期时账号在当天结束前有效
+func parseExpiry(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if d, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		t := d.AddDate(0, 0, 1)
+		return &t, nil
+	}
+	return nil, fmt.Errorf("invalid expires_at %q, expected RFC 3339 or YYYY-MM-DD", value)
+}
@@ -2,13 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
+	"gorm.io/gorm/logger"
+
+	"github.com/Gaojianli/raduis_mgnt/authstats"
+	"github.com/Gaojianli/raduis_mgnt/bulk"
 	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/database"
 	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passhash"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/retention"
 )
 
 const (
@@ -30,13 +41,30 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	if err := passhash.Init(); err != nil {
+		log.Fatal("Failed to initialize password hashing:", err)
+	}
+
+	if err := passpolicy.Init(); err != nil {
+		log.Fatal("Failed to initialize password policy:", err)
+	}
+
+	ctx := context.Background()
+
+	// migrate 需要在表结构迁移之前连接，不能使用会自动迁移的 Connect
+	if command == "migrate" {
+		if err := database.Open(); err != nil {
+			log.Fatal("Failed to connect to database:", err)
+		}
+		migrate(ctx, os.Args[2:])
+		return
+	}
+
 	// 连接数据库
 	if err := database.Connect(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
-	ctx := context.Background()
-
 	switch command {
 	case "reset-password":
 		resetAdminPassword(ctx)
@@ -44,6 +72,12 @@ func main() {
 		createAdminUser(ctx)
 	case "init":
 		initAdminUser(ctx)
+	case "import-users":
+		importUsers(ctx, os.Args[2:])
+	case "export-users":
+		exportUsers(ctx, os.Args[2:])
+	case "purge-auth-logs":
+		purgeAuthLogs(ctx, os.Args[2:])
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		showUsage()
@@ -58,11 +92,23 @@ func showUsage() {
 	fmt.Println("  reset-password  Reset admin password to default (admin123)")
 	fmt.Println("  create-admin    Create default admin user if not exists")
 	fmt.Println("  init            Initialize admin user (create if not exists, reset password)")
+	fmt.Println("  import-users    Import users from a CSV or JSON file")
+	fmt.Println("                  [--dry-run] [--upsert] [--format csv|json] <file>")
+	fmt.Println("  export-users    Export all users as CSV or JSON")
+	fmt.Println("                  [--format csv|json] [--include-password-hash] [--output file]")
+	fmt.Println("  purge-auth-logs Archive and delete auth logs according to the retention policy")
+	fmt.Println("                  [--max-age 2160h] [--max-rows n] [--batch-size n]")
+	fmt.Println("  migrate         Show or change the database schema version")
+	fmt.Println("                  status | up [--to version] | down [--steps n] | force <version>")
 	fmt.Println("")
 	fmt.Println("Examples:")
 	fmt.Println("  go run cmd/admin_tool.go reset-password")
 	fmt.Println("  go run cmd/admin_tool.go create-admin")
 	fmt.Println("  go run cmd/admin_tool.go init")
+	fmt.Println("  go run cmd/admin_tool.go import-users --dry-run students.csv")
+	fmt.Println("  go run cmd/admin_tool.go export-users --format json --output users.json")
+	fmt.Println("  go run cmd/admin_tool.go purge-auth-logs --max-age 2160h")
+	fmt.Println("  go run cmd/admin_tool.go migrate status")
 }
 
 func resetAdminPassword(ctx context.Context) {
@@ -127,3 +173,195 @@ func initAdminUser(ctx context.Context) {
 		resetAdminPassword(ctx)
 	}
 }
+
+func importUsers(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("import-users", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "validate only, do not write to the database")
+	upsert := flags.Bool("upsert", false, "update users that already exist")
+	format := flags.String("format", "", "csv or json (default: by file extension)")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatal("Usage: admin_tool import-users [--dry-run] [--upsert] [--format csv|json] <file>")
+	}
+
+	path := flags.Arg(0)
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal("Failed to open import file:", err)
+	}
+	defer file.Close()
+
+	fileFormat, err := bulk.ParseFormat(*format, path, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+	records, err := bulk.Parse(file, fileFormat, config.AppConfig.ImportMaxRows)
+	if err != nil {
+		log.Fatal("Invalid import file:", err)
+	}
+
+	report, err := bulk.Import(ctx, records, bulk.Options{DryRun: *dryRun, Upsert: *upsert}, func(done int) {
+		if done%100 == 0 || done == len(records) {
+			fmt.Printf("\rProcessed %d/%d", done, len(records))
+		}
+	})
+	fmt.Println()
+	if report != nil {
+		for _, rowErr := range report.Errors {
+			fmt.Printf("  row %d (%s): %s\n", rowErr.Row, rowErr.Username, rowErr.Error)
+		}
+		if !report.DryRun {
+			database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+				ActorName:  "admin_tool",
+				Action:     "user.import",
+				TargetType: "user",
+				Detail:     report.Summary(),
+			})
+		}
+	}
+	if err != nil {
+		log.Fatal("Import aborted:", err)
+	}
+
+	if report.DryRun {
+		fmt.Printf("Dry run: %d would be created, %d would be updated, %d failed\n", report.Created, report.Updated, report.Failed)
+	} else {
+		fmt.Printf("✅ Import finished: %d created, %d updated, %d failed\n", report.Created, report.Updated, report.Failed)
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func exportUsers(ctx context.Context, args []string) {
+	flags := flag.NewFlagSet("export-users", flag.ExitOnError)
+	format := flags.String("format", bulk.FormatCSV, "csv or json")
+	includeHashes := flags.Bool("include-password-hash", false, "include password hashes of local accounts")
+	output := flags.String("output", "", "output file (default: stdout)")
+	flags.Parse(args)
+
+	fileFormat, err := bulk.ParseFormat(*format, "", "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := os.Stdout
+	if *output == "" {
+		// SQL 日志同样写到标准输出，会混入导出内容
+		database.DB.Logger = logger.Discard
+	} else {
+		if out, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer out.Close()
+	}
+
+	if err := bulk.Export(ctx, out, fileFormat, *includeHashes); err != nil {
+		log.Fatal("Export failed:", err)
+	}
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorName:  "admin_tool",
+		Action:     "user.export",
+		TargetType: "user",
+		Detail:     fmt.Sprintf("format=%s, include_password_hash=%t", fileFormat, *includeHashes),
+	})
+}
+
+// purgeAuthLogs 命令行参数覆盖环境变量中的保留策略，归档目标仍由 AUTH_LOG_ARCHIVE 决定
+func purgeAuthLogs(ctx context.Context, args []string) {
+	policy := retention.ConfiguredPolicy()
+	flags := flag.NewFlagSet("purge-auth-logs", flag.ExitOnError)
+	flags.DurationVar(&policy.MaxAge, "max-age", policy.MaxAge, "delete logs older than this, e.g. 2160h for 90 days")
+	flags.IntVar(&policy.MaxRows, "max-rows", policy.MaxRows, "keep at most this many of the newest logs")
+	flags.IntVar(&policy.BatchSize, "batch-size", policy.BatchSize, "rows archived and deleted per batch")
+	flags.Parse(args)
+
+	if err := retention.Init(); err != nil {
+		log.Fatal("Failed to initialize auth log retention:", err)
+	}
+	// 每批删除的 SQL 包含全部 ID，只保留警告日志
+	database.DB.Logger = logger.Default.LogMode(logger.Warn)
+
+	// 只删除已计入统计汇总的日志，先补齐汇总
+	if err := authstats.Aggregate(ctx); err != nil {
+		log.Fatal("Failed to update auth stats rollups:", err)
+	}
+
+	run, err := retention.Run(ctx, policy, retention.TriggerCLI)
+	if errors.Is(err, retention.ErrDisabled) {
+		log.Fatal("No retention policy: set AUTH_LOG_MAX_AGE / AUTH_LOG_MAX_ROWS or pass --max-age / --max-rows")
+	}
+	if run != nil {
+		fmt.Printf("Archived %d logs in %d files, deleted %d logs\n", run.Archived, run.Files, run.Deleted)
+	}
+	if err != nil {
+		log.Fatal("Purge failed:", err)
+	}
+	fmt.Println("✅ Auth log purge finished")
+}
+
+func migrate(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		log.Fatal("Usage: admin_tool migrate status | up [--to version] | down [--steps n] | force <version>")
+	}
+	// 迁移脚本中的 DDL 已由下面的日志说明，SQL 日志只保留警告
+	database.DB.Logger = logger.Default.LogMode(logger.Warn)
+
+	switch args[0] {
+	case "status":
+		state, infos, err := database.MigrationStatus(ctx)
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		for _, info := range infos {
+			status := "pending"
+			switch {
+			case info.Dirty:
+				status = "DIRTY, applied " + info.AppliedAt.Local().Format(time.RFC3339)
+			case info.AppliedAt != nil:
+				status = "applied " + info.AppliedAt.Local().Format(time.RFC3339)
+			}
+			if !info.Known {
+				status += " (unknown to this build)"
+			}
+			fmt.Printf("%06d_%-30s %s\n", info.Version, info.Name, status)
+		}
+		fmt.Printf("\nSchema version %d, latest %d\n", state.Version, state.Latest)
+
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ExitOnError)
+		to := flags.Int64("to", 0, "migrate up to this version (default: latest)")
+		flags.Parse(args[1:])
+		if err := database.MigrateUp(ctx, *to); err != nil {
+			log.Fatal("Migration failed:", err)
+		}
+		fmt.Println("✅ Database schema is up to date")
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		flags.Parse(args[1:])
+		if err := database.MigrateDown(ctx, *steps); err != nil {
+			log.Fatal("Rollback failed:", err)
+		}
+		if state, err := database.SchemaVersion(ctx); err == nil {
+			fmt.Printf("✅ Schema version is now %d\n", state.Version)
+		}
+
+	case "force":
+		if len(args) != 2 {
+			log.Fatal("Usage: admin_tool migrate force <version>")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatal("Invalid version:", err)
+		}
+		if err := database.ForceVersion(ctx, version); err != nil {
+			log.Fatal("Failed to set schema version:", err)
+		}
+		fmt.Printf("✅ Schema version set to %d without running any migration\n", version)
+
+	default:
+		log.Fatalf("Unknown migrate command: %s", args[0])
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/database"
 	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passhash"
 )
 
 func main() {
@@ -17,6 +18,10 @@ func main() {
 		log.Fatal("Failed to load config:", err)
 	}
 
+	if err := passhash.Init(); err != nil {
+		log.Fatal("Failed to initialize password hashing:", err)
+	}
+
 	// 连接数据库
 	if err := database.Connect(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
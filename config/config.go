@@ -1,54 +1,446 @@
 package config
 
 import (
-	"os"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
+	// AppEnv 为 development 或 production，production 下拒绝使用默认密钥启动
+	AppEnv   string
+	LogLevel string
+
+	// DBDriver 为 mysql、postgres 或 sqlite；DBDSN 非空时直接使用，否则由 DB_HOST 等拼接
+	DBDriver          string
+	DBDSN             string
 	DBHost            string
 	DBPort            int
 	DBUser            string
 	DBPassword        string
 	DBName            string
+	DBAutoMigrate     bool
 	JWTSecret         string
 	ServerPort        string
 	DefaultAdminUser  string
 	DefaultAdminPass  string
 	DefaultAdminEmail string
+
+	// 数据库连接池
+	DBPoolMaxOpen     int
+	DBPoolMaxIdle     int
+	DBPoolMaxLifetime time.Duration
+
+	// HTTP 服务超时，0 表示不限制
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	ServerIdleTimeout  time.Duration
+
+	// 可信反向代理的 IP 或 CIDR，只有来自这些地址的请求才读取 X-Forwarded-For / X-Real-IP，为空时使用连接的对端地址
+	TrustedProxies []string
+
+	// 登录令牌有效期与可刷新期限
+	JWTLifetime   time.Duration
+	JWTMaxRefresh time.Duration
+
+	// 自助注册
+	RegistrationEnabled        bool
+	RegistrationInviteCode     string
+	RegistrationAllowedDomains []string
+
+	// 邮件发送
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// 异步邮件队列容量与发送协程数，队列满时丢弃新邮件
+	MailQueueSize int
+	MailWorkers   int
+
+	// 担保访客
+	GuestGroup          string
+	GuestLifetime       time.Duration
+	GuestMaxLifetime    time.Duration
+	GuestRequestTTL     time.Duration
+	GuestExpiryInterval time.Duration
+
+	// 多因素认证
+	MFAIssuer          string
+	MFARequiredGroups  []string
+	MFARadiusChallenge bool
+
+	// Web 登录第二因素
+	WebMFARequireAdmin bool
+	MFALoginTimeout    time.Duration
+	WebAuthnRPID       string
+	WebAuthnRPName     string
+	WebAuthnRPOrigins  []string
+
+	// 密码哈希
+	PasswordHashScheme    string
+	PasswordBcryptCost    int
+	PasswordArgon2Memory  int
+	PasswordArgon2Time    int
+	PasswordArgon2Threads int
+
+	// 密码策略
+	PasswordMinLength       int
+	PasswordRequiredClasses []string
+	PasswordBlocklistFile   string
+	PasswordHistorySize     int
+	PasswordMaxAge          time.Duration
+	PasswordExpiryWarning   time.Duration
+
+	// 忘记密码
+	PasswordResetEnabled    bool
+	PasswordResetTTL        time.Duration
+	PasswordResetURL        string
+	PasswordResetRateLimit  int
+	PasswordResetRateWindow time.Duration
+
+	// 认证后端
+	AuthBackends []string
+
+	// LDAP / Active Directory
+	LDAPURL                string
+	LDAPStartTLS           bool
+	LDAPInsecureSkipVerify bool
+	LDAPBindDN             string
+	LDAPBindPassword       string
+	LDAPBaseDN             string
+	LDAPUserFilter         string
+	LDAPEmailAttribute     string
+	LDAPGroupAttribute     string
+	LDAPAdminGroups        []string
+	LDAPGroupMapping       []string
+	LDAPTimeout            time.Duration
+
+	// OIDC 单点登录
+	OIDCIssuer            string
+	OIDCClientID          string
+	OIDCClientSecret      string
+	OIDCRedirectURL       string
+	OIDCScopes            []string
+	OIDCUsernameClaim     string
+	OIDCEmailClaim        string
+	OIDCGroupsClaim       string
+	OIDCAdminGroups       []string
+	OIDCAllowedGroups     []string
+	OIDCGroupMapping      []string
+	OIDCPostLoginRedirect string
+
+	// SCIM 2.0 用户与组同步
+	SCIMToken      string
+	SCIMMaxResults int
+
+	// 批量导入
+	ImportMaxRows        int
+	ImportAsyncThreshold int
+	MaxRequestBodySize   int
+
+	// 认证日志保留与归档
+	AuthLogMaxAge                time.Duration
+	AuthLogMaxRows               int
+	AuthLogRetentionInterval     time.Duration
+	AuthLogRetentionBatchSize    int
+	AuthLogArchive               string
+	AuthLogArchiveDir            string
+	AuthLogArchiveS3Endpoint     string
+	AuthLogArchiveS3Region       string
+	AuthLogArchiveS3Bucket       string
+	AuthLogArchiveS3Prefix       string
+	AuthLogArchiveS3AccessKey    string
+	AuthLogArchiveS3SecretKey    string
+	AuthLogArchiveS3SessionToken string
+	AuthLogArchiveS3PathStyle    bool
+
+	// 认证统计汇总
+	AuthStatsRollupInterval  time.Duration
+	AuthStatsHourlyRetention time.Duration
+
+	// 认证日志写入队列
+	AuthLogQueueSize int
+
+	// 运行时设置，各实例按此间隔检查其他实例的修改
+	SettingsRefreshInterval time.Duration
+
+	// Prometheus 指标
+	MetricsAddr  string
+	MetricsToken string
+
+	// 就绪检查与退出
+	ReadinessDBTimeout time.Duration
+	ShutdownDelay      time.Duration
+	ShutdownTimeout    time.Duration
+
+	// OpenTelemetry 链路追踪
+	OTelEndpoint    string
+	OTelHeaders     []string
+	OTelServiceName string
+	OTelSampleRatio float64
 }
 
 var AppConfig *Config
 
+// LoadConfig 按 环境变量（含 .env）> 配置文件 > 默认值 的优先级加载配置并校验
 func LoadConfig() error {
 	godotenv.Load()
+	if err := loadFile(); err != nil {
+		return err
+	}
+	lookedUp = map[string]bool{}
+	loadErrs = nil
 
-	dbPort, err := strconv.Atoi(getEnv("DB_PORT", "3306"))
-	if err != nil {
-		dbPort = 3306
+	dbDriver := strings.ToLower(getEnv("DB_DRIVER", "mysql"))
+	defaultDBPort := 3306
+	if dbDriver == "postgres" {
+		defaultDBPort = 5432
 	}
 
 	AppConfig = &Config{
+		AppEnv:   strings.ToLower(getEnv("APP_ENV", EnvDevelopment)),
+		LogLevel: strings.ToLower(getEnv("LOG_LEVEL", "info")),
+
+		DBDriver:          dbDriver,
+		DBDSN:             getEnv("DB_DSN", ""),
 		DBHost:            getEnv("DB_HOST", "localhost"),
-		DBPort:            dbPort,
+		DBPort:            getEnvInt("DB_PORT", defaultDBPort),
 		DBUser:            getEnv("DB_USER", "root"),
 		DBPassword:        getEnv("DB_PASSWORD", ""),
 		DBName:            getEnv("DB_NAME", "radius_mgnt"),
-		JWTSecret:         getEnv("JWT_SECRET", "your-secret-key"),
+		DBAutoMigrate:     getEnvBool("DB_AUTO_MIGRATE", true),
+		JWTSecret:         getEnv("JWT_SECRET", defaultJWTSecret),
 		ServerPort:        getEnv("SERVER_PORT", ":8080"),
 		DefaultAdminUser:  getEnv("DEFAULT_ADMIN_USER", "admin"),
-		DefaultAdminPass:  getEnv("DEFAULT_ADMIN_PASSWORD", "admin123"),
+		DefaultAdminPass:  getEnv("DEFAULT_ADMIN_PASSWORD", DefaultAdminPassword),
 		DefaultAdminEmail: getEnv("DEFAULT_ADMIN_EMAIL", "admin@example.com"),
+
+		DBPoolMaxOpen:     getEnvInt("DB_POOL_MAX_OPEN", 100),
+		DBPoolMaxIdle:     getEnvInt("DB_POOL_MAX_IDLE", 10),
+		DBPoolMaxLifetime: getEnvDuration("DB_POOL_MAX_LIFETIME", time.Hour),
+
+		ServerReadTimeout:  getEnvDuration("SERVER_READ_TIMEOUT", 3*time.Minute),
+		ServerWriteTimeout: getEnvDuration("SERVER_WRITE_TIMEOUT", 0),
+		ServerIdleTimeout:  getEnvDuration("SERVER_IDLE_TIMEOUT", 3*time.Minute),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES"),
+
+		JWTLifetime:   getEnvDuration("JWT_LIFETIME", 24*time.Hour),
+		JWTMaxRefresh: getEnvDuration("JWT_MAX_REFRESH", 24*time.Hour),
+
+		RegistrationEnabled:        getEnvBool("REGISTRATION_ENABLED", false),
+		RegistrationInviteCode:     getEnv("REGISTRATION_INVITE_CODE", ""),
+		RegistrationAllowedDomains: getEnvList("REGISTRATION_ALLOWED_DOMAINS"),
+
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnvInt("SMTP_PORT", 25),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "radius-manager@localhost"),
+
+		MailQueueSize: getEnvInt("MAIL_QUEUE_SIZE", 100),
+		MailWorkers:   getEnvInt("MAIL_WORKERS", 2),
+
+		GuestGroup:          getEnv("GUEST_GROUP", "guests"),
+		GuestLifetime:       getEnvDuration("GUEST_LIFETIME", 24*time.Hour),
+		GuestMaxLifetime:    getEnvDuration("GUEST_MAX_LIFETIME", 7*24*time.Hour),
+		GuestRequestTTL:     getEnvDuration("GUEST_REQUEST_TTL", 72*time.Hour),
+		GuestExpiryInterval: getEnvDuration("GUEST_EXPIRY_INTERVAL", 5*time.Minute),
+
+		MFAIssuer:          getEnv("MFA_ISSUER", "RADIUS Manager"),
+		MFARequiredGroups:  getEnvList("MFA_REQUIRED_GROUPS"),
+		MFARadiusChallenge: getEnvBool("MFA_RADIUS_CHALLENGE", false),
+
+		WebMFARequireAdmin: getEnvBool("WEB_MFA_REQUIRE_ADMIN", true),
+		MFALoginTimeout:    getEnvDuration("MFA_LOGIN_TIMEOUT", 5*time.Minute),
+		WebAuthnRPID:       getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPName:     getEnv("WEBAUTHN_RP_NAME", "RADIUS Manager"),
+		WebAuthnRPOrigins:  getEnvList("WEBAUTHN_RP_ORIGINS"),
+
+		PasswordHashScheme:    getEnv("PASSWORD_HASH_SCHEME", "argon2id"),
+		PasswordBcryptCost:    getEnvInt("PASSWORD_BCRYPT_COST", 12),
+		PasswordArgon2Memory:  getEnvInt("PASSWORD_ARGON2_MEMORY", 19456),
+		PasswordArgon2Time:    getEnvInt("PASSWORD_ARGON2_TIME", 2),
+		PasswordArgon2Threads: getEnvInt("PASSWORD_ARGON2_THREADS", 1),
+
+		PasswordMinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 6),
+		PasswordRequiredClasses: getEnvList("PASSWORD_REQUIRED_CLASSES"),
+		PasswordBlocklistFile:   getEnv("PASSWORD_BLOCKLIST_FILE", ""),
+		PasswordHistorySize:     getEnvInt("PASSWORD_HISTORY_SIZE", 0),
+		PasswordMaxAge:          getEnvDuration("PASSWORD_MAX_AGE", 0),
+		PasswordExpiryWarning:   getEnvDuration("PASSWORD_EXPIRY_WARNING", 14*24*time.Hour),
+
+		PasswordResetEnabled:    getEnvBool("PASSWORD_RESET_ENABLED", getEnv("SMTP_HOST", "") != ""),
+		PasswordResetTTL:        getEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		PasswordResetURL:        getEnv("PASSWORD_RESET_URL", ""),
+		PasswordResetRateLimit:  getEnvInt("PASSWORD_RESET_RATE_LIMIT", 5),
+		PasswordResetRateWindow: getEnvDuration("PASSWORD_RESET_RATE_WINDOW", 15*time.Minute),
+
+		AuthBackends: getEnvList("AUTH_BACKENDS"),
+
+		LDAPURL:                getEnv("LDAP_URL", ""),
+		LDAPStartTLS:           getEnvBool("LDAP_START_TLS", false),
+		LDAPInsecureSkipVerify: getEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+		LDAPBindDN:             getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+		LDAPBaseDN:             getEnv("LDAP_BASE_DN", ""),
+		LDAPUserFilter:         getEnv("LDAP_USER_FILTER", "(&(objectClass=user)(sAMAccountName=%s))"),
+		LDAPEmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		LDAPGroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		LDAPAdminGroups:        getEnvList("LDAP_ADMIN_GROUPS"),
+		LDAPGroupMapping:       getEnvList("LDAP_GROUP_MAPPING"),
+		LDAPTimeout:            getEnvDuration("LDAP_TIMEOUT", 10*time.Second),
+
+		OIDCIssuer:            getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:          getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:       getEnv("OIDC_REDIRECT_URL", "http://localhost:8080/api/v1/auth/oidc/callback"),
+		OIDCScopes:            getEnvList("OIDC_SCOPES"),
+		OIDCUsernameClaim:     getEnv("OIDC_USERNAME_CLAIM", "preferred_username"),
+		OIDCEmailClaim:        getEnv("OIDC_EMAIL_CLAIM", "email"),
+		OIDCGroupsClaim:       getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		OIDCAdminGroups:       getEnvList("OIDC_ADMIN_GROUPS"),
+		OIDCAllowedGroups:     getEnvList("OIDC_ALLOWED_GROUPS"),
+		OIDCGroupMapping:      getEnvList("OIDC_GROUP_MAPPING"),
+		OIDCPostLoginRedirect: getEnv("OIDC_POST_LOGIN_REDIRECT", "/"),
+
+		SCIMToken:      getEnv("SCIM_TOKEN", ""),
+		SCIMMaxResults: getEnvInt("SCIM_MAX_RESULTS", 200),
+
+		ImportMaxRows:        getEnvInt("IMPORT_MAX_ROWS", 50000),
+		ImportAsyncThreshold: getEnvInt("IMPORT_ASYNC_THRESHOLD", 500),
+		MaxRequestBodySize:   getEnvInt("MAX_REQUEST_BODY_SIZE", 32<<20),
+
+		AuthLogMaxAge:                getEnvDuration("AUTH_LOG_MAX_AGE", 0),
+		AuthLogMaxRows:               getEnvInt("AUTH_LOG_MAX_ROWS", 0),
+		AuthLogRetentionInterval:     getEnvDuration("AUTH_LOG_RETENTION_INTERVAL", time.Hour),
+		AuthLogRetentionBatchSize:    getEnvInt("AUTH_LOG_RETENTION_BATCH_SIZE", 5000),
+		AuthLogArchive:               getEnv("AUTH_LOG_ARCHIVE", "none"),
+		AuthLogArchiveDir:            getEnv("AUTH_LOG_ARCHIVE_DIR", "./archive/auth_logs"),
+		AuthLogArchiveS3Endpoint:     getEnv("AUTH_LOG_ARCHIVE_S3_ENDPOINT", ""),
+		AuthLogArchiveS3Region:       getEnv("AUTH_LOG_ARCHIVE_S3_REGION", "us-east-1"),
+		AuthLogArchiveS3Bucket:       getEnv("AUTH_LOG_ARCHIVE_S3_BUCKET", ""),
+		AuthLogArchiveS3Prefix:       getEnv("AUTH_LOG_ARCHIVE_S3_PREFIX", "auth_logs/"),
+		AuthLogArchiveS3AccessKey:    getEnv("AUTH_LOG_ARCHIVE_S3_ACCESS_KEY", ""),
+		AuthLogArchiveS3SecretKey:    getEnv("AUTH_LOG_ARCHIVE_S3_SECRET_KEY", ""),
+		AuthLogArchiveS3SessionToken: getEnv("AUTH_LOG_ARCHIVE_S3_SESSION_TOKEN", ""),
+		AuthLogArchiveS3PathStyle:    getEnvBool("AUTH_LOG_ARCHIVE_S3_PATH_STYLE", true),
+
+		AuthStatsRollupInterval:  getEnvDuration("AUTH_STATS_ROLLUP_INTERVAL", time.Minute),
+		AuthStatsHourlyRetention: getEnvDuration("AUTH_STATS_HOURLY_RETENTION", 90*24*time.Hour),
+
+		AuthLogQueueSize: getEnvInt("AUTH_LOG_QUEUE_SIZE", 10000),
+
+		SettingsRefreshInterval: getEnvDuration("SETTINGS_REFRESH_INTERVAL", 10*time.Second),
+
+		MetricsAddr:  getEnv("METRICS_ADDR", ""),
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		ReadinessDBTimeout: getEnvDuration("READINESS_DB_TIMEOUT", 2*time.Second),
+		ShutdownDelay:      getEnvDuration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT", 20*time.Second),
+
+		OTelEndpoint:    getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		OTelHeaders:     getEnvList("OTEL_EXPORTER_OTLP_HEADERS"),
+		OTelServiceName: getEnv("OTEL_SERVICE_NAME", "radius-manager"),
+		OTelSampleRatio: getEnvFloat("OTEL_TRACES_SAMPLER_ARG", 1),
 	}
 
-	return nil
+	if len(AppConfig.AuthBackends) == 0 {
+		AppConfig.AuthBackends = []string{"local"}
+	}
+
+	if len(AppConfig.OIDCScopes) == 0 {
+		AppConfig.OIDCScopes = []string{"openid", "profile", "email"}
+	}
+
+	if len(AppConfig.WebAuthnRPOrigins) == 0 {
+		AppConfig.WebAuthnRPOrigins = []string{"http://localhost:8080"}
+	}
+
+	errs := append(loadErrs, unknownFileKeys()...)
+	errs = append(errs, AppConfig.Validate())
+	return errors.Join(errs...)
 }
 
+// getEnv 等函数除环境变量外也读取配置文件与 _FILE 指向的文件，见 lookup。
+// 无法解析时记为加载错误；取值范围（包括 0 与负数）由 Validate 检查
 func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func invalid(key, value, expected string) {
+	loadErrs = append(loadErrs, fmt.Errorf("%s=%q is not %s", key, value, expected))
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	raw := lookup(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		invalid(key, raw, "a boolean")
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	raw := lookup(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		invalid(key, raw, "an integer")
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	raw := lookup(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		invalid(key, raw, "a number")
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	raw := lookup(key)
+	if raw == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		invalid(key, raw, "a duration such as 30s or 24h")
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList 读取逗号分隔的列表，忽略空项
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(lookup(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigKeepsZeroWhereItMeansUnlimited(t *testing.T) {
+	t.Setenv("SERVER_READ_TIMEOUT", "0")
+	t.Setenv("SERVER_IDLE_TIMEOUT", "0s")
+	t.Setenv("PASSWORD_HISTORY_SIZE", "0")
+
+	if err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if AppConfig.ServerReadTimeout != 0 || AppConfig.ServerIdleTimeout != 0 {
+		t.Errorf("server timeouts = %s / %s, want 0", AppConfig.ServerReadTimeout, AppConfig.ServerIdleTimeout)
+	}
+	if AppConfig.PasswordHistorySize != 0 {
+		t.Errorf("PasswordHistorySize = %d, want 0", AppConfig.PasswordHistorySize)
+	}
+}
+
+func TestLoadConfigRejectsNegativeAndZeroValues(t *testing.T) {
+	t.Setenv("SERVER_WRITE_TIMEOUT", "-1s")
+	t.Setenv("AUTH_LOG_QUEUE_SIZE", "-5")
+	t.Setenv("JWT_LIFETIME", "0")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "-0.5")
+
+	err := LoadConfig()
+	if err == nil {
+		t.Fatal("LoadConfig succeeded, want validation errors")
+	}
+	for _, name := range []string{"SERVER_WRITE_TIMEOUT", "AUTH_LOG_QUEUE_SIZE", "JWT_LIFETIME", "OTEL_TRACES_SAMPLER_ARG"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error does not mention %s: %v", name, err)
+		}
+	}
+}
+
+func TestPasswordResetFollowsSMTP(t *testing.T) {
+	if err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if AppConfig.PasswordResetEnabled {
+		t.Error("password reset enabled without SMTP_HOST")
+	}
+
+	t.Setenv("SMTP_HOST", "mail.example.com")
+	if err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if !AppConfig.PasswordResetEnabled {
+		t.Error("password reset disabled although SMTP_HOST is set")
+	}
+
+	t.Setenv("SMTP_HOST", "")
+	t.Setenv("PASSWORD_RESET_ENABLED", "true")
+	if err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "PASSWORD_RESET_ENABLED") {
+		t.Errorf("LoadConfig = %v, want PASSWORD_RESET_ENABLED error", err)
+	}
+}
+
+func TestLoadConfigRejectsInvalidTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,127.0.0.1,::1,proxy.local")
+
+	err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), `"proxy.local"`) || strings.Contains(err.Error(), "10.0.0.0/8") {
+		t.Fatalf("LoadConfig = %v, want only proxy.local rejected", err)
+	}
+}
+
+func TestRequireChangedAdminPasswordRefusesProduction(t *testing.T) {
+	c := &Config{AppEnv: EnvProduction, DefaultAdminUser: "admin"}
+	if err := c.RequireChangedAdminPassword(false); err != nil {
+		t.Errorf("changed password refused: %v", err)
+	}
+	if err := c.RequireChangedAdminPassword(true); err == nil || !strings.Contains(err.Error(), `"admin"`) {
+		t.Errorf("RequireChangedAdminPassword(true) = %v, want refusal naming the admin", err)
+	}
+
+	c.AppEnv = EnvDevelopment
+	if err := c.RequireChangedAdminPassword(true); err != nil {
+		t.Errorf("development refused to start: %v", err)
+	}
+}
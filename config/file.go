@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// 未设置 CONFIG_FILE 时在工作目录中按顺序查找
+var configFileCandidates = []string{"config.yaml", "config.yml", "config.toml"}
+
+// sectionAliases 配置文件中与环境变量前缀不同的分节名
+var sectionAliases = map[string]string{"DATABASE": "DB"}
+
+var (
+	// fileValues 配置文件展开后的值，键即对应的环境变量名
+	fileValues map[string]string
+	filePath   string
+	// lookedUp 加载配置时读取过的键，用于发现配置文件中拼错的键
+	lookedUp map[string]bool
+	loadErrs []error
+)
+
+// loadFile 读取 YAML 或 TOML 配置文件，分节与键名以下划线连接并转为大写后即为环境变量名，
+// 如 database.pool.max_open 对应 DB_POOL_MAX_OPEN；列表展开为逗号分隔的值
+func loadFile() error {
+	fileValues = map[string]string{}
+	filePath = os.Getenv("CONFIG_FILE")
+	if filePath == "" {
+		for _, candidate := range configFileCandidates {
+			if _, err := os.Stat(candidate); err == nil {
+				filePath = candidate
+				break
+			}
+		}
+		if filePath == "" {
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", filePath, err)
+	}
+	return flatten("", tree)
+}
+
+func flatten(prefix string, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for name, child := range v {
+			key := strings.ToUpper(name)
+			if prefix == "" {
+				if alias, ok := sectionAliases[key]; ok {
+					key = alias
+				}
+			} else {
+				key = prefix + "_" + key
+			}
+			if err := flatten(key, child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("config key %s: list items must be plain values", prefix)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		fileValues[prefix] = strings.Join(items, ",")
+	default:
+		if prefix == "" {
+			return fmt.Errorf("config file must contain a mapping at the top level")
+		}
+		fileValues[prefix] = fmt.Sprint(v)
+	}
+	return nil
+}
+
+// lookup 依次读取环境变量、<KEY>_FILE 环境变量指向的文件、配置文件中的键及其 _file 形式。
+// 密钥可放在挂载的文件中（如 Docker / Kubernetes secrets），不必出现在环境变量或配置文件里
+func lookup(key string) string {
+	lookedUp[key] = true
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		return readSecret(key, path)
+	}
+	if value := fileValues[key]; value != "" {
+		return value
+	}
+	if path := fileValues[key+"_FILE"]; path != "" {
+		return readSecret(key, path)
+	}
+	return ""
+}
+
+func readSecret(key, path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		loadErrs = append(loadErrs, fmt.Errorf("%s_FILE: %w", key, err))
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// unknownFileKeys 配置文件中没有被读取的键多半是拼写错误，报错而不是静默忽略
+func unknownFileKeys() []error {
+	var unknown []string
+	for key := range fileValues {
+		if !lookedUp[key] && !lookedUp[strings.TrimSuffix(key, "_FILE")] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+
+	errs := make([]error, 0, len(unknown))
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("unknown key %s in config file %s", key, filePath))
+	}
+	return errs
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+
+	defaultJWTSecret   = "your-secret-key"
+	minJWTSecretLength = 32
+
+	// DefaultAdminPassword 内置的默认管理员密码
+	DefaultAdminPassword = "admin123"
+)
+
+// 文档与示例配置中的占位密钥，照抄时同样视为默认值
+var placeholderJWTSecrets = []string{
+	defaultJWTSecret,
+	"your-jwt-secret-key-change-in-production",
+	"your-production-jwt-secret-key-min-32-chars",
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// validProxy 接受单个 IP 或 CIDR
+func validProxy(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
+
+// Validate 检查取值范围与相互关系，返回全部问题而不是只报第一个
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	positive := func(name string, value int) {
+		check(value > 0, "%s must be positive, got %d", name, value)
+	}
+	nonNegative := func(name string, value int) {
+		check(value >= 0, "%s must not be negative, got %d", name, value)
+	}
+	positiveDuration := func(name string, value time.Duration) {
+		check(value > 0, "%s must be positive, got %s", name, value)
+	}
+	// 0 表示不限制或关闭的时长只要求非负
+	nonNegativeDuration := func(name string, value time.Duration) {
+		check(value >= 0, "%s must not be negative, got %s", name, value)
+	}
+
+	check(oneOf(c.AppEnv, EnvDevelopment, EnvProduction), "APP_ENV must be development or production, got %q", c.AppEnv)
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "error"), "LOG_LEVEL must be debug, info, warn or error, got %q", c.LogLevel)
+
+	check(oneOf(c.DBDriver, "mysql", "postgres", "sqlite"), "DB_DRIVER must be mysql, postgres or sqlite, got %q", c.DBDriver)
+	check(c.DBPort > 0 && c.DBPort <= 65535, "DB_PORT %d is out of range", c.DBPort)
+	positive("DB_POOL_MAX_OPEN", c.DBPoolMaxOpen)
+	nonNegative("DB_POOL_MAX_IDLE", c.DBPoolMaxIdle)
+	nonNegativeDuration("DB_POOL_MAX_LIFETIME", c.DBPoolMaxLifetime)
+	check(c.DBPoolMaxIdle <= c.DBPoolMaxOpen, "DB_POOL_MAX_IDLE (%d) must not exceed DB_POOL_MAX_OPEN (%d)", c.DBPoolMaxIdle, c.DBPoolMaxOpen)
+
+	nonNegativeDuration("SERVER_READ_TIMEOUT", c.ServerReadTimeout)
+	nonNegativeDuration("SERVER_WRITE_TIMEOUT", c.ServerWriteTimeout)
+	nonNegativeDuration("SERVER_IDLE_TIMEOUT", c.ServerIdleTimeout)
+	for _, proxy := range c.TrustedProxies {
+		check(validProxy(proxy), "TRUSTED_PROXIES entry %q is not an IP address or CIDR", proxy)
+	}
+	positiveDuration("JWT_LIFETIME", c.JWTLifetime)
+	positiveDuration("JWT_MAX_REFRESH", c.JWTMaxRefresh)
+	positiveDuration("MFA_LOGIN_TIMEOUT", c.MFALoginTimeout)
+	check(c.MFALoginTimeout < c.JWTLifetime, "MFA_LOGIN_TIMEOUT (%s) must be shorter than JWT_LIFETIME (%s)", c.MFALoginTimeout, c.JWTLifetime)
+
+	check(c.SMTPPort > 0 && c.SMTPPort <= 65535, "SMTP_PORT %d is out of range", c.SMTPPort)
+	positive("MAIL_QUEUE_SIZE", c.MailQueueSize)
+	positive("MAIL_WORKERS", c.MailWorkers)
+
+	positiveDuration("GUEST_LIFETIME", c.GuestLifetime)
+	positiveDuration("GUEST_MAX_LIFETIME", c.GuestMaxLifetime)
+	positiveDuration("GUEST_REQUEST_TTL", c.GuestRequestTTL)
+	positiveDuration("GUEST_EXPIRY_INTERVAL", c.GuestExpiryInterval)
+
+	positive("PASSWORD_BCRYPT_COST", c.PasswordBcryptCost)
+	positive("PASSWORD_ARGON2_MEMORY", c.PasswordArgon2Memory)
+	positive("PASSWORD_ARGON2_TIME", c.PasswordArgon2Time)
+	positive("PASSWORD_ARGON2_THREADS", c.PasswordArgon2Threads)
+	positive("PASSWORD_MIN_LENGTH", c.PasswordMinLength)
+	nonNegative("PASSWORD_HISTORY_SIZE", c.PasswordHistorySize)
+	nonNegativeDuration("PASSWORD_MAX_AGE", c.PasswordMaxAge)
+	nonNegativeDuration("PASSWORD_EXPIRY_WARNING", c.PasswordExpiryWarning)
+	positiveDuration("PASSWORD_RESET_TTL", c.PasswordResetTTL)
+	positive("PASSWORD_RESET_RATE_LIMIT", c.PasswordResetRateLimit)
+	positiveDuration("PASSWORD_RESET_RATE_WINDOW", c.PasswordResetRateWindow)
+	// 未配置 SMTP 时邮件只写日志，重置令牌无法送达用户
+	check(!c.PasswordResetEnabled || c.SMTPHost != "", "PASSWORD_RESET_ENABLED requires SMTP_HOST")
+
+	positiveDuration("LDAP_TIMEOUT", c.LDAPTimeout)
+	positive("SCIM_MAX_RESULTS", c.SCIMMaxResults)
+	positive("IMPORT_MAX_ROWS", c.ImportMaxRows)
+	nonNegative("IMPORT_ASYNC_THRESHOLD", c.ImportAsyncThreshold)
+	positive("MAX_REQUEST_BODY_SIZE", c.MaxRequestBodySize)
+
+	nonNegativeDuration("AUTH_LOG_MAX_AGE", c.AuthLogMaxAge)
+	nonNegative("AUTH_LOG_MAX_ROWS", c.AuthLogMaxRows)
+	positiveDuration("AUTH_LOG_RETENTION_INTERVAL", c.AuthLogRetentionInterval)
+	positive("AUTH_LOG_RETENTION_BATCH_SIZE", c.AuthLogRetentionBatchSize)
+	positiveDuration("AUTH_STATS_ROLLUP_INTERVAL", c.AuthStatsRollupInterval)
+	positiveDuration("AUTH_STATS_HOURLY_RETENTION", c.AuthStatsHourlyRetention)
+	positive("AUTH_LOG_QUEUE_SIZE", c.AuthLogQueueSize)
+
+	positiveDuration("READINESS_DB_TIMEOUT", c.ReadinessDBTimeout)
+	nonNegativeDuration("SHUTDOWN_DELAY", c.ShutdownDelay)
+	positiveDuration("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	check(oneOf(c.AuthLogArchive, "none", "local", "s3"), "AUTH_LOG_ARCHIVE must be none, local or s3, got %q", c.AuthLogArchive)
+	positiveDuration("SETTINGS_REFRESH_INTERVAL", c.SettingsRefreshInterval)
+	check(c.OTelSampleRatio >= 0 && c.OTelSampleRatio <= 1, "OTEL_TRACES_SAMPLER_ARG must be between 0 and 1, got %g", c.OTelSampleRatio)
+
+	return errors.Join(errs...)
+}
+
+// unsafeDefaults 仍在使用的默认密钥
+func (c *Config) unsafeDefaults() []string {
+	var problems []string
+	if oneOf(c.JWTSecret, placeholderJWTSecrets...) {
+		problems = append(problems, "JWT_SECRET is a default or example value")
+	} else if len(c.JWTSecret) < minJWTSecretLength {
+		problems = append(problems, fmt.Sprintf("JWT_SECRET is shorter than %d characters", minJWTSecretLength))
+	}
+	if c.DefaultAdminPass == DefaultAdminPassword {
+		problems = append(problems, "DEFAULT_ADMIN_PASSWORD is the built-in default")
+	}
+	return problems
+}
+
+// RequireSafeSecrets 由服务端启动时调用：production 下仍使用默认密钥时拒绝启动，development 下只打印警告。
+// 命令行工具不签发令牌，不做此检查
+func (c *Config) RequireSafeSecrets() error {
+	return c.refuseInProduction(c.unsafeDefaults())
+}
+
+// RequireChangedAdminPassword 连接数据库后调用。DEFAULT_ADMIN_PASSWORD 只在首次创建管理员时使用，
+// 修改配置不会改变已存的密码，因此由调用方检查库中默认管理员的哈希是否仍匹配内置密码
+func (c *Config) RequireChangedAdminPassword(stillDefault bool) error {
+	if !stillDefault {
+		return nil
+	}
+	return c.refuseInProduction([]string{fmt.Sprintf("admin user %q still has the built-in default password", c.DefaultAdminUser)})
+}
+
+func (c *Config) refuseInProduction(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	if c.AppEnv == EnvProduction {
+		errs := make([]error, 0, len(problems))
+		for _, problem := range problems {
+			errs = append(errs, errors.New(problem))
+		}
+		return fmt.Errorf("refusing to start in production with unsafe defaults: %w", errors.Join(errs...))
+	}
+	for _, problem := range problems {
+		log.Printf("Warning: %s, which is refused when APP_ENV=production", problem)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/authstats"
+)
+
+type AnalyticsController struct{}
+
+// parseAnalyticsTime 与列表筛选相同支持 RFC 3339 与 YYYY-MM-DD，日期按 tz 时区解析
+func parseAnalyticsTime(value string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d, nil
+}
+
+// Get 认证统计：from / to 默认最近 24 小时，interval 为 minute、hour（默认）或 day，
+// tz 为 IANA 时区名（默认 UTC），top 为失败次数最多的用户数（默认 10）
+func (ac *AnalyticsController) Get(ctx context.Context, c *app.RequestContext) {
+	badRequest := func(message string) {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": message,
+		})
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		badRequest("Invalid tz, expected an IANA time zone name")
+		return
+	}
+
+	q := authstats.Query{
+		To:       time.Now(),
+		Interval: c.DefaultQuery("interval", authstats.IntervalHour),
+		Location: loc,
+	}
+	if value := c.Query("to"); value != "" {
+		if q.To, err = parseAnalyticsTime(value, loc, true); err != nil {
+			badRequest("invalid to, expected RFC 3339 or YYYY-MM-DD")
+			return
+		}
+	}
+	q.From = q.To.Add(-24 * time.Hour)
+	if value := c.Query("from"); value != "" {
+		if q.From, err = parseAnalyticsTime(value, loc, false); err != nil {
+			badRequest("invalid from, expected RFC 3339 or YYYY-MM-DD")
+			return
+		}
+	}
+	if q.Top, err = strconv.Atoi(c.DefaultQuery("top", "10")); err != nil {
+		badRequest("invalid top")
+		return
+	}
+	if err := q.Validate(); err != nil {
+		badRequest(err.Error())
+		return
+	}
+
+	report, err := authstats.Analyze(ctx, q)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to get auth analytics",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": report,
+	})
+}
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/settings"
+)
+
+// 导出时每批读取的日志条数
+const authLogExportBatchSize = 1000
+
+// total=approx 且带筛选条件时最多数到这么多条
+const authLogApproxCountCap = 10000
+
+var authLogCSVHeader = []string{"id", "created_at", "username", "auth_type", "success", "reason", "ip_address", "device_mac", "target_ssid", "user_agent"}
+
+func newAuthLogResponse(log *models.AuthLog) AuthLogResponse {
+	return AuthLogResponse{
+		ID:         log.ID,
+		Username:   log.Username,
+		AuthType:   log.AuthType,
+		Success:    log.Success,
+		IPAddress:  log.IPAddress,
+		UserAgent:  log.UserAgent,
+		DeviceMAC:  log.DeviceMAC,
+		TargetSSID: log.TargetSSID,
+		Reason:     log.Reason,
+		CreatedAt:  log.CreatedAt.Unix(),
+	}
+}
+
+// authLogFilterFromQuery 列表与导出共用的筛选参数：username、success、auth_type、nas_ip、
+// device_mac、ssid、reason 精确匹配，from / to 为时间范围（RFC 3339 或 YYYY-MM-DD）
+func authLogFilterFromQuery(c *app.RequestContext) (dao.AuthLogFilter, error) {
+	filter := dao.AuthLogFilter{
+		Username:   c.Query("username"),
+		AuthType:   c.Query("auth_type"),
+		IPAddress:  c.Query("nas_ip"),
+		DeviceMAC:  c.Query("device_mac"),
+		TargetSSID: c.Query("ssid"),
+		Reason:     c.Query("reason"),
+	}
+
+	var err error
+	if filter.Success, err = parseListBool(c.Query("success")); err != nil {
+		return filter, errors.New("invalid success")
+	}
+	if filter.Since, err = parseListTime(c.Query("from"), false); err != nil {
+		return filter, errors.New("invalid from, expected RFC 3339 or YYYY-MM-DD")
+	}
+	if filter.Until, err = parseListTime(c.Query("to"), true); err != nil {
+		return filter, errors.New("invalid to, expected RFC 3339 or YYYY-MM-DD")
+	}
+	return filter, nil
+}
+
+// encodeAuthLogCursor 游标对客户端不透明，内容为 created_at 纳秒时间戳与 ID
+func encodeAuthLogCursor(log *models.AuthLog) string {
+	raw := strconv.FormatInt(log.CreatedAt.UnixNano(), 10) + "." + strconv.FormatUint(uint64(log.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAuthLogCursor(cursor string) (*dao.AuthLogCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	nanos, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	ns, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	logID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return &dao.AuthLogCursor{CreatedAt: time.Unix(0, ns), ID: uint(logID)}, nil
+}
+
+// ListAuthLogsV2 游标分页的认证日志列表，筛选参数与 v1 相同。
+// cursor 为上一页返回的 next_cursor；total=exact 精确计数，total=approx 返回估计值，默认不计数
+func (uc *UserController) ListAuthLogsV2(ctx context.Context, c *app.RequestContext) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > settings.MaxAuthLogPageSize.Get() {
+		limit = 50
+	}
+
+	filter, err := authLogFilterFromQuery(c)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	var after *dao.AuthLogCursor
+	if cursor := c.Query("cursor"); cursor != "" {
+		if after, err = decodeAuthLogCursor(cursor); err != nil {
+			c.JSON(consts.StatusBadRequest, map[string]interface{}{
+				"code":    consts.StatusBadRequest,
+				"message": "Invalid cursor",
+			})
+			return
+		}
+	}
+
+	totalMode := c.DefaultQuery("total", "none")
+	if totalMode != "none" && totalMode != "exact" && totalMode != "approx" {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "total must be none, exact or approx",
+		})
+		return
+	}
+
+	// 多取一条用于判断是否还有下一页
+	logs, err := database.DAO.AuthLog.ListPage(ctx, filter, after, limit+1)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to get auth logs",
+		})
+		return
+	}
+	hasMore := len(logs) > limit
+	if hasMore {
+		logs = logs[:limit]
+	}
+
+	logResponses := make([]AuthLogResponse, len(logs))
+	for i := range logs {
+		logResponses[i] = newAuthLogResponse(&logs[i])
+	}
+	data := map[string]interface{}{
+		"logs":     logResponses,
+		"has_more": hasMore,
+	}
+	if hasMore {
+		data["next_cursor"] = encodeAuthLogCursor(&logs[len(logs)-1])
+	}
+
+	if totalMode != "none" {
+		total, approximate, err := countAuthLogs(ctx, filter, totalMode == "approx")
+		if err != nil {
+			c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+				"code":    consts.StatusInternalServerError,
+				"message": "Failed to count auth logs",
+			})
+			return
+		}
+		data["total"] = total
+		data["total_approximate"] = approximate
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": data,
+	})
+}
+
+// countAuthLogs approx 时无筛选条件读取表统计信息，有筛选条件时最多数到 authLogApproxCountCap 条
+func countAuthLogs(ctx context.Context, filter dao.AuthLogFilter, approx bool) (int64, bool, error) {
+	if !approx {
+		total, err := database.DAO.AuthLog.Count(ctx, filter)
+		return total, false, err
+	}
+
+	if filter == (dao.AuthLogFilter{}) {
+		if total, ok, err := database.DAO.AuthLog.EstimateTotal(ctx); err != nil || ok {
+			return total, true, err
+		}
+	}
+	total, err := database.DAO.AuthLog.CountUpTo(ctx, filter, authLogApproxCountCap)
+	return total, total >= authLogApproxCountCap, err
+}
+
+// ExportAuthLogs 按与列表相同的筛选条件流式导出认证日志，format 为 csv（默认）或 ndjson
+func (uc *UserController) ExportAuthLogs(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if format != "csv" && format != "ndjson" {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "format must be csv or ndjson",
+		})
+		return
+	}
+
+	filter, err := authLogFilterFromQuery(c)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &currentUser.UserID,
+		ActorName:  currentUser.Username,
+		Action:     "auth_log.export",
+		TargetType: "auth_log",
+		Detail:     fmt.Sprintf("format=%s, query=%s", format, c.Request.URI().QueryString()),
+	})
+
+	// 与用户导出相同，响应体在处理函数返回后才被读取，客户端断开时管道关闭，导出随之终止
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(exportAuthLogs(context.Background(), writer, format, filter))
+	}()
+
+	contentType := "text/csv; charset=utf-8"
+	if format == "ndjson" {
+		contentType = "application/x-ndjson"
+	}
+	filename := fmt.Sprintf("auth-logs-%s.%s", time.Now().Format("20060102-150405"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Response.Header.SetContentType(contentType)
+	c.SetBodyStream(reader, -1)
+}
+
+func exportAuthLogs(ctx context.Context, w io.Writer, format string, filter dao.AuthLogFilter) error {
+	buffered := bufio.NewWriter(w)
+
+	var write func(logs []models.AuthLog) error
+	if format == "ndjson" {
+		encoder := json.NewEncoder(buffered)
+		write = func(logs []models.AuthLog) error {
+			for i := range logs {
+				if err := encoder.Encode(&logs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	} else {
+		writer := csv.NewWriter(buffered)
+		if err := writer.Write(authLogCSVHeader); err != nil {
+			return err
+		}
+		write = func(logs []models.AuthLog) error {
+			for _, log := range logs {
+				if err := writer.Write([]string{
+					strconv.FormatUint(uint64(log.ID), 10),
+					log.CreatedAt.Format(time.RFC3339),
+					log.Username,
+					log.AuthType,
+					strconv.FormatBool(log.Success),
+					log.Reason,
+					log.IPAddress,
+					log.DeviceMAC,
+					log.TargetSSID,
+					log.UserAgent,
+				}); err != nil {
+					return err
+				}
+			}
+			writer.Flush()
+			return writer.Error()
+		}
+	}
+
+	if err := database.DAO.AuthLog.Each(ctx, filter, authLogExportBatchSize, write); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/authlog"
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/lifecycle"
+)
+
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+// 队列占用超过该比例时报告降级，继续升高会开始丢弃认证日志
+const authLogQueueDegradedRatio = 0.8
+
+type ComponentHealth struct {
+	Status  string      `json:"status"`
+	Message string      `json:"message,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+type HealthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+type HealthController struct {
+	mu sync.Mutex
+	// 上次检查时的累计丢弃与写入失败数，两次检查之间有新增即报告降级
+	lastDropped int64
+	lastFailed  int64
+}
+
+// Liveness 进程能处理请求即返回 200，不检查依赖，避免数据库故障时容器被反复重启
+func (hc *HealthController) Liveness(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, HealthResponse{Status: HealthOK})
+}
+
+// Readiness 检查进程是否正在退出，以及数据库、表结构迁移与认证日志队列。有组件不可用时返回 503，负载均衡应摘除该实例；
+// 降级时仍返回 200 以继续接收流量
+func (hc *HealthController) Readiness(ctx context.Context, c *app.RequestContext) {
+	components := map[string]ComponentHealth{
+		"server":         hc.checkServer(),
+		"database":       hc.checkDatabase(ctx),
+		"migrations":     hc.checkMigrations(ctx),
+		"auth_log_queue": hc.checkAuthLogQueue(),
+	}
+
+	status := HealthOK
+	for _, component := range components {
+		switch component.Status {
+		case HealthUnavailable:
+			status = HealthUnavailable
+		case HealthDegraded:
+			if status == HealthOK {
+				status = HealthDegraded
+			}
+		}
+	}
+
+	code := consts.StatusOK
+	if status == HealthUnavailable {
+		code = consts.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, HealthResponse{Status: status, Components: components})
+}
+
+func (hc *HealthController) checkServer() ComponentHealth {
+	if lifecycle.ShuttingDown() {
+		return ComponentHealth{Status: HealthUnavailable, Message: "shutting down"}
+	}
+	return ComponentHealth{Status: HealthOK}
+}
+
+func (hc *HealthController) checkDatabase(ctx context.Context) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.ReadinessDBTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := database.Ping(ctx)
+	latency := map[string]int64{"latency_ms": time.Since(start).Milliseconds()}
+	if err != nil {
+		return ComponentHealth{Status: HealthUnavailable, Message: err.Error(), Details: latency}
+	}
+	return ComponentHealth{Status: HealthOK, Details: latency}
+}
+
+// checkMigrations 每次读取 schema_migrations，滚动升级时其他实例执行的迁移也能反映出来
+func (hc *HealthController) checkMigrations(ctx context.Context) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, config.AppConfig.ReadinessDBTimeout)
+	defer cancel()
+
+	state, err := database.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		return ComponentHealth{Status: HealthUnavailable, Message: err.Error()}
+	case state.Dirty:
+		return ComponentHealth{Status: HealthUnavailable, Message: "a migration failed part way and needs manual repair", Details: state}
+	case state.Pending():
+		return ComponentHealth{Status: HealthUnavailable, Message: "database schema has pending migrations", Details: state}
+	case state.Version > state.Latest:
+		// 新版本实例已执行了迁移，本实例在被替换前仍可继续服务
+		return ComponentHealth{Status: HealthDegraded, Message: "database schema is newer than this build", Details: state}
+	}
+	return ComponentHealth{Status: HealthOK, Details: state}
+}
+
+func (hc *HealthController) checkAuthLogQueue() ComponentHealth {
+	stats := authlog.Stats()
+	if !stats.Started {
+		return ComponentHealth{Status: HealthUnavailable, Message: "auth log writer is not running", Details: stats}
+	}
+
+	hc.mu.Lock()
+	newDrops := stats.Dropped > hc.lastDropped
+	newFailures := stats.Failed > hc.lastFailed
+	hc.lastDropped, hc.lastFailed = stats.Dropped, stats.Failed
+	hc.mu.Unlock()
+
+	switch {
+	case newDrops:
+		return ComponentHealth{Status: HealthDegraded, Message: "auth logs were dropped since the last check", Details: stats}
+	case newFailures:
+		return ComponentHealth{Status: HealthDegraded, Message: "auth log writes failed since the last check", Details: stats}
+	case float64(stats.Depth) >= authLogQueueDegradedRatio*float64(stats.Capacity):
+		return ComponentHealth{Status: HealthDegraded, Message: "auth log queue is nearly full", Details: stats}
+	}
+	return ComponentHealth{Status: HealthOK, Details: stats}
+}
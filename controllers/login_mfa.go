@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/mfa"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// LoginMFAController 处理 Web 登录的第二步，调用方持有登录接口返回的中间令牌
+type LoginMFAController struct{}
+
+// VerifyCode 使用 TOTP 验证码或恢复码完成登录
+func (lc *LoginMFAController) VerifyCode(ctx context.Context, c *app.RequestContext) {
+	user, ok := lc.getPendingUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	valid, err := mfa.Verify(ctx, user.ID, req.Code)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to verify code",
+		})
+		return
+	}
+	if !valid {
+		mfa.Attempts.Fail(user.ID)
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Invalid verification code",
+		})
+		return
+	}
+
+	mfa.Attempts.Reset(user.ID)
+	middleware.IssueToken(ctx, c, user)
+}
+
+func (lc *LoginMFAController) BeginWebAuthn(ctx context.Context, c *app.RequestContext) {
+	user, ok := lc.getPendingUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	assertion, err := mfa.BeginWebAuthnLogin(ctx, user)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Failed to start security key login",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": assertion,
+	})
+}
+
+func (lc *LoginMFAController) FinishWebAuthn(ctx context.Context, c *app.RequestContext) {
+	user, ok := lc.getPendingUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := mfa.FinishWebAuthnLogin(ctx, user, c.Request.Body()); err != nil {
+		mfa.Attempts.Fail(user.ID)
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Security key verification failed",
+		})
+		return
+	}
+
+	mfa.Attempts.Reset(user.ID)
+	middleware.IssueToken(ctx, c, user)
+}
+
+// getPendingUser 根据中间令牌加载用户并检查失败次数，失败时已写入响应
+func (lc *LoginMFAController) getPendingUser(ctx context.Context, c *app.RequestContext) (*models.User, bool) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return nil, false
+	}
+
+	if !mfa.Attempts.Allowed(currentUser.UserID) {
+		c.JSON(consts.StatusTooManyRequests, map[string]interface{}{
+			"code":    consts.StatusTooManyRequests,
+			"message": "Too many failed attempts, please sign in again later",
+		})
+		return nil, false
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, currentUser.UserID)
+	if err != nil || user.Banned || user.Status != models.UserStatusActive || user.IsExpired() {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,18 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+)
+
+// Metrics 以 Prometheus 文本格式输出指标
+func Metrics(ctx context.Context, c *app.RequestContext) {
+	var buf bytes.Buffer
+	metrics.WriteTo(&buf)
+	c.Data(consts.StatusOK, metrics.ContentType, buf.Bytes())
+}
@@ -0,0 +1,608 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/mfa"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type MFAController struct{}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type AdminSetMFARequiredRequest struct {
+	Required bool `json:"required"`
+}
+
+func (mc *MFAController) GetStatus(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "User not found",
+		})
+		return
+	}
+
+	required, err := mfa.IsRequired(ctx, user)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to get MFA status",
+		})
+		return
+	}
+
+	enabled, err := mfa.IsEnrolled(ctx, user.ID)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to get MFA status",
+		})
+		return
+	}
+
+	remaining, err := database.DAO.MFA.CountUnusedBackupCodes(ctx, user.ID)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to get MFA status",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": map[string]interface{}{
+			"enabled":                enabled,
+			"required":               required,
+			"backup_codes_remaining": remaining,
+		},
+	})
+}
+
+// Enroll 生成新的 TOTP 密钥，需调用 Activate 校验验证码后才会生效
+func (mc *MFAController) Enroll(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	existing, err := database.DAO.MFA.GetByUserID(ctx, currentUser.UserID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to enroll MFA",
+		})
+		return
+	}
+	if existing != nil && existing.Enabled {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "MFA is already enabled",
+		})
+		return
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to generate secret",
+		})
+		return
+	}
+
+	record := existing
+	if record == nil {
+		record = &models.UserMFA{UserID: currentUser.UserID}
+	}
+	record.Secret = secret
+	record.Enabled = false
+	record.LastUsedStep = 0
+
+	if err := database.DAO.MFA.Save(ctx, record); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to enroll MFA",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": map[string]interface{}{
+			"secret":           secret,
+			"provisioning_uri": mfa.ProvisioningURI(config.AppConfig.MFAIssuer, currentUser.Username, secret),
+		},
+	})
+}
+
+// Activate 校验首个验证码后启用 TOTP，并返回一次性备用码
+func (mc *MFAController) Activate(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	var req MFACodeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	record, err := database.DAO.MFA.GetByUserID(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "MFA enrollment not started",
+		})
+		return
+	}
+	if record.Enabled {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "MFA is already enabled",
+		})
+		return
+	}
+
+	step, ok := mfa.ValidateTOTP(record.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid verification code",
+		})
+		return
+	}
+
+	codes, err := replaceBackupCodes(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to generate backup codes",
+		})
+		return
+	}
+
+	if err := database.DAO.MFA.Enable(ctx, currentUser.UserID, step); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to enable MFA",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "MFA enabled successfully",
+		"data": map[string]interface{}{
+			"backup_codes": codes,
+		},
+	})
+}
+
+// RegenerateBackupCodes 使用当前验证码换取一组新的备用码，旧备用码全部作废
+func (mc *MFAController) RegenerateBackupCodes(ctx context.Context, c *app.RequestContext) {
+	currentUser, ok := mc.verifyCurrentUserCode(ctx, c)
+	if !ok {
+		return
+	}
+
+	codes, err := replaceBackupCodes(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to generate backup codes",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": map[string]interface{}{
+			"backup_codes": codes,
+		},
+	})
+}
+
+func (mc *MFAController) Disable(ctx context.Context, c *app.RequestContext) {
+	currentUser, ok := mc.verifyCurrentUserCode(ctx, c)
+	if !ok {
+		return
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "User not found",
+		})
+		return
+	}
+
+	required, err := mfa.IsRequired(ctx, user)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to disable MFA",
+		})
+		return
+	}
+	if required {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "MFA is required for this account",
+		})
+		return
+	}
+
+	keys, err := database.DAO.WebAuthn.CountByUserID(ctx, currentUser.UserID)
+	if err == nil {
+		if keys > 0 {
+			err = database.DAO.MFA.DeleteTOTP(ctx, currentUser.UserID)
+		} else {
+			err = database.DAO.MFA.Delete(ctx, currentUser.UserID)
+		}
+	}
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to disable MFA",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "MFA disabled successfully",
+	})
+}
+
+func (mc *MFAController) ListWebAuthnCredentials(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	creds, err := database.DAO.WebAuthn.ListByUserID(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to fetch security keys",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": creds,
+	})
+}
+
+func (mc *MFAController) BeginWebAuthnRegistration(ctx context.Context, c *app.RequestContext) {
+	user, ok := mc.getCurrentUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	creation, err := mfa.BeginWebAuthnRegistration(ctx, user)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to start security key registration",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": creation,
+	})
+}
+
+// FinishWebAuthnRegistration 请求体为浏览器返回的 PublicKeyCredential，名称通过 name 查询参数传入。
+// 首次绑定且没有可用恢复码时一并生成恢复码
+func (mc *MFAController) FinishWebAuthnRegistration(ctx context.Context, c *app.RequestContext) {
+	user, ok := mc.getCurrentUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	cred, err := mfa.FinishWebAuthnRegistration(ctx, user, c.Request.Body(), c.Query("name"))
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Security key registration failed",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	data := map[string]interface{}{
+		"credential": cred,
+	}
+
+	remaining, err := database.DAO.MFA.CountUnusedBackupCodes(ctx, user.ID)
+	if err == nil && remaining == 0 {
+		if codes, err := replaceBackupCodes(ctx, user.ID); err == nil {
+			data["backup_codes"] = codes
+		}
+	}
+
+	c.JSON(consts.StatusCreated, map[string]interface{}{
+		"code":    consts.StatusCreated,
+		"message": "Security key registered successfully",
+		"data":    data,
+	})
+}
+
+// DeleteWebAuthnCredential 与关闭 TOTP 相同，须提供当前验证码或恢复码，仅凭会话令牌不能移除第二因素
+func (mc *MFAController) DeleteWebAuthnCredential(ctx context.Context, c *app.RequestContext) {
+	credID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid credential ID",
+		})
+		return
+	}
+
+	currentUser, ok := mc.verifyCurrentUserCode(ctx, c)
+	if !ok {
+		return
+	}
+
+	deleted, err := database.DAO.WebAuthn.Delete(ctx, currentUser.UserID, uint(credID))
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to delete security key",
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "Security key not found",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Security key deleted successfully",
+	})
+}
+
+// AdminResetMFA 清除用户的 TOTP、安全密钥和备用码，用于设备丢失等情况
+func (mc *MFAController) AdminResetMFA(ctx context.Context, c *app.RequestContext) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, uint(userID))
+	if err != nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "User not found",
+		})
+		return
+	}
+
+	if err := database.DAO.MFA.Delete(ctx, user.ID); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to reset MFA",
+		})
+		return
+	}
+
+	if err := database.DAO.WebAuthn.DeleteByUserID(ctx, user.ID); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to reset MFA",
+		})
+		return
+	}
+
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &currentUser.UserID,
+		ActorName:  currentUser.Username,
+		Action:     "mfa.reset",
+		TargetType: "user",
+		TargetID:   user.ID,
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "MFA reset successfully",
+	})
+}
+
+func (mc *MFAController) AdminSetMFARequired(ctx context.Context, c *app.RequestContext) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid user ID",
+		})
+		return
+	}
+
+	var req AdminSetMFARequiredRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, uint(userID))
+	if err != nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "User not found",
+		})
+		return
+	}
+
+	if err := database.DAO.User.UpdateRequireMFA(ctx, user.ID, req.Required); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to update MFA requirement",
+		})
+		return
+	}
+
+	user.RequireMFA = req.Required
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": fmt.Sprintf("MFA requirement set to %t", req.Required),
+		"data":    user.ToResponse(),
+	})
+}
+
+func (mc *MFAController) getCurrentUser(ctx context.Context, c *app.RequestContext) (*models.User, bool) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return nil, false
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, currentUser.UserID)
+	if err != nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "User not found",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// verifyCurrentUserCode 校验当前用户提交的验证码，与登录第二步共用失败计数，防止持有会话者穷举验证码；失败时已写入响应
+func (mc *MFAController) verifyCurrentUserCode(ctx context.Context, c *app.RequestContext) (*middleware.Claims, bool) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return nil, false
+	}
+
+	if !mfa.Attempts.Allowed(currentUser.UserID) {
+		c.JSON(consts.StatusTooManyRequests, map[string]interface{}{
+			"code":    consts.StatusTooManyRequests,
+			"message": "Too many failed attempts, please try again later",
+		})
+		return nil, false
+	}
+
+	var req MFACodeRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return nil, false
+	}
+
+	ok, err := mfa.Verify(ctx, currentUser.UserID, req.Code)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to verify code",
+		})
+		return nil, false
+	}
+	if !ok {
+		mfa.Attempts.Fail(currentUser.UserID)
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid verification code",
+		})
+		return nil, false
+	}
+
+	mfa.Attempts.Reset(currentUser.UserID)
+	return currentUser, true
+}
+
+func replaceBackupCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes, err := mfa.GenerateBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = mfa.HashBackupCode(code)
+	}
+
+	if err := database.DAO.MFA.ReplaceBackupCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/hertz-contrib/jwt"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/mfa"
+)
+
+func TestVerifyCurrentUserCodeLimitsFailures(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+	const userID = 42
+	defer mfa.Attempts.Reset(userID)
+
+	codes, err := replaceBackupCodes(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regenerate := func(code string) int {
+		body := `{"code":"` + code + `"}`
+		c := ut.CreateUtRequestContext(consts.MethodPost, "/api/v1/mfa/backup-codes", &ut.Body{Body: strings.NewReader(body), Len: len(body)},
+			ut.Header{Key: "Content-Type", Value: "application/json"})
+		c.Set("JWT_PAYLOAD", jwt.MapClaims{"user_id": float64(userID)})
+		(&MFAController{}).RegenerateBackupCodes(ctx, c)
+		return c.Response.StatusCode()
+	}
+
+	for i := 0; i < 5; i++ {
+		if got := regenerate("000000"); got != consts.StatusBadRequest {
+			t.Fatalf("attempt %d: status = %d, want %d", i+1, got, consts.StatusBadRequest)
+		}
+	}
+	// 达到上限后即使验证码正确也拒绝，且不消耗备用码
+	if got := regenerate(codes[0]); got != consts.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", got, consts.StatusTooManyRequests)
+	}
+
+	mfa.Attempts.Reset(userID)
+	if got := regenerate(codes[0]); got != consts.StatusOK {
+		t.Fatalf("status after reset = %d, want %d", got, consts.StatusOK)
+	}
+	if ok, err := database.DAO.MFA.ConsumeBackupCode(ctx, userID, mfa.HashBackupCode(codes[1])); err != nil || ok {
+		t.Errorf("old backup code still valid after regeneration: %v, %v", ok, err)
+	}
+}
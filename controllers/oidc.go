@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/sso"
+)
+
+type OIDCController struct{}
+
+// Login 跳转到 IdP 的授权页面，流程状态写入仅回调地址可见的 HttpOnly Cookie
+func (oc *OIDCController) Login(ctx context.Context, c *app.RequestContext) {
+	if sso.Default == nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": sso.ErrDisabled.Error(),
+		})
+		return
+	}
+
+	authURL, cookie, err := sso.Default.AuthCodeURL()
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		c.JSON(consts.StatusBadGateway, map[string]interface{}{
+			"code":    consts.StatusBadGateway,
+			"message": "Identity provider is unavailable",
+		})
+		return
+	}
+
+	oc.setLoginCookie(c, cookie)
+	c.Redirect(consts.StatusFound, []byte(authURL))
+}
+
+// Callback IdP 回调，校验通过后签发与密码登录相同的 JWT，
+// 以 URL 片段的形式带回前端，避免令牌出现在访问日志与 Referer 中
+func (oc *OIDCController) Callback(ctx context.Context, c *app.RequestContext) {
+	if sso.Default == nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": sso.ErrDisabled.Error(),
+		})
+		return
+	}
+
+	// 状态 Cookie 只用一次，无论结果如何都清除
+	cookie := string(c.Cookie(sso.LoginCookie))
+	oc.setLoginCookie(c, "")
+
+	if idpError := c.Query("error"); idpError != "" {
+		log.Printf("OIDC login rejected by identity provider: %s %s", idpError, c.Query("error_description"))
+		oc.redirect(c, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	user, err := sso.Default.Exchange(ctx, cookie, c.Query("state"), c.Query("code"))
+	if err != nil {
+		switch {
+		case errors.Is(err, sso.ErrInvalidState):
+			oc.redirect(c, url.Values{"error": {"invalid_state"}})
+		case errors.Is(err, sso.ErrNotAllowed):
+			oc.redirect(c, url.Values{"error": {"access_denied"}})
+		default:
+			log.Printf("OIDC callback failed: %v", err)
+			oc.redirect(c, url.Values{"error": {"server_error"}})
+		}
+		return
+	}
+
+	token, expire, err := middleware.JWTMiddleware.TokenGenerator(user)
+	if err != nil {
+		oc.redirect(c, url.Values{"error": {"server_error"}})
+		return
+	}
+	middleware.RecordLogin(user.ID)
+
+	oc.redirect(c, url.Values{
+		"token":  {token},
+		"expire": {expire.Format(time.RFC3339)},
+	})
+}
+
+// setLoginCookie 写入或（value 为空时）删除状态 Cookie，Path 取回调地址的路径；
+// IdP 回调是跨站的顶级跳转，SameSite=Lax 下 Cookie 仍会带上
+func (oc *OIDCController) setLoginCookie(c *app.RequestContext, value string) {
+	path := "/"
+	if u, err := url.Parse(config.AppConfig.OIDCRedirectURL); err == nil && u.Path != "" {
+		path = u.Path
+	}
+
+	cookie := protocol.AcquireCookie()
+	defer protocol.ReleaseCookie(cookie)
+	cookie.SetKey(sso.LoginCookie)
+	cookie.SetValue(value)
+	if value == "" {
+		cookie.SetExpire(protocol.CookieExpireDelete)
+	} else {
+		cookie.SetMaxAge(int(sso.LoginTTL / time.Second))
+	}
+	cookie.SetPath(path)
+	cookie.SetHTTPOnly(true)
+	cookie.SetSecure(strings.HasPrefix(config.AppConfig.OIDCRedirectURL, "https://"))
+	cookie.SetSameSite(protocol.CookieSameSiteLaxMode)
+	c.Response.Header.SetCookie(cookie)
+}
+
+func (oc *OIDCController) redirect(c *app.RequestContext, fragment url.Values) {
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(consts.StatusFound, []byte(config.AppConfig.OIDCPostLoginRedirect+"#"+fragment.Encode()))
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+func TestLoginCookieIsScopedToCallback(t *testing.T) {
+	config.AppConfig = &config.Config{OIDCRedirectURL: "https://radius.example.com/api/v1/auth/oidc/callback"}
+	oc := &OIDCController{}
+
+	c := ut.CreateUtRequestContext(consts.MethodGet, "/api/v1/auth/oidc/login", nil)
+	oc.setLoginCookie(c, "payload.signature")
+	set := string(c.Response.Header.Peek("Set-Cookie"))
+	for _, want := range []string{"oidc_login=payload.signature", "max-age=600", "path=/api/v1/auth/oidc/callback", "HttpOnly", "secure", "SameSite=Lax"} {
+		if !strings.Contains(set, want) {
+			t.Errorf("Set-Cookie %q lacks %q", set, want)
+		}
+	}
+
+	c = ut.CreateUtRequestContext(consts.MethodGet, "/api/v1/auth/oidc/callback", nil)
+	oc.setLoginCookie(c, "")
+	if set := string(c.Response.Header.Peek("Set-Cookie")); !strings.Contains(set, "oidc_login=;") || !strings.Contains(set, "expires=Tue, 10 Nov 2009") {
+		t.Errorf("Set-Cookie %q does not delete the cookie", set)
+	}
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/mailer"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/passreset"
+)
+
+type PasswordResetController struct {
+	// accountLimiter 限制同一账号收到重置邮件的频率，超限时静默丢弃
+	accountLimiter *middleware.RateLimiter
+}
+
+func NewPasswordResetController() *PasswordResetController {
+	return &PasswordResetController{
+		accountLimiter: middleware.NewRateLimiter(3, time.Hour),
+	}
+}
+
+type ForgotPasswordRequest struct {
+	// Identifier 用户名或邮箱
+	Identifier string `json:"identifier" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ForgotPassword 无论账号是否存在都返回相同响应，实际的查询与发信在后台进行，避免通过响应内容或耗时枚举账号
+func (pc *PasswordResetController) ForgotPassword(ctx context.Context, c *app.RequestContext) {
+	if !config.AppConfig.PasswordResetEnabled {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "Password reset is disabled",
+		})
+		return
+	}
+
+	var req ForgotPasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 队列已满时同样返回 202，丢弃计入 radius_manager_mail_dropped_total
+	requestIP := c.ClientIP()
+	mailer.Submit(func(ctx context.Context) {
+		pc.sendResetEmail(ctx, req.Identifier, requestIP)
+	})
+
+	c.JSON(consts.StatusAccepted, map[string]interface{}{
+		"code":    consts.StatusAccepted,
+		"message": "If the account exists, a password reset email has been sent",
+	})
+}
+
+func (pc *PasswordResetController) sendResetEmail(ctx context.Context, identifier, requestIP string) {
+	user, err := database.DAO.User.GetByUsername(ctx, identifier)
+	if err != nil {
+		user, err = database.DAO.User.GetByEmail(ctx, identifier)
+	}
+	if err != nil || !passreset.Eligible(user) {
+		return
+	}
+
+	if !pc.accountLimiter.Allow(strconv.FormatUint(uint64(user.ID), 10)) {
+		log.Printf("Password reset for %s rate limited", user.Username)
+		return
+	}
+
+	token, err := passreset.Issue(ctx, user, requestIP)
+	if err != nil {
+		log.Printf("Failed to issue password reset token for %s: %v", user.Username, err)
+		return
+	}
+
+	instructions := "Your reset token is:\n\n" + token
+	if base := config.AppConfig.PasswordResetURL; base != "" {
+		if link, err := url.Parse(base); err == nil {
+			query := link.Query()
+			query.Set("token", token)
+			link.RawQuery = query.Encode()
+			instructions = "Open the following link to choose a new password:\n\n" + link.String()
+		}
+	}
+
+	err = mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Password reset request",
+		Body: fmt.Sprintf("Hello %s,\n\nA password reset was requested for your account from %s.\n\n%s\n\nThis token expires in %s and can be used once. If you did not request it, you can ignore this email.\n",
+			user.Username, requestIP, instructions, config.AppConfig.PasswordResetTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
+	}
+}
+
+func (pc *PasswordResetController) ResetPassword(ctx context.Context, c *app.RequestContext) {
+	if !config.AppConfig.PasswordResetEnabled {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "Password reset is disabled",
+		})
+		return
+	}
+
+	var req ResetPasswordRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	token, err := passreset.Lookup(ctx, req.Token)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid or expired reset token",
+		})
+		return
+	}
+
+	// 先校验新密码，避免因密码不合规而浪费令牌
+	if err := passpolicy.Check(ctx, token.User, req.NewPassword); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+
+	if err := token.Consume(ctx); err != nil {
+		if errors.Is(err, passreset.ErrInvalidToken) {
+			c.JSON(consts.StatusBadRequest, map[string]interface{}{
+				"code":    consts.StatusBadRequest,
+				"message": "Invalid or expired reset token",
+			})
+			return
+		}
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to reset password",
+		})
+		return
+	}
+
+	if err := passpolicy.SetPassword(ctx, token.User, req.NewPassword, false); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &token.User.ID,
+		ActorName:  token.User.Username,
+		Action:     "password.reset",
+		TargetType: "user",
+		TargetID:   token.User.ID,
+		Detail:     "ip=" + c.ClientIP(),
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Password has been reset successfully",
+	})
+}
@@ -2,13 +2,25 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"gorm.io/gorm"
 
+	"github.com/Gaojianli/raduis_mgnt/authlog"
+	"github.com/Gaojianli/raduis_mgnt/authn"
+	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+	"github.com/Gaojianli/raduis_mgnt/mfa"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
 	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/settings"
 )
 
 type RadiusController struct{}
@@ -16,11 +28,15 @@ type RadiusController struct{}
 type RadiusAuthRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// State 由 Access-Challenge 流程回传，此时 Password 为验证码
+	State string `json:"state"`
 }
 
 type RadiusAuthResponse struct {
 	StatusCode int    `json:"REST-HTTP-Status-Code,omitempty"`
 	Reply      string `json:"reply:Reply-Message,omitempty"`
+	PacketType string `json:"control:Response-Packet-Type,omitempty"`
+	State      string `json:"reply:State,omitempty"`
 }
 
 type RadiusAuthorizeResponse struct {
@@ -32,50 +48,185 @@ func (rc *RadiusController) Authenticate(ctx context.Context, c *app.RequestCont
 	if err := c.BindAndValidate(&req); err != nil {
 		c.JSON(consts.StatusBadRequest, RadiusAuthResponse{
 			StatusCode: 400,
-			Reply:      "Invalid request format",
+			Reply:      settings.ReplyInvalidRequest.Get(),
 		})
 		return
 	}
 
-	user, err := database.DAO.User.GetByUsernameForAuth(ctx, req.Username)
-	if err != nil {
+	// Access-Challenge 第二轮：密码已在第一轮校验，这里只校验验证码
+	if req.State != "" {
+		user, err := database.DAO.User.GetByUsernameForAuth(ctx, req.Username)
+		if err != nil {
+			c.JSON(consts.StatusNotFound, RadiusAuthResponse{
+				StatusCode: 404,
+				Reply:      settings.ReplyUserNotFound.Get(),
+			})
+			return
+		}
+
+		userID, ok := mfa.Challenges.Take(strings.TrimPrefix(req.State, "0x"), user.Username)
+		if !ok || userID != user.ID {
+			rc.recordAuthLog(ctx, c, req.Username, models.AuthReasonChallengeExpired)
+			c.JSON(consts.StatusForbidden, RadiusAuthResponse{
+				StatusCode: 403,
+				Reply:      settings.ReplyChallengeExpired.Get(),
+			})
+			return
+		}
+		rc.verifyMFACode(ctx, c, user, req.Password)
+		return
+	}
+
+	// 已绑定 TOTP 的账号按 "password+123456" 拆分，只校验一次密码：拆分后的密码错误时不再用完整口令重试，
+	// 避免一次错误登录对 LDAP 绑定两次。因此密码本身以 "+" 加六位数字结尾的用户须始终附加验证码
+	password, code := req.Password, ""
+	if p, cd, ok := mfa.SplitPassword(req.Password); ok {
+		if existing, err := database.DAO.User.GetByUsernameForAuth(ctx, req.Username); err == nil {
+			enrolled, err := mfa.IsEnrolled(ctx, existing.ID)
+			if err != nil {
+				c.JSON(consts.StatusInternalServerError, RadiusAuthResponse{
+					StatusCode: 500,
+					Reply:      settings.ReplyInternalError.Get(),
+				})
+				return
+			}
+			if enrolled {
+				password, code = p, cd
+			}
+		}
+	}
+
+	user, err := authn.Authenticate(ctx, req.Username, password)
+	if err == nil && code != "" {
+		rc.verifyMFACode(ctx, c, user, code)
+		return
+	}
+	switch {
+	case errors.Is(err, authn.ErrUnknownUser):
+		rc.recordAuthLog(ctx, c, req.Username, models.AuthReasonUnknownUser)
 		c.JSON(consts.StatusNotFound, RadiusAuthResponse{
 			StatusCode: 404,
-			Reply:      "Authentication failed: user not found or disabled",
+			Reply:      settings.ReplyUserNotFound.Get(),
+		})
+		return
+	case errors.Is(err, authn.ErrInvalidCredentials):
+		rc.recordAuthLog(ctx, c, req.Username, models.AuthReasonInvalidPassword)
+		c.JSON(consts.StatusForbidden, RadiusAuthResponse{
+			StatusCode: 403,
+			Reply:      settings.ReplyInvalidPassword.Get(),
+		})
+		return
+	case err != nil:
+		log.Printf("Authentication backend error for %s: %v", req.Username, err)
+		c.JSON(consts.StatusInternalServerError, RadiusAuthResponse{
+			StatusCode: 500,
+			Reply:      settings.ReplyInternalError.Get(),
 		})
 		return
 	}
 
-	if !user.CheckPassword(req.Password) {
-		// 记录密码错误的认证日志
-		authLog := &models.AuthLog{
-			Username:   req.Username,
-			AuthType:   "authenticate",
-			Success:    false,
-			IPAddress:  string(c.GetHeader("X-NAS-IP")),
-			UserAgent:  string(c.UserAgent()),
-			DeviceMAC:  string(c.GetHeader("X-Device-MAC")),
-			TargetSSID: string(c.GetHeader("X-Target-SSID")),
-			CreatedAt:  time.Now(),
-		}
+	mfaRequired, err := mfa.IsRequired(ctx, user)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, RadiusAuthResponse{
+			StatusCode: 500,
+			Reply:      settings.ReplyInternalError.Get(),
+		})
+		return
+	}
+	mfaEnrolled, err := mfa.IsEnrolled(ctx, user.ID)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, RadiusAuthResponse{
+			StatusCode: 500,
+			Reply:      settings.ReplyInternalError.Get(),
+		})
+		return
+	}
+
+	if !mfaRequired && !mfaEnrolled {
+		rc.accept(ctx, c, user)
+		return
+	}
+
+	if !mfaEnrolled {
+		rc.recordAuthLog(ctx, c, req.Username, models.AuthReasonMFANotEnrolled)
+		c.JSON(consts.StatusForbidden, RadiusAuthResponse{
+			StatusCode: 403,
+			Reply:      settings.ReplyMFAEnrollmentRequired.Get(),
+		})
+		return
+	}
+
+	if !config.AppConfig.MFARadiusChallenge {
+		rc.recordAuthLog(ctx, c, req.Username, models.AuthReasonMFACodeRequired)
+		c.JSON(consts.StatusForbidden, RadiusAuthResponse{
+			StatusCode: 403,
+			Reply:      settings.ReplyMFACodeRequired.Get(),
+		})
+		return
+	}
+
+	state, err := mfa.Challenges.Issue(user.ID, user.Username)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, RadiusAuthResponse{
+			StatusCode: 500,
+			Reply:      settings.ReplyInternalError.Get(),
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, RadiusAuthResponse{
+		PacketType: "Access-Challenge",
+		State:      state,
+		Reply:      settings.ReplyMFAPrompt.Get(),
+	})
+}
+
+func (rc *RadiusController) verifyMFACode(ctx context.Context, c *app.RequestContext, user *models.User, code string) {
+	ok, err := mfa.Verify(ctx, user.ID, code)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, RadiusAuthResponse{
+			StatusCode: 500,
+			Reply:      settings.ReplyInternalError.Get(),
+		})
+		return
+	}
 
-		// 异步记录日志，不影响响应速度
-		go func() {
-			database.DAO.AuthLog.Create(context.Background(), authLog)
-		}()
+	if !ok {
+		rc.recordAuthLog(ctx, c, user.Username, models.AuthReasonInvalidMFACode)
+		c.JSON(consts.StatusForbidden, RadiusAuthResponse{
+			StatusCode: 403,
+			Reply:      settings.ReplyInvalidMFACode.Get(),
+		})
+		return
+	}
 
+	rc.accept(ctx, c, user)
+}
+
+// accept 认证通过后检查密码有效期：已过期则拒绝，即将过期时通过 Reply-Message 提醒
+func (rc *RadiusController) accept(ctx context.Context, c *app.RequestContext, user *models.User) {
+	if passpolicy.IsExpired(user) {
+		rc.recordAuthLog(ctx, c, user.Username, models.AuthReasonPasswordExpired)
 		c.JSON(consts.StatusForbidden, RadiusAuthResponse{
 			StatusCode: 403,
-			Reply:      "Authentication failed: invalid password",
+			Reply:      settings.ReplyPasswordExpired.Get(),
 		})
 		return
 	}
 
-	// 记录认证成功的日志
+	rc.recordAuthLog(ctx, c, user.Username, "")
+	middleware.RecordLogin(user.ID)
+	warning, _ := passpolicy.ExpiryWarning(user)
+	c.JSON(consts.StatusOK, RadiusAuthResponse{Reply: warning})
+}
+
+// recordAuthLog 通过队列异步记录认证日志，不影响响应速度；reason 为空表示认证成功
+func (rc *RadiusController) recordAuthLog(ctx context.Context, c *app.RequestContext, username string, reason string) {
 	authLog := &models.AuthLog{
-		Username:   user.Username,
+		Username:   username,
 		AuthType:   "authenticate",
-		Success:    true,
+		Success:    reason == "",
+		Reason:     reason,
 		IPAddress:  string(c.GetHeader("X-NAS-IP")),
 		UserAgent:  string(c.UserAgent()),
 		DeviceMAC:  string(c.GetHeader("X-Device-MAC")),
@@ -83,12 +234,13 @@ func (rc *RadiusController) Authenticate(ctx context.Context, c *app.RequestCont
 		CreatedAt:  time.Now(),
 	}
 
-	// 异步记录日志，不影响响应速度
-	go func() {
-		database.DAO.AuthLog.Create(context.Background(), authLog)
-	}()
+	authlog.Record(ctx, authLog)
 
-	c.JSON(consts.StatusOK, RadiusAuthResponse{})
+	result := "success"
+	if reason != "" {
+		result = "failure"
+	}
+	metrics.RadiusAuths.Inc(result, reason)
 }
 
 func (rc *RadiusController) Authorize(ctx context.Context, c *app.RequestContext) {
@@ -98,7 +250,7 @@ func (rc *RadiusController) Authorize(ctx context.Context, c *app.RequestContext
 
 	if err := c.BindAndValidate(&req); err != nil {
 		c.JSON(consts.StatusBadRequest, RadiusAuthorizeResponse{
-			Reply: "Invalid request format",
+			Reply: settings.ReplyInvalidRequest.Get(),
 		})
 		return
 	}
@@ -106,9 +258,16 @@ func (rc *RadiusController) Authorize(ctx context.Context, c *app.RequestContext
 	_, err := database.DAO.User.GetByUsernameForAuth(ctx, req.Username)
 	success := err == nil
 
+	// 目录用户首次认证前本地还没有影子记录，交由认证阶段判断
+	if !success && authn.HasExternal() {
+		if _, lookupErr := database.DAO.User.GetByUsername(ctx, req.Username); errors.Is(lookupErr, gorm.ErrRecordNotFound) {
+			success = true
+		}
+	}
+
 	if !success {
 		c.JSON(consts.StatusForbidden, RadiusAuthorizeResponse{
-			Reply: "User not found, disabled, or banned",
+			Reply: settings.ReplyAuthorizeRejected.Get(),
 		})
 		return
 	}
@@ -129,7 +288,7 @@ func (rc *RadiusController) Accounting(ctx context.Context, c *app.RequestContex
 	if err := c.BindAndValidate(&req); err != nil {
 		c.JSON(consts.StatusBadRequest, map[string]interface{}{
 			"result":  "error",
-			"message": "Invalid request format",
+			"message": settings.ReplyInvalidRequest.Get(),
 		})
 		return
 	}
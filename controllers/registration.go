@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/mailer"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/settings"
+)
+
+type RegistrationController struct{}
+
+// RegisterRequest 字段由 validateAccount 校验：BindAndValidate 只识别 vd 标签，binding 标签不会生效
+type RegisterRequest struct {
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code"`
+}
+
+type RejectRegistrationRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (rc *RegistrationController) Register(ctx context.Context, c *app.RequestContext) {
+	if !config.AppConfig.RegistrationEnabled {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "Registration is disabled",
+		})
+		return
+	}
+
+	var req RegisterRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if err := validateRegistration(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	inviteCode := config.AppConfig.RegistrationInviteCode
+	if inviteCode != "" && subtle.ConstantTimeCompare([]byte(req.InviteCode), []byte(inviteCode)) != 1 {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "Invalid invite code",
+		})
+		return
+	}
+
+	if !emailDomainAllowed(req.Email, config.AppConfig.RegistrationAllowedDomains) {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "Email domain is not allowed",
+		})
+		return
+	}
+
+	if err := passpolicy.Validate(req.Username, req.Password); err != nil {
+		writePasswordError(c, err)
+		return
+	}
+
+	_, err := database.DAO.User.GetByUsernameOrEmail(ctx, req.Username, req.Email)
+	if err == nil {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "Username or email already exists",
+		})
+		return
+	}
+
+	user := models.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+		Status:   models.UserStatusPending,
+	}
+
+	if err := database.DAO.User.Create(ctx, &user); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to create user",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusCreated, map[string]interface{}{
+		"code":    consts.StatusCreated,
+		"message": "Registration submitted, waiting for admin approval",
+		"data":    user.ToResponse(),
+	})
+}
+
+func (rc *RegistrationController) GetPendingRegistrations(ctx context.Context, c *app.RequestContext) {
+	page, _ := strconv.Atoi(string(c.Query("page")))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(string(c.Query("limit")))
+	if limit < 1 || limit > settings.MaxPageSize.Get() {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	users, total, err := database.DAO.User.ListByStatus(ctx, models.UserStatusPending, offset, limit)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to fetch registrations",
+		})
+		return
+	}
+
+	userResponses := make([]models.UserResponse, 0, len(users))
+	for _, user := range users {
+		userResponses = append(userResponses, user.ToResponse())
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": map[string]interface{}{
+			"users": userResponses,
+			"pagination": map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+func (rc *RegistrationController) ApproveRegistration(ctx context.Context, c *app.RequestContext) {
+	user, ok := rc.getPendingUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := database.DAO.User.UpdateStatus(ctx, user.ID, models.UserStatusActive); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to approve registration",
+		})
+		return
+	}
+
+	user.Status = models.UserStatusActive
+	mailer.SendAsync(mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Your account has been approved",
+		Body:    fmt.Sprintf("Hello %s,\n\nYour account registration has been approved. You can now sign in.\n", user.Username),
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Registration approved successfully",
+		"data":    user.ToResponse(),
+	})
+}
+
+func (rc *RegistrationController) RejectRegistration(ctx context.Context, c *app.RequestContext) {
+	var req RejectRegistrationRequest
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	user, ok := rc.getPendingUser(ctx, c)
+	if !ok {
+		return
+	}
+
+	if err := database.DAO.User.UpdateStatus(ctx, user.ID, models.UserStatusRejected); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to reject registration",
+		})
+		return
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\nYour account registration has been rejected.\n", user.Username)
+	if req.Reason != "" {
+		body += "\nReason: " + req.Reason + "\n"
+	}
+
+	user.Status = models.UserStatusRejected
+	mailer.SendAsync(mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Your account registration was rejected",
+		Body:    body,
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Registration rejected successfully",
+		"data":    user.ToResponse(),
+	})
+}
+
+// getPendingUser 读取路径中的用户并确认其处于待审核状态，失败时已写入响应
+func (rc *RegistrationController) getPendingUser(ctx context.Context, c *app.RequestContext) (*models.User, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid user ID",
+		})
+		return nil, false
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, uint(userID))
+	if err != nil {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "User not found",
+		})
+		return nil, false
+	}
+
+	if user.Status != models.UserStatusPending {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "Registration is not pending",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 50
+	maxEmailLength    = 254
+)
+
+// validateAccount 校验公开接口提交的用户名与邮箱
+func validateAccount(username, email string) error {
+	if n := utf8.RuneCountInString(username); n < minUsernameLength || n > maxUsernameLength {
+		return fmt.Errorf("username must be %d-%d characters", minUsernameLength, maxUsernameLength)
+	}
+	if strings.TrimSpace(username) != username {
+		return errors.New("username must not start or end with spaces")
+	}
+	if !validEmail(email) {
+		return errors.New("email must be a valid address")
+	}
+	return nil
+}
+
+// validEmail 只接受不带显示名的单个地址，如 alice@example.com
+func validEmail(email string) bool {
+	if email == "" || len(email) > maxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+func validateRegistration(req *RegisterRequest) error {
+	if err := validateAccount(req.Username, req.Email); err != nil {
+		return err
+	}
+	if req.Password == "" {
+		return errors.New("password is required")
+	}
+	return nil
+}
+
+// emailDomainAllowed 未配置域名白名单时也要求邮箱包含域名
+func emailDomainAllowed(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return false
+	}
+	if len(domains) == 0 {
+		return true
+	}
+
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range domains {
+		if domain == strings.ToLower(strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+func postJSON(handler func(context.Context, *app.RequestContext), path, body string) int {
+	c := ut.CreateUtRequestContext(consts.MethodPost, path,
+		&ut.Body{Body: strings.NewReader(body), Len: len(body)},
+		ut.Header{Key: "Content-Type", Value: "application/json"})
+	handler(context.Background(), c)
+	return c.Response.StatusCode()
+}
+
+func TestRegisterRejectsInvalidInput(t *testing.T) {
+	config.AppConfig = &config.Config{RegistrationEnabled: true}
+
+	cases := map[string]string{
+		"empty body":       `{}`,
+		"malformed json":   `{"username":`,
+		"password only":    `{"password":"abcdefgh"}`,
+		"short username":   `{"username":"ab","email":"ab@example.com","password":"abcdefgh"}`,
+		"long username":    `{"username":"` + strings.Repeat("a", 51) + `","email":"a@example.com","password":"abcdefgh"}`,
+		"padded username":  `{"username":" alice","email":"alice@example.com","password":"abcdefgh"}`,
+		"missing email":    `{"username":"alice","password":"abcdefgh"}`,
+		"invalid email":    `{"username":"alice","email":"alice","password":"abcdefgh"}`,
+		"display name":     `{"username":"alice","email":"Alice <alice@example.com>","password":"abcdefgh"}`,
+		"missing password": `{"username":"alice","email":"alice@example.com"}`,
+	}
+	rc := &RegistrationController{}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := postJSON(rc.Register, "/api/v1/register", body); got != consts.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", got, consts.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestEmailDomainAllowed(t *testing.T) {
+	cases := []struct {
+		email   string
+		domains []string
+		want    bool
+	}{
+		{"alice@example.com", nil, true},
+		{"", nil, false},
+		{"alice@", nil, false},
+		{"alice@example.com", []string{"@Example.com"}, true},
+		{"alice@other.com", []string{"example.com"}, false},
+	}
+	for _, tc := range cases {
+		if got := emailDomainAllowed(tc.email, tc.domains); got != tc.want {
+			t.Errorf("emailDomainAllowed(%q, %v) = %v, want %v", tc.email, tc.domains, got, tc.want)
+		}
+	}
+}
+
+func TestRegisterCreatesPendingUser(t *testing.T) {
+	config.AppConfig = &config.Config{RegistrationEnabled: true, PasswordMinLength: 8}
+	dbtest.Open(t)
+	rc := &RegistrationController{}
+
+	body := `{"username":"alice","email":"alice@example.com","password":"correct horse battery"}`
+	if got := postJSON(rc.Register, "/api/v1/register", body); got != consts.StatusCreated {
+		t.Fatalf("status = %d, want %d", got, consts.StatusCreated)
+	}
+	user, err := database.DAO.User.GetByUsername(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Status != models.UserStatusPending || user.IsAdmin {
+		t.Errorf("registered user status = %q, admin = %v, want a pending non-admin", user.Status, user.IsAdmin)
+	}
+	if !user.CheckPassword("correct horse battery") || user.Password == "correct horse battery" {
+		t.Error("password was not stored as a verifiable hash")
+	}
+
+	// 用户名或邮箱已被占用
+	for _, body := range []string{
+		`{"username":"alice","email":"other@example.com","password":"correct horse battery"}`,
+		`{"username":"alice2","email":"alice@example.com","password":"correct horse battery"}`,
+	} {
+		if got := postJSON(rc.Register, "/api/v1/register", body); got != consts.StatusConflict {
+			t.Errorf("duplicate registration status = %d, want %d", got, consts.StatusConflict)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/settings"
+)
+
+type SettingsController struct{}
+
+// settingValue 新值可以是 JSON 字符串或数字，时长须为字符串，如 "12h"
+func settingValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// auditValue 历史中的空值表示默认值
+func auditValue(value *string) string {
+	if value == nil {
+		return "(default)"
+	}
+	return strconv.Quote(*value)
+}
+
+func writeSettingError(c *app.RequestContext, err error) {
+	var invalid *settings.InvalidValueError
+	switch {
+	case errors.Is(err, settings.ErrUnknownKey):
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "Setting not found",
+		})
+	case errors.As(err, &invalid):
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": invalid.Error(),
+		})
+	default:
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to update setting",
+		})
+	}
+}
+
+func (sc *SettingsController) List(ctx context.Context, c *app.RequestContext) {
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Settings retrieved successfully",
+		"data":    settings.List(),
+	})
+}
+
+func (sc *SettingsController) Get(ctx context.Context, c *app.RequestContext) {
+	view, err := settings.Get(c.Param("key"))
+	if err != nil {
+		writeSettingError(c, err)
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Setting retrieved successfully",
+		"data":    view,
+	})
+}
+
+// Update 请求体为 {"value": ...}，新值在本实例立即生效，其他实例在 SETTINGS_REFRESH_INTERVAL 内生效
+func (sc *SettingsController) Update(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	var req struct {
+		Value interface{} `json:"value"`
+	}
+	if err := c.BindAndValidate(&req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request format",
+		})
+		return
+	}
+	value, ok := settingValue(req.Value)
+	if !ok {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "value must be a string or a number",
+		})
+		return
+	}
+
+	key := c.Param("key")
+	change, err := settings.Set(ctx, key, value, currentUser.UserID, currentUser.Username)
+	if err != nil {
+		writeSettingError(c, err)
+		return
+	}
+	sc.audit(ctx, currentUser, "setting.update", change)
+	sc.respond(c, key, "Setting updated successfully")
+}
+
+// Reset 删除保存的值，恢复为默认值
+func (sc *SettingsController) Reset(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	key := c.Param("key")
+	change, err := settings.Reset(ctx, key, currentUser.UserID, currentUser.Username)
+	if err != nil {
+		writeSettingError(c, err)
+		return
+	}
+	sc.audit(ctx, currentUser, "setting.reset", change)
+	sc.respond(c, key, "Setting reset to default")
+}
+
+// History 修改记录按时间倒序，limit 默认 50，最大 200
+func (sc *SettingsController) History(ctx context.Context, c *app.RequestContext) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	changes, err := settings.History(ctx, c.Param("key"), limit)
+	if err != nil {
+		writeSettingError(c, err)
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Setting history retrieved successfully",
+		"data":    changes,
+	})
+}
+
+// audit 值未变化时 change 为空，不记录
+func (sc *SettingsController) audit(ctx context.Context, actor *middleware.Claims, action string, change *models.SettingChange) {
+	if change == nil {
+		return
+	}
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &actor.UserID,
+		ActorName:  actor.Username,
+		Action:     action,
+		TargetType: "setting",
+		Detail:     fmt.Sprintf("key=%s, old=%s, new=%s", change.Key, auditValue(change.OldValue), auditValue(change.NewValue)),
+	})
+}
+
+func (sc *SettingsController) respond(c *app.RequestContext, key, message string) {
+	view, err := settings.Get(key)
+	if err != nil {
+		writeSettingError(c, err)
+		return
+	}
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": message,
+		"data":    view,
+	})
+}
@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/mailer"
+	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/settings"
+)
+
+type SponsoredGuestController struct{}
+
+// GuestAccessRequest 字段在 validate 中校验，binding 标签不会被 BindAndValidate 识别
+type GuestAccessRequest struct {
+	Username     string `json:"username"`
+	Email        string `json:"email"`
+	FullName     string `json:"full_name"`
+	Reason       string `json:"reason"`
+	SponsorEmail string `json:"sponsor_email"`
+}
+
+const (
+	maxGuestFullNameLength = 100
+	maxGuestReasonLength   = 500
+)
+
+func (r *GuestAccessRequest) validate() error {
+	if err := validateAccount(r.Username, r.Email); err != nil {
+		return err
+	}
+	if !validEmail(r.SponsorEmail) {
+		return errors.New("sponsor_email must be a valid address")
+	}
+	if utf8.RuneCountInString(r.FullName) > maxGuestFullNameLength {
+		return fmt.Errorf("full_name must be at most %d characters", maxGuestFullNameLength)
+	}
+	if utf8.RuneCountInString(r.Reason) > maxGuestReasonLength {
+		return fmt.Errorf("reason must be at most %d characters", maxGuestReasonLength)
+	}
+	return nil
+}
+
+type ApproveGuestRequest struct {
+	LifetimeHours int `json:"lifetime_hours"`
+}
+
+type RejectGuestRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SubmitRequest 访客提交申请，无需登录。担保人不存在、用户名或邮箱已被占用时返回与成功相同的响应，
+// 避免匿名调用者借此探测账号，原因只记入审计日志
+func (gc *SponsoredGuestController) SubmitRequest(ctx context.Context, c *app.RequestContext) {
+	var req GuestAccessRequest
+	if err := bindGuestRequest(c, &req); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	accepted := func() {
+		c.JSON(consts.StatusAccepted, map[string]interface{}{
+			"code":    consts.StatusAccepted,
+			"message": "Guest request received, the sponsor will be asked to approve it",
+		})
+	}
+	ignore := func(reason string) {
+		database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+			ActorName:  "anonymous",
+			Action:     "guest.request_ignored",
+			TargetType: "guest_request",
+			Detail: fmt.Sprintf("reason=%s, username=%s, email=%s, sponsor_email=%s, ip=%s",
+				reason, req.Username, req.Email, req.SponsorEmail, c.ClientIP()),
+		})
+		accepted()
+	}
+
+	sponsor, err := database.DAO.User.GetByEmail(ctx, req.SponsorEmail)
+	if err != nil || !canSponsor(sponsor) {
+		ignore("sponsor not found")
+		return
+	}
+
+	if _, err := database.DAO.User.GetByUsernameOrEmail(ctx, req.Username, req.Email); err == nil {
+		ignore("username or email already exists")
+		return
+	}
+
+	pending, err := database.DAO.GuestRequest.CountPendingByUsername(ctx, req.Username)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to create guest request",
+		})
+		return
+	}
+	if pending > 0 {
+		ignore("request for this username already pending")
+		return
+	}
+
+	guestReq := models.GuestRequest{
+		Username:  req.Username,
+		Email:     req.Email,
+		FullName:  req.FullName,
+		Reason:    req.Reason,
+		SponsorID: sponsor.ID,
+		Status:    models.GuestRequestPending,
+	}
+
+	if err := database.DAO.GuestRequest.Create(ctx, &guestReq); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to create guest request",
+		})
+		return
+	}
+
+	mailer.SendAsync(mailer.Message{
+		To:      []string{sponsor.Email},
+		Subject: "Guest access request awaiting your approval",
+		Body: fmt.Sprintf("Hello %s,\n\n%s <%s> has requested guest network access and named you as sponsor.\nReason: %s\n\nPlease review the request in your dashboard.\n",
+			sponsor.Username, displayName(guestReq), guestReq.Email, guestReq.Reason),
+	})
+
+	accepted()
+}
+
+func bindGuestRequest(c *app.RequestContext, req *GuestAccessRequest) error {
+	if err := c.BindAndValidate(req); err != nil {
+		return err
+	}
+	return req.validate()
+}
+
+// ListRequests 担保人查看指向自己的访客申请
+func (gc *SponsoredGuestController) ListRequests(ctx context.Context, c *app.RequestContext) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(string(c.Query("page")))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(string(c.Query("limit")))
+	if limit < 1 || limit > settings.MaxPageSize.Get() {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	reqs, total, err := database.DAO.GuestRequest.ListBySponsor(ctx, currentUser.UserID, c.Query("status"), offset, limit)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to fetch guest requests",
+		})
+		return
+	}
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code": consts.StatusOK,
+		"data": map[string]interface{}{
+			"requests": reqs,
+			"pagination": map[string]interface{}{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+func (gc *SponsoredGuestController) ApproveRequest(ctx context.Context, c *app.RequestContext) {
+	var body ApproveGuestRequest
+	if err := c.BindAndValidate(&body); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sponsor, guestReq, ok := gc.getSponsoredRequest(ctx, c)
+	if !ok {
+		return
+	}
+
+	if guestReq.Status != models.GuestRequestPending {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "Guest request is not pending",
+		})
+		return
+	}
+
+	lifetime := config.AppConfig.GuestLifetime
+	if body.LifetimeHours > 0 {
+		lifetime = time.Duration(body.LifetimeHours) * time.Hour
+	}
+	if lifetime > config.AppConfig.GuestMaxLifetime {
+		lifetime = config.AppConfig.GuestMaxLifetime
+	}
+
+	group, err := database.DAO.Group.GetOrCreate(ctx, config.AppConfig.GuestGroup)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to prepare guest group",
+		})
+		return
+	}
+
+	password, err := generateGuestPassword()
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to generate password",
+		})
+		return
+	}
+
+	expiresAt := time.Now().Add(lifetime)
+	guest := models.User{
+		Username:  guestReq.Username,
+		Email:     guestReq.Email,
+		Password:  password,
+		ExpiresAt: &expiresAt,
+		SponsorID: &sponsor.ID,
+		Groups:    []models.Group{*group},
+	}
+
+	if err := database.DAO.GuestRequest.Approve(ctx, guestReq, &guest); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to create guest account",
+		})
+		return
+	}
+
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &sponsor.ID,
+		ActorName:  sponsor.Username,
+		Action:     "guest.approved",
+		TargetType: "user",
+		TargetID:   guest.ID,
+		Detail: fmt.Sprintf("guest_request_id=%d, sponsor_id=%d, expires_at=%s",
+			guestReq.ID, sponsor.ID, expiresAt.Format(time.RFC3339)),
+	})
+
+	mailer.SendAsync(mailer.Message{
+		To:      []string{guest.Email},
+		Subject: "Your guest access has been approved",
+		Body: fmt.Sprintf("Hello %s,\n\n%s approved your guest access.\n\nUsername: %s\nPassword: %s\nValid until: %s\n",
+			displayName(*guestReq), sponsor.Username, guest.Username, password, expiresAt.Format(time.RFC1123)),
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Guest request approved successfully",
+		"data":    guestReq,
+	})
+}
+
+func (gc *SponsoredGuestController) RejectRequest(ctx context.Context, c *app.RequestContext) {
+	var body RejectGuestRequest
+	if err := c.BindAndValidate(&body); err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sponsor, guestReq, ok := gc.getSponsoredRequest(ctx, c)
+	if !ok {
+		return
+	}
+
+	if guestReq.Status != models.GuestRequestPending {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "Guest request is not pending",
+		})
+		return
+	}
+
+	if err := database.DAO.GuestRequest.UpdateStatus(ctx, guestReq.ID, models.GuestRequestRejected, body.Reason); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to reject guest request",
+		})
+		return
+	}
+
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &sponsor.ID,
+		ActorName:  sponsor.Username,
+		Action:     "guest.rejected",
+		TargetType: "guest_request",
+		TargetID:   guestReq.ID,
+		Detail:     body.Reason,
+	})
+
+	mailBody := fmt.Sprintf("Hello %s,\n\nYour guest access request was rejected by %s.\n", displayName(*guestReq), sponsor.Username)
+	if body.Reason != "" {
+		mailBody += "\nReason: " + body.Reason + "\n"
+	}
+	mailer.SendAsync(mailer.Message{
+		To:      []string{guestReq.Email},
+		Subject: "Your guest access request was rejected",
+		Body:    mailBody,
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Guest request rejected successfully",
+	})
+}
+
+// RevokeRequest 担保人提前终止已批准的访客账号
+func (gc *SponsoredGuestController) RevokeRequest(ctx context.Context, c *app.RequestContext) {
+	sponsor, guestReq, ok := gc.getSponsoredRequest(ctx, c)
+	if !ok {
+		return
+	}
+
+	if guestReq.Status != models.GuestRequestApproved || guestReq.GuestUserID == nil {
+		c.JSON(consts.StatusConflict, map[string]interface{}{
+			"code":    consts.StatusConflict,
+			"message": "Guest request is not approved",
+		})
+		return
+	}
+
+	if err := database.DAO.User.UpdateStatus(ctx, *guestReq.GuestUserID, models.UserStatusExpired); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to revoke guest account",
+		})
+		return
+	}
+
+	if err := database.DAO.GuestRequest.UpdateStatus(ctx, guestReq.ID, models.GuestRequestRevoked, ""); err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": "Failed to update guest request",
+		})
+		return
+	}
+
+	database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+		ActorID:    &sponsor.ID,
+		ActorName:  sponsor.Username,
+		Action:     "guest.revoked",
+		TargetType: "user",
+		TargetID:   *guestReq.GuestUserID,
+		Detail:     fmt.Sprintf("guest_request_id=%d, sponsor_id=%d", guestReq.ID, sponsor.ID),
+	})
+
+	c.JSON(consts.StatusOK, map[string]interface{}{
+		"code":    consts.StatusOK,
+		"message": "Guest access revoked successfully",
+	})
+}
+
+// getSponsoredRequest 读取路径中的申请并确认当前用户是其担保人，失败时已写入响应
+func (gc *SponsoredGuestController) getSponsoredRequest(ctx context.Context, c *app.RequestContext) (*models.User, *models.GuestRequest, bool) {
+	currentUser, err := middleware.GetCurrentUser(ctx, c)
+	if err != nil {
+		c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+			"code":    consts.StatusUnauthorized,
+			"message": "Unauthorized",
+		})
+		return nil, nil, false
+	}
+
+	requestID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Invalid request ID",
+		})
+		return nil, nil, false
+	}
+
+	guestReq, err := database.DAO.GuestRequest.GetByID(ctx, uint(requestID))
+	if err != nil || guestReq.SponsorID != currentUser.UserID {
+		c.JSON(consts.StatusNotFound, map[string]interface{}{
+			"code":    consts.StatusNotFound,
+			"message": "Guest request not found",
+		})
+		return nil, nil, false
+	}
+
+	sponsor, err := database.DAO.User.GetByID(ctx, currentUser.UserID)
+	if err != nil || !canSponsor(sponsor) {
+		c.JSON(consts.StatusForbidden, map[string]interface{}{
+			"code":    consts.StatusForbidden,
+			"message": "You are not allowed to sponsor guests",
+		})
+		return nil, nil, false
+	}
+
+	return sponsor, guestReq, true
+}
+
+// canSponsor 只有正常状态的非访客账号才能担保访客
+func canSponsor(user *models.User) bool {
+	return user.Status == models.UserStatusActive && !user.Banned && !user.IsExpired() && user.SponsorID == nil
+}
+
+func displayName(req models.GuestRequest) string {
+	if req.FullName != "" {
+		return req.FullName
+	}
+	return req.Username
+}
+
+func generateGuestPassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+func TestSubmitGuestRequestRejectsInvalidInput(t *testing.T) {
+	valid := `"username":"guest1","email":"guest1@example.com","sponsor_email":"host@example.com"`
+	cases := map[string]string{
+		"empty body":      `{}`,
+		"malformed json":  `{"username":`,
+		"empty username":  `{"email":"guest1@example.com","sponsor_email":"host@example.com"}`,
+		"invalid email":   `{"username":"guest1","email":"guest1","sponsor_email":"host@example.com"}`,
+		"invalid sponsor": `{"username":"guest1","email":"guest1@example.com","sponsor_email":"host"}`,
+		"long full name":  `{` + valid + `,"full_name":"` + strings.Repeat("a", 101) + `"}`,
+		"long reason":     `{` + valid + `,"reason":"` + strings.Repeat("a", 501) + `"}`,
+	}
+	gc := &SponsoredGuestController{}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := postJSON(gc.SubmitRequest, "/api/v1/sponsored-guests/requests", body); got != consts.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", got, consts.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -2,15 +2,21 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 
+	"github.com/Gaojianli/raduis_mgnt/dao"
 	"github.com/Gaojianli/raduis_mgnt/database"
 	"github.com/Gaojianli/raduis_mgnt/middleware"
 	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/settings"
 )
 
 type UserController struct{}
@@ -18,17 +24,21 @@ type UserController struct{}
 type CreateUserRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
-	IsAdmin  *bool  `json:"is_admin"`
+	Password string `json:"password"`
+	// PasswordHash 从其他系统迁移用户时直接导入已有哈希，与 Password 二选一
+	PasswordHash string `json:"password_hash"`
+	IsAdmin      *bool  `json:"is_admin"`
 }
 
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 type AdminChangePasswordRequest struct {
-	NewPassword string `json:"new_password" binding:"required,min=6"`
+	NewPassword string `json:"new_password" binding:"required"`
+	// MustChange 默认为 true，管理员设置的密码需由用户下次登录时修改
+	MustChange *bool `json:"must_change"`
 }
 
 type StatsResponse struct {
@@ -36,6 +46,8 @@ type StatsResponse struct {
 	ActiveUsers int64 `json:"active_users"`
 	BannedUsers int64 `json:"banned_users"`
 	AuthCount   int64 `json:"auth_count"`
+	// LastRetentionRun 最近一次认证日志清理，仅管理员统计接口返回
+	LastRetentionRun *models.RetentionRun `json:"last_retention_run,omitempty"`
 }
 
 type AuthLogsResponse struct {
@@ -58,6 +70,7 @@ type AuthLogResponse struct {
 	UserAgent  string `json:"user_agent"`
 	DeviceMAC  string `json:"device_mac"`
 	TargetSSID string `json:"target_ssid"`
+	Reason     string `json:"reason,omitempty"`
 	CreatedAt  int64  `json:"created_at"`
 }
 
@@ -72,6 +85,13 @@ func (uc *UserController) AdminCreateUser(ctx context.Context, c *app.RequestCon
 		return
 	}
 
+	if req.PasswordHash == "" {
+		if err := passpolicy.Validate(req.Username, req.Password); err != nil {
+			writePasswordError(c, err)
+			return
+		}
+	}
+
 	_, err := database.DAO.User.GetByUsernameOrEmail(ctx, req.Username, req.Email)
 	if err == nil {
 		c.JSON(consts.StatusConflict, map[string]interface{}{
@@ -93,6 +113,16 @@ func (uc *UserController) AdminCreateUser(ctx context.Context, c *app.RequestCon
 		IsAdmin:  isAdmin,
 	}
 
+	if req.PasswordHash != "" {
+		if err := user.SetPasswordHash(req.PasswordHash); err != nil {
+			c.JSON(consts.StatusBadRequest, map[string]interface{}{
+				"code":    consts.StatusBadRequest,
+				"message": "Unsupported password hash format",
+			})
+			return
+		}
+	}
+
 	if err := database.DAO.User.Create(ctx, &user); err != nil {
 		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
 			"code":    consts.StatusInternalServerError,
@@ -170,19 +200,8 @@ func (uc *UserController) ChangePassword(ctx context.Context, c *app.RequestCont
 		return
 	}
 
-	if err := user.HashPassword(req.NewPassword); err != nil {
-		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
-			"code":    consts.StatusInternalServerError,
-			"message": "Failed to hash password",
-		})
-		return
-	}
-
-	if err := database.DAO.User.Update(ctx, user); err != nil {
-		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
-			"code":    consts.StatusInternalServerError,
-			"message": "Failed to update password",
-		})
+	if err := passpolicy.SetPassword(ctx, user, req.NewPassword, false); err != nil {
+		writePasswordError(c, err)
 		return
 	}
 
@@ -192,19 +211,106 @@ func (uc *UserController) ChangePassword(ctx context.Context, c *app.RequestCont
 	})
 }
 
+// parseListTime 接受 RFC 3339 时间或 YYYY-MM-DD 日期；end 为 true 时日期取当天结束，使区间包含该日
+func parseListTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	d, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if end {
+		d = d.AddDate(0, 0, 1)
+	}
+	return &d, nil
+}
+
+func parseListBool(value string) (*bool, error) {
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// userFilterFromQuery 解析管理端用户列表的筛选与排序参数，分页参数由调用方处理
+func userFilterFromQuery(c *app.RequestContext) (dao.UserFilter, error) {
+	filter := dao.UserFilter{
+		Query:      strings.TrimSpace(c.Query("q")),
+		Status:     c.Query("status"),
+		AuthSource: c.Query("auth_source"),
+		Group:      c.Query("group"),
+		SortBy:     c.DefaultQuery("sort", "id"),
+	}
+
+	var err error
+	if filter.IsAdmin, err = parseListBool(c.Query("is_admin")); err != nil {
+		return filter, errors.New("invalid is_admin")
+	}
+	if filter.Banned, err = parseListBool(c.Query("banned")); err != nil {
+		return filter, errors.New("invalid banned")
+	}
+
+	ranges := []struct {
+		param string
+		end   bool
+		dest  **time.Time
+	}{
+		{"created_from", false, &filter.CreatedAfter},
+		{"created_to", true, &filter.CreatedBefore},
+		{"last_login_from", false, &filter.LastLoginAfter},
+		{"last_login_to", true, &filter.LastLoginBefore},
+	}
+	for _, r := range ranges {
+		if *r.dest, err = parseListTime(c.Query(r.param), r.end); err != nil {
+			return filter, fmt.Errorf("invalid %s, expected RFC 3339 or YYYY-MM-DD", r.param)
+		}
+	}
+
+	if !dao.UserSortColumns[filter.SortBy] {
+		return filter, fmt.Errorf("invalid sort column %q", filter.SortBy)
+	}
+	switch strings.ToLower(c.DefaultQuery("order", "asc")) {
+	case "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+	return filter, nil
+}
+
+// GetUsers 支持 q（用户名或邮箱子串）、is_admin、banned、status、auth_source、group、
+// created_from / created_to、last_login_from / last_login_to 筛选，以及 sort / order 排序
 func (uc *UserController) GetUsers(ctx context.Context, c *app.RequestContext) {
 	page, _ := strconv.Atoi(string(c.Query("page")))
 	if page < 1 {
 		page = 1
 	}
 	limit, _ := strconv.Atoi(string(c.Query("limit")))
-	if limit < 1 || limit > 100 {
+	if limit < 1 || limit > settings.MaxPageSize.Get() {
 		limit = 20
 	}
 
-	offset := (page - 1) * limit
+	filter, err := userFilterFromQuery(c)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": err.Error(),
+		})
+		return
+	}
+	filter.Offset = (page - 1) * limit
+	filter.Limit = limit
 
-	users, total, err := database.DAO.User.List(ctx, offset, limit)
+	users, total, err := database.DAO.User.List(ctx, filter)
 	if err != nil {
 		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
 			"code":    consts.StatusInternalServerError,
@@ -261,19 +367,13 @@ func (uc *UserController) AdminChangePassword(ctx context.Context, c *app.Reques
 		return
 	}
 
-	if err := user.HashPassword(req.NewPassword); err != nil {
-		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
-			"code":    consts.StatusInternalServerError,
-			"message": "Failed to hash password",
-		})
-		return
+	mustChange := true
+	if req.MustChange != nil {
+		mustChange = *req.MustChange
 	}
 
-	if err := database.DAO.User.Update(ctx, user); err != nil {
-		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
-			"code":    consts.StatusInternalServerError,
-			"message": "Failed to update password",
-		})
+	if err := passpolicy.SetPassword(ctx, user, req.NewPassword, mustChange); err != nil {
+		writePasswordError(c, err)
 		return
 	}
 
@@ -435,7 +535,7 @@ func (uc *UserController) GetStats(ctx context.Context, c *app.RequestContext) {
 			return
 		}
 
-		totalAuthCount, err := database.DAO.AuthLog.GetTotalSuccessCount(ctx)
+		totalAuthCount, err := database.DAO.AuthStats.SuccessCount(ctx, "")
 		if err != nil {
 			c.JSON(consts.StatusInternalServerError, map[string]interface{}{
 				"code":    consts.StatusInternalServerError,
@@ -452,7 +552,7 @@ func (uc *UserController) GetStats(ctx context.Context, c *app.RequestContext) {
 		}
 	} else {
 		// 普通用户只能查看自己的授权次数
-		authCount, err := database.DAO.AuthLog.GetSuccessCountByUsername(ctx, currentUser.Username)
+		authCount, err := database.DAO.AuthStats.SuccessCount(ctx, currentUser.Username)
 		if err != nil {
 			c.JSON(consts.StatusInternalServerError, map[string]interface{}{
 				"code":    consts.StatusInternalServerError,
@@ -502,7 +602,7 @@ func (uc *UserController) GetAdminStats(ctx context.Context, c *app.RequestConte
 		return
 	}
 
-	totalAuthCount, err := database.DAO.AuthLog.GetTotalSuccessCount(ctx)
+	totalAuthCount, err := database.DAO.AuthStats.SuccessCount(ctx, "")
 	if err != nil {
 		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
 			"code":    consts.StatusInternalServerError,
@@ -517,6 +617,9 @@ func (uc *UserController) GetAdminStats(ctx context.Context, c *app.RequestConte
 		BannedUsers: bannedUsers,
 		AuthCount:   totalAuthCount,
 	}
+	if run, err := database.DAO.RetentionRun.Latest(ctx); err == nil {
+		stats.LastRetentionRun = run
+	}
 
 	c.JSON(consts.StatusOK, map[string]interface{}{
 		"code": consts.StatusOK,
@@ -528,7 +631,6 @@ func (uc *UserController) GetAuthLogs(ctx context.Context, c *app.RequestContext
 	// 获取分页参数
 	page := c.DefaultQuery("page", "1")
 	limit := c.DefaultQuery("limit", "20")
-	username := c.Query("username") // 可选的用户名筛选
 
 	pageInt, err := strconv.Atoi(page)
 	if err != nil || pageInt < 1 {
@@ -536,14 +638,23 @@ func (uc *UserController) GetAuthLogs(ctx context.Context, c *app.RequestContext
 	}
 
 	limitInt, err := strconv.Atoi(limit)
-	if err != nil || limitInt < 1 || limitInt > 100 {
+	if err != nil || limitInt < 1 || limitInt > settings.MaxPageSize.Get() {
 		limitInt = 20
 	}
 
-	offset := (pageInt - 1) * limitInt
+	filter, err := authLogFilterFromQuery(c)
+	if err != nil {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": err.Error(),
+		})
+		return
+	}
+	filter.Offset = (pageInt - 1) * limitInt
+	filter.Limit = limitInt
 
 	// 查询日志
-	logs, total, err := database.DAO.AuthLog.List(ctx, offset, limitInt, username)
+	logs, total, err := database.DAO.AuthLog.List(ctx, filter)
 	if err != nil {
 		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
 			"code":    consts.StatusInternalServerError,
@@ -554,18 +665,8 @@ func (uc *UserController) GetAuthLogs(ctx context.Context, c *app.RequestContext
 
 	// 转换为响应格式
 	logResponses := make([]AuthLogResponse, len(logs))
-	for i, log := range logs {
-		logResponses[i] = AuthLogResponse{
-			ID:         log.ID,
-			Username:   log.Username,
-			AuthType:   log.AuthType,
-			Success:    log.Success,
-			IPAddress:  log.IPAddress,
-			UserAgent:  log.UserAgent,
-			DeviceMAC:  log.DeviceMAC,
-			TargetSSID: log.TargetSSID,
-			CreatedAt:  log.CreatedAt.Unix(),
-		}
+	for i := range logs {
+		logResponses[i] = newAuthLogResponse(&logs[i])
 	}
 
 	c.JSON(consts.StatusOK, map[string]interface{}{
@@ -580,3 +681,29 @@ func (uc *UserController) GetAuthLogs(ctx context.Context, c *app.RequestContext
 		},
 	})
 }
+
+// writePasswordError 密码不符合策略时返回 400 及具体原因，其余错误按保存失败处理
+func writePasswordError(c *app.RequestContext, err error) {
+	if errors.Is(err, passpolicy.ErrExternalAccount) {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":    consts.StatusBadRequest,
+			"message": "Password is managed by an external directory",
+		})
+		return
+	}
+
+	var violation *passpolicy.ViolationError
+	if errors.As(err, &violation) {
+		c.JSON(consts.StatusBadRequest, map[string]interface{}{
+			"code":       consts.StatusBadRequest,
+			"message":    "Password does not meet policy",
+			"violations": violation.Violations,
+		})
+		return
+	}
+
+	c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+		"code":    consts.StatusInternalServerError,
+		"message": "Failed to update password",
+	})
+}
@@ -0,0 +1,35 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type AuditLogDAO interface {
+	Create(ctx context.Context, auditLog *models.AuditLog) error
+	ListByTarget(ctx context.Context, targetType string, targetID uint) ([]models.AuditLog, error)
+}
+
+type auditLogDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewAuditLogDAO(db *gorm.DB) AuditLogDAO {
+	return &auditLogDAOImpl{db: db}
+}
+
+func (d *auditLogDAOImpl) Create(ctx context.Context, auditLog *models.AuditLog) error {
+	return d.db.WithContext(ctx).Create(auditLog).Error
+}
+
+func (d *auditLogDAOImpl) ListByTarget(ctx context.Context, targetType string, targetID uint) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	err := d.db.WithContext(ctx).
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Order("created_at ASC").
+		Find(&logs).Error
+	return logs, err
+}
@@ -2,6 +2,7 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,10 +12,78 @@ import (
 
 type AuthLogDAO interface {
 	Create(ctx context.Context, authLog *models.AuthLog) error
+	CreateBatch(ctx context.Context, logs []*models.AuthLog) error
 	GetSuccessCountByUsername(ctx context.Context, username string) (int64, error)
 	GetTotalSuccessCount(ctx context.Context) (int64, error)
 	GetSuccessCountByDateRange(ctx context.Context, start, end time.Time) (int64, error)
-	List(ctx context.Context, offset, limit int, username string) ([]models.AuthLog, int64, error)
+	List(ctx context.Context, filter AuthLogFilter) ([]models.AuthLog, int64, error)
+	Each(ctx context.Context, filter AuthLogFilter, batchSize int, fn func([]models.AuthLog) error) error
+	ListPage(ctx context.Context, filter AuthLogFilter, after *AuthLogCursor, limit int) ([]models.AuthLog, error)
+	Count(ctx context.Context, filter AuthLogFilter) (int64, error)
+	CountUpTo(ctx context.Context, filter AuthLogFilter, max int64) (int64, error)
+	EstimateTotal(ctx context.Context) (int64, bool, error)
+	RowLimitBoundary(ctx context.Context, keep int) (uint, error)
+	ListExpired(ctx context.Context, before time.Time, throughID, maxID uint, limit int) ([]models.AuthLog, error)
+	DeleteByIDs(ctx context.Context, ids []uint) (int64, error)
+	ListAfter(ctx context.Context, afterID uint, limit int) ([]models.AuthLog, error)
+	MinuteSeries(ctx context.Context, from, to time.Time, afterID uint) ([]SeriesPoint, error)
+	Breakdown(ctx context.Context, dimension string, from, to time.Time, afterID uint) ([]BreakdownRow, error)
+	TopFailures(ctx context.Context, from, to time.Time, afterID uint, limit int) ([]UserCount, error)
+	DistinctDevices(ctx context.Context, from, to time.Time) (int64, error)
+}
+
+// AuthLogCursor 游标分页位置，即上一页最后一条日志的排序键
+type AuthLogCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// AuthLogFilter 认证日志查询条件，零值字段不参与过滤
+type AuthLogFilter struct {
+	Username   string
+	Success    *bool
+	AuthType   string
+	IPAddress  string // NAS IP
+	DeviceMAC  string
+	TargetSSID string
+	Reason     string
+	Since      *time.Time
+	Until      *time.Time
+
+	Offset int
+	Limit  int
+}
+
+func (f AuthLogFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Username != "" {
+		query = query.Where("username = ?", f.Username)
+	}
+	if f.Success != nil {
+		query = query.Where("success = ?", *f.Success)
+	}
+	if f.AuthType != "" {
+		query = query.Where("auth_type = ?", f.AuthType)
+	}
+	if f.IPAddress != "" {
+		query = query.Where("ip_address = ?", f.IPAddress)
+	}
+	if f.DeviceMAC != "" {
+		query = query.Where("device_mac = ?", f.DeviceMAC)
+	}
+	if f.TargetSSID != "" {
+		// 模型未指定列名，gorm 生成的列名是 target_ss_id
+		query = query.Where("target_ss_id = ?", f.TargetSSID)
+	}
+	if f.Reason != "" {
+		query = query.Where("reason = ?", f.Reason)
+	}
+	if f.Since != nil {
+		query = query.Where("created_at >= ?", *f.Since)
+	}
+	if f.Until != nil {
+		query = query.Where("created_at < ?", *f.Until)
+	}
+	return query
 }
 
 type authLogDAOImpl struct {
@@ -29,6 +98,10 @@ func (d *authLogDAOImpl) Create(ctx context.Context, authLog *models.AuthLog) er
 	return d.db.WithContext(ctx).Create(authLog).Error
 }
 
+func (d *authLogDAOImpl) CreateBatch(ctx context.Context, logs []*models.AuthLog) error {
+	return d.db.WithContext(ctx).Create(logs).Error
+}
+
 func (d *authLogDAOImpl) GetSuccessCountByUsername(ctx context.Context, username string) (int64, error) {
 	var count int64
 	err := d.db.WithContext(ctx).Model(&models.AuthLog{}).
@@ -53,16 +126,11 @@ func (d *authLogDAOImpl) GetSuccessCountByDateRange(ctx context.Context, start,
 	return count, err
 }
 
-func (d *authLogDAOImpl) List(ctx context.Context, offset, limit int, username string) ([]models.AuthLog, int64, error) {
+func (d *authLogDAOImpl) List(ctx context.Context, filter AuthLogFilter) ([]models.AuthLog, int64, error) {
 	var logs []models.AuthLog
 	var total int64
 
-	query := d.db.WithContext(ctx).Model(&models.AuthLog{})
-	
-	// 如果提供了用户名筛选
-	if username != "" {
-		query = query.Where("username = ?", username)
-	}
+	query := filter.apply(d.db.WithContext(ctx).Model(&models.AuthLog{}))
 
 	// 获取总数
 	if err := query.Count(&total).Error; err != nil {
@@ -70,9 +138,215 @@ func (d *authLogDAOImpl) List(ctx context.Context, offset, limit int, username s
 	}
 
 	// 获取分页数据，按创建时间倒序
-	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&logs).Error; err != nil {
+	if err := query.Order("created_at DESC, id DESC").Offset(filter.Offset).Limit(filter.Limit).Find(&logs).Error; err != nil {
 		return nil, 0, err
 	}
 
 	return logs, total, nil
-}
\ No newline at end of file
+}
+
+// ListPage 按 created_at, id 倒序返回 after 之后的至多 limit 条日志，不随翻页深度变慢；忽略 Offset / Limit
+func (d *authLogDAOImpl) ListPage(ctx context.Context, filter AuthLogFilter, after *AuthLogCursor, limit int) ([]models.AuthLog, error) {
+	query := filter.apply(d.db.WithContext(ctx).Model(&models.AuthLog{}))
+	if after != nil {
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", after.CreatedAt, after.CreatedAt, after.ID)
+	}
+
+	var logs []models.AuthLog
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+func (d *authLogDAOImpl) Count(ctx context.Context, filter AuthLogFilter) (int64, error) {
+	var count int64
+	err := filter.apply(d.db.WithContext(ctx).Model(&models.AuthLog{})).Count(&count).Error
+	return count, err
+}
+
+// CountUpTo 统计符合条件的日志数，最多数到 max，避免在大表上完整扫描
+func (d *authLogDAOImpl) CountUpTo(ctx context.Context, filter AuthLogFilter, max int64) (int64, error) {
+	var count int64
+	sub := filter.apply(d.db.WithContext(ctx).Model(&models.AuthLog{})).Select("1").Limit(int(max))
+	err := d.db.WithContext(ctx).Table("(?) AS capped", sub).Count(&count).Error
+	return count, err
+}
+
+// EstimateTotal 从表统计信息读取总行数估计值，不支持时 ok 为 false
+func (d *authLogDAOImpl) EstimateTotal(ctx context.Context) (int64, bool, error) {
+	var query string
+	switch d.db.Dialector.Name() {
+	case "mysql":
+		query = "SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?"
+	case "postgres":
+		// 从未 ANALYZE 的表 reltuples 为 -1
+		query = "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)"
+	default:
+		return 0, false, nil
+	}
+	var rows int64
+	err := d.db.WithContext(ctx).Raw(query, models.AuthLog{}.TableName()).Scan(&rows).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if rows < 0 {
+		return 0, false, nil
+	}
+	return rows, true, nil
+}
+
+// Each 按 ID 倒序分批读取符合条件的全部日志，忽略 Offset / Limit，内存占用只与 batchSize 有关
+func (d *authLogDAOImpl) Each(ctx context.Context, filter AuthLogFilter, batchSize int, fn func([]models.AuthLog) error) error {
+	var beforeID uint
+	for {
+		query := filter.apply(d.db.WithContext(ctx).Model(&models.AuthLog{}))
+		if beforeID > 0 {
+			query = query.Where("id < ?", beforeID)
+		}
+
+		var logs []models.AuthLog
+		if err := query.Order("id DESC").Limit(batchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		if err := fn(logs); err != nil {
+			return err
+		}
+		beforeID = logs[len(logs)-1].ID
+	}
+}
+
+// RowLimitBoundary 返回保留最新 keep 条之外的最大 ID，没有超出的日志时返回 0
+func (d *authLogDAOImpl) RowLimitBoundary(ctx context.Context, keep int) (uint, error) {
+	var ids []uint
+	err := d.db.WithContext(ctx).Model(&models.AuthLog{}).
+		Order("id DESC").Offset(keep).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// ListExpired 按 ID 顺序返回早于 before 或 ID 不大于 throughID 的至多 limit 条日志，零值条件不参与；
+// 结果的 ID 都不大于 maxID
+func (d *authLogDAOImpl) ListExpired(ctx context.Context, before time.Time, throughID, maxID uint, limit int) ([]models.AuthLog, error) {
+	query := d.db.WithContext(ctx).Model(&models.AuthLog{}).Where("id <= ?", maxID)
+	switch {
+	case !before.IsZero() && throughID > 0:
+		query = query.Where("created_at < ? OR id <= ?", before, throughID)
+	case !before.IsZero():
+		query = query.Where("created_at < ?", before)
+	case throughID > 0:
+		query = query.Where("id <= ?", throughID)
+	default:
+		return nil, nil
+	}
+
+	var logs []models.AuthLog
+	err := query.Order("id ASC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+func (d *authLogDAOImpl) DeleteByIDs(ctx context.Context, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := d.db.WithContext(ctx).Where("id IN ?", ids).Delete(&models.AuthLog{})
+	return result.RowsAffected, result.Error
+}
+
+// ListAfter 按 ID 顺序返回 afterID 之后的至多 limit 条日志
+func (d *authLogDAOImpl) ListAfter(ctx context.Context, afterID uint, limit int) ([]models.AuthLog, error) {
+	var logs []models.AuthLog
+	err := d.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id ASC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// analyticsRange 统计查询的公共条件；afterID 非零时只统计尚未汇总的日志
+func (d *authLogDAOImpl) analyticsRange(ctx context.Context, from, to time.Time, afterID uint) *gorm.DB {
+	query := d.db.WithContext(ctx).Model(&models.AuthLog{}).Where("created_at >= ? AND created_at < ?", from, to)
+	if afterID > 0 {
+		query = query.Where("id > ?", afterID)
+	}
+	return query
+}
+
+// minuteExpr 把 created_at 格式化为所在分钟，返回结果所在的时区。
+// MySQL 按连接时区（loc=Local）保存时间，PostgreSQL 与 SQLite 统一换算为 UTC
+func (d *authLogDAOImpl) minuteExpr() (string, *time.Location) {
+	switch d.db.Dialector.Name() {
+	case "mysql":
+		return "DATE_FORMAT(created_at, '%Y-%m-%d %H:%i:00')", time.Local
+	case "sqlite":
+		return "strftime('%Y-%m-%d %H:%M:00', created_at)", time.UTC
+	default:
+		return "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:00')", time.UTC
+	}
+}
+
+// MinuteSeries 按分钟统计成功与失败次数
+func (d *authLogDAOImpl) MinuteSeries(ctx context.Context, from, to time.Time, afterID uint) ([]SeriesPoint, error) {
+	var rows []struct {
+		Bucket  string
+		Success bool
+		Count   int64
+	}
+	expr, loc := d.minuteExpr()
+	err := d.analyticsRange(ctx, from, to, afterID).
+		Select(expr + " AS bucket, success, COUNT(*) AS count").
+		Group(expr + ", success").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]SeriesPoint, 0, len(rows))
+	for _, row := range rows {
+		bucket, err := time.ParseInLocation("2006-01-02 15:04:05", row.Bucket, loc)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, SeriesPoint{Bucket: bucket, Success: row.Success, Count: row.Count})
+	}
+	return points, nil
+}
+
+func (d *authLogDAOImpl) Breakdown(ctx context.Context, dimension string, from, to time.Time, afterID uint) ([]BreakdownRow, error) {
+	column, ok := AnalyticsDimensions[dimension]
+	if !ok {
+		return nil, errors.New("unknown dimension " + dimension)
+	}
+	var rows []BreakdownRow
+	err := d.analyticsRange(ctx, from, to, afterID).
+		Select(column.AuthLog + " AS dim, success, COUNT(*) AS count").
+		Group(column.AuthLog + ", success").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// TopFailures 失败次数最多的用户，limit 不大于 0 时返回全部
+func (d *authLogDAOImpl) TopFailures(ctx context.Context, from, to time.Time, afterID uint, limit int) ([]UserCount, error) {
+	var users []UserCount
+	query := d.analyticsRange(ctx, from, to, afterID).
+		Select("username, COUNT(*) AS count").
+		Where("success = ?", false).
+		Group("username").Order("count DESC, username")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&users).Error
+	return users, err
+}
+
+// DistinctDevices 时间范围内出现过的不同设备 MAC 数，只能统计仍保留的原始日志
+func (d *authLogDAOImpl) DistinctDevices(ctx context.Context, from, to time.Time) (int64, error) {
+	var count int64
+	err := d.analyticsRange(ctx, from, to, 0).
+		Where("device_mac <> ?", "").
+		Select("COUNT(DISTINCT device_mac)").
+		Scan(&count).Error
+	return count, err
+}
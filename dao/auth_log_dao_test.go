@@ -0,0 +1,56 @@
+package dao_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+func TestAuthLogListPageWalksEveryRowOnce(t *testing.T) {
+	dbtest.Open(t)
+	ctx := context.Background()
+
+	// 同一时刻的多条日志须按 ID 区分；不同时区的时间须按绝对时间排序
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	shanghai := time.FixedZone("CST", 8*3600)
+	times := []time.Time{base, base, base, base.Add(time.Second).In(shanghai), base.Add(-time.Second).In(shanghai), base.Add(2 * time.Second)}
+	var logs []*models.AuthLog
+	for i, at := range times {
+		logs = append(logs, &models.AuthLog{Username: "alice", AuthType: "authenticate", Success: i%2 == 0, IPAddress: "10.0.0.1", CreatedAt: at})
+	}
+	if err := database.DAO.AuthLog.CreateBatch(ctx, logs); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []uint
+	var cursor *dao.AuthLogCursor
+	for page := 0; page < 10; page++ {
+		rows, err := database.DAO.AuthLog.ListPage(ctx, dao.AuthLogFilter{Username: "alice"}, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		for _, row := range rows {
+			seen = append(seen, row.ID)
+		}
+		last := rows[len(rows)-1]
+		cursor = &dao.AuthLogCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	want := []uint{logs[5].ID, logs[3].ID, logs[2].ID, logs[1].ID, logs[0].ID, logs[4].ID}
+	if len(seen) != len(want) {
+		t.Fatalf("pages returned IDs %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("pages returned IDs %v, want %v", seen, want)
+		}
+	}
+}
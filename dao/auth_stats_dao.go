@@ -0,0 +1,202 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// 汇总进度只有一行
+const authStatsStateID = 1
+
+type AuthStatsDAO interface {
+	Watermark(ctx context.Context) (uint, error)
+	Apply(ctx context.Context, rollups []models.AuthLogRollup, from, to uint) error
+	SuccessCount(ctx context.Context, username string) (int64, error)
+	PruneHourly(ctx context.Context, before time.Time) (int64, error)
+	Series(ctx context.Context, period string, from, to time.Time) ([]SeriesPoint, error)
+	Breakdown(ctx context.Context, period, dimension string, from, to time.Time) ([]BreakdownRow, error)
+	TopFailures(ctx context.Context, period string, from, to time.Time, limit int) ([]UserCount, error)
+	FailureCounts(ctx context.Context, period string, from, to time.Time, usernames []string) ([]UserCount, error)
+}
+
+// SeriesPoint 某个时间桶内成功或失败的次数
+type SeriesPoint struct {
+	Bucket  time.Time
+	Success bool
+	Count   int64
+}
+
+// BreakdownRow 按维度分组的成功或失败次数
+type BreakdownRow struct {
+	Key     string `gorm:"column:dim"`
+	Success bool
+	Count   int64
+}
+
+type UserCount struct {
+	Username string `json:"username"`
+	Count    int64  `json:"count"`
+}
+
+// AnalyticsDimensions 可用于分组统计的维度及其列名，汇总表与 auth_logs 中的列名不完全相同
+var AnalyticsDimensions = map[string]struct{ Rollup, AuthLog string }{
+	"nas":       {"ip_address", "ip_address"},
+	"ssid":      {"target_ssid", "target_ss_id"},
+	"reason":    {"reason", "reason"},
+	"auth_type": {"auth_type", "auth_type"},
+}
+
+type authStatsDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewAuthStatsDAO(db *gorm.DB) AuthStatsDAO {
+	return &authStatsDAOImpl{db: db}
+}
+
+func (d *authStatsDAOImpl) Watermark(ctx context.Context) (uint, error) {
+	var state models.AuthStatsState
+	err := d.db.WithContext(ctx).First(&state, authStatsStateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return state.LastLogID, err
+}
+
+// ErrWatermarkMoved 汇总进度已被其他实例推进，本批汇总已回滚
+var ErrWatermarkMoved = errors.New("auth stats watermark was moved by another writer")
+
+// Apply 在同一事务中累加汇总并把进度从 from 推进到 to，保证每条日志只被计入一次。
+// 进度已不是 from 时（多个实例同时汇总）回滚并返回 ErrWatermarkMoved
+func (d *authStatsDAOImpl) Apply(ctx context.Context, rollups []models.AuthLogRollup, from, to uint) error {
+	// 冲突时把本批计数加到已有行上
+	increment := gorm.Expr(`"auth_log_rollups"."count" + EXCLUDED."count"`)
+	if d.db.Dialector.Name() == "mysql" {
+		increment = gorm.Expr("`auth_log_rollups`.`count` + VALUES(`count`)")
+	}
+
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先推进进度：并发的汇总在进度行上排队，前一个提交后后一个的条件不再成立。首次汇总时还没有进度行
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.AuthStatsState{ID: authStatsStateID}).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&models.AuthStatsState{}).
+			Where("id = ? AND last_log_id = ?", authStatsStateID, from).
+			Updates(map[string]interface{}{"last_log_id": to, "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWatermarkMoved
+		}
+
+		if len(rollups) > 0 {
+			err = tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{
+					{Name: "period"}, {Name: "bucket_start"}, {Name: "username"}, {Name: "ip_address"},
+					{Name: "target_ssid"}, {Name: "auth_type"}, {Name: "success"}, {Name: "reason"},
+				},
+				DoUpdates: clause.Assignments(map[string]interface{}{"count": increment}),
+			}).CreateInBatches(&rollups, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SuccessCount 成功认证总次数，username 为空时统计全部用户。汇总之外只需统计进度之后的少量日志；
+// 在同一事务中读取以免与汇总任务交错导致重复或遗漏计数。已被清理的日志仍计入
+func (d *authStatsDAOImpl) SuccessCount(ctx context.Context, username string) (int64, error) {
+	var total int64
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var state models.AuthStatsState
+		if err := tx.Where("id = ?", authStatsStateID).Limit(1).Find(&state).Error; err != nil {
+			return err
+		}
+
+		rolled := tx.Model(&models.AuthLogRollup{}).Where("period = ? AND success = ?", models.RollupDay, true)
+		recent := tx.Model(&models.AuthLog{}).Where("id > ? AND success = ?", state.LastLogID, true)
+		if username != "" {
+			rolled = rolled.Where("username = ?", username)
+			recent = recent.Where("username = ?", username)
+		}
+
+		var sum, count int64
+		if err := rolled.Select("COALESCE(SUM(count), 0)").Scan(&sum).Error; err != nil {
+			return err
+		}
+		if err := recent.Count(&count).Error; err != nil {
+			return err
+		}
+		total = sum + count
+		return nil
+	}, &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead})
+	return total, err
+}
+
+// PruneHourly 删除早于 before 的小时汇总，按天汇总永久保留
+func (d *authStatsDAOImpl) PruneHourly(ctx context.Context, before time.Time) (int64, error) {
+	result := d.db.WithContext(ctx).
+		Where("period = ? AND bucket_start < ?", models.RollupHour, before).
+		Delete(&models.AuthLogRollup{})
+	return result.RowsAffected, result.Error
+}
+
+// Series 按汇总桶（UTC 小时或天）返回 [from, to) 内的成功与失败次数
+func (d *authStatsDAOImpl) Series(ctx context.Context, period string, from, to time.Time) ([]SeriesPoint, error) {
+	var points []SeriesPoint
+	err := d.db.WithContext(ctx).Model(&models.AuthLogRollup{}).
+		Select("bucket_start AS bucket, success, SUM(count) AS count").
+		Where("period = ? AND bucket_start >= ? AND bucket_start < ?", period, from, to).
+		Group("bucket_start, success").
+		Scan(&points).Error
+	return points, err
+}
+
+func (d *authStatsDAOImpl) Breakdown(ctx context.Context, period, dimension string, from, to time.Time) ([]BreakdownRow, error) {
+	column, ok := AnalyticsDimensions[dimension]
+	if !ok {
+		return nil, errors.New("unknown dimension " + dimension)
+	}
+	var rows []BreakdownRow
+	err := d.db.WithContext(ctx).Model(&models.AuthLogRollup{}).
+		Select(column.Rollup+" AS dim, success, SUM(count) AS count").
+		Where("period = ? AND bucket_start >= ? AND bucket_start < ?", period, from, to).
+		Group(column.Rollup + ", success").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// TopFailures 失败次数最多的用户
+func (d *authStatsDAOImpl) TopFailures(ctx context.Context, period string, from, to time.Time, limit int) ([]UserCount, error) {
+	var users []UserCount
+	err := d.db.WithContext(ctx).Model(&models.AuthLogRollup{}).
+		Select("username, SUM(count) AS count").
+		Where("period = ? AND success = ? AND bucket_start >= ? AND bucket_start < ?", period, false, from, to).
+		Group("username").Order("count DESC, username").Limit(limit).
+		Scan(&users).Error
+	return users, err
+}
+
+// FailureCounts 指定用户的失败次数，没有失败的用户不返回
+func (d *authStatsDAOImpl) FailureCounts(ctx context.Context, period string, from, to time.Time, usernames []string) ([]UserCount, error) {
+	var users []UserCount
+	err := d.db.WithContext(ctx).Model(&models.AuthLogRollup{}).
+		Select("username, SUM(count) AS count").
+		Where("period = ? AND success = ? AND bucket_start >= ? AND bucket_start < ?", period, false, from, to).
+		Where("username IN ?", usernames).
+		Group("username").
+		Scan(&users).Error
+	return users, err
+}
@@ -3,13 +3,33 @@ package dao
 import "gorm.io/gorm"
 
 type DAOManager struct {
-	User    UserDAO
-	AuthLog AuthLogDAO
+	User            UserDAO
+	AuthLog         AuthLogDAO
+	Group           GroupDAO
+	GuestRequest    GuestRequestDAO
+	AuditLog        AuditLogDAO
+	MFA             MFADAO
+	WebAuthn        WebAuthnDAO
+	PasswordHistory PasswordHistoryDAO
+	PasswordReset   PasswordResetDAO
+	RetentionRun    RetentionRunDAO
+	AuthStats       AuthStatsDAO
+	Setting         SettingDAO
 }
 
 func NewDAOManager(db *gorm.DB) *DAOManager {
 	return &DAOManager{
-		User:    NewUserDAO(db),
-		AuthLog: NewAuthLogDAO(db),
+		User:            NewUserDAO(db),
+		AuthLog:         NewAuthLogDAO(db),
+		Group:           NewGroupDAO(db),
+		GuestRequest:    NewGuestRequestDAO(db),
+		AuditLog:        NewAuditLogDAO(db),
+		MFA:             NewMFADAO(db),
+		WebAuthn:        NewWebAuthnDAO(db),
+		PasswordHistory: NewPasswordHistoryDAO(db),
+		PasswordReset:   NewPasswordResetDAO(db),
+		RetentionRun:    NewRetentionRunDAO(db),
+		AuthStats:       NewAuthStatsDAO(db),
+		Setting:         NewSettingDAO(db),
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,246 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type GroupDAO interface {
+	GetByName(ctx context.Context, name string) (*models.Group, error)
+	GetOrCreate(ctx context.Context, name string) (*models.Group, error)
+	List(ctx context.Context) ([]models.Group, error)
+	IsMemberOfAny(ctx context.Context, userID uint, names []string) (bool, error)
+	SyncMembership(ctx context.Context, userID uint, managed, memberOf []string) error
+	GetByID(ctx context.Context, id uint) (*models.Group, error)
+	Create(ctx context.Context, group *models.Group) error
+	Update(ctx context.Context, group *models.Group) error
+	Delete(ctx context.Context, id uint) error
+	Search(ctx context.Context, where string, args []interface{}, offset, limit int) ([]models.Group, int64, error)
+	Members(ctx context.Context, groupID uint) ([]models.User, error)
+	UpdateMembers(ctx context.Context, groupID uint, add, remove []uint) error
+	ReplaceUserGroups(ctx context.Context, userID uint, names []string) error
+}
+
+type groupDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewGroupDAO(db *gorm.DB) GroupDAO {
+	return &groupDAOImpl{db: db}
+}
+
+func (d *groupDAOImpl) GetByName(ctx context.Context, name string) (*models.Group, error) {
+	var group models.Group
+	err := d.db.WithContext(ctx).Where("name = ?", name).First(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (d *groupDAOImpl) GetOrCreate(ctx context.Context, name string) (*models.Group, error) {
+	group := models.Group{Name: name}
+	err := d.db.WithContext(ctx).Where("name = ?", name).FirstOrCreate(&group).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (d *groupDAOImpl) List(ctx context.Context) ([]models.Group, error) {
+	var groups []models.Group
+	err := d.db.WithContext(ctx).Order("name ASC").Find(&groups).Error
+	return groups, err
+}
+
+func (d *groupDAOImpl) IsMemberOfAny(ctx context.Context, userID uint, names []string) (bool, error) {
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err := d.db.WithContext(ctx).Model(&models.Group{}).
+		Joins("JOIN user_groups ON user_groups.group_id = radius_groups.id").
+		Where("user_groups.user_id = ? AND radius_groups.name IN ?", userID, names).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SyncMembership 将用户在 managed 这些组中的成员关系同步为 memberOf，不影响其他组
+func (d *groupDAOImpl) SyncMembership(ctx context.Context, userID uint, managed, memberOf []string) error {
+	if len(managed) == 0 {
+		return nil
+	}
+
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var groups []models.Group
+		seen := make(map[string]bool, len(managed))
+		for _, name := range managed {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			group := models.Group{Name: name}
+			if err := tx.Where("name = ?", name).FirstOrCreate(&group).Error; err != nil {
+				return err
+			}
+			groups = append(groups, group)
+		}
+
+		wanted := make(map[string]bool, len(memberOf))
+		for _, name := range memberOf {
+			wanted[name] = true
+		}
+
+		// 先删除全部受管组的成员关系再按需插入，避免依赖各数据库不同的冲突语法
+		var managedIDs []uint
+		var rows []map[string]interface{}
+		for _, group := range groups {
+			managedIDs = append(managedIDs, group.ID)
+			if wanted[group.Name] {
+				rows = append(rows, map[string]interface{}{"user_id": userID, "group_id": group.ID})
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM user_groups WHERE user_id = ? AND group_id IN ?", userID, managedIDs).Error; err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			if err := tx.Table("user_groups").Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d *groupDAOImpl) GetByID(ctx context.Context, id uint) (*models.Group, error) {
+	var group models.Group
+	err := d.db.WithContext(ctx).First(&group, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (d *groupDAOImpl) Create(ctx context.Context, group *models.Group) error {
+	return d.db.WithContext(ctx).Create(group).Error
+}
+
+func (d *groupDAOImpl) Update(ctx context.Context, group *models.Group) error {
+	return d.db.WithContext(ctx).Save(group).Error
+}
+
+func (d *groupDAOImpl) Delete(ctx context.Context, id uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_groups WHERE group_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Group{}, id).Error
+	})
+}
+
+// Search 按 where 条件分页查询，where 为空时查询全部
+func (d *groupDAOImpl) Search(ctx context.Context, where string, args []interface{}, offset, limit int) ([]models.Group, int64, error) {
+	var groups []models.Group
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&models.Group{})
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		return groups, total, nil
+	}
+
+	if err := query.Order("id ASC").Offset(offset).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (d *groupDAOImpl) Members(ctx context.Context, groupID uint) ([]models.User, error) {
+	var users []models.User
+	err := d.db.WithContext(ctx).
+		Joins("JOIN user_groups ON user_groups.user_id = users.id").
+		Where("user_groups.group_id = ?", groupID).
+		Order("users.id ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// UpdateMembers 增删组成员，不存在的用户 ID 被忽略
+func (d *groupDAOImpl) UpdateMembers(ctx context.Context, groupID uint, add, remove []uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(remove) > 0 {
+			if err := tx.Exec("DELETE FROM user_groups WHERE group_id = ? AND user_id IN ?", groupID, remove).Error; err != nil {
+				return err
+			}
+		}
+		if len(add) == 0 {
+			return nil
+		}
+
+		var existing []uint
+		if err := tx.Model(&models.User{}).Where("id IN ?", add).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			return nil
+		}
+		// 先删后插，避免重复成员触发主键冲突
+		if err := tx.Exec("DELETE FROM user_groups WHERE group_id = ? AND user_id IN ?", groupID, existing).Error; err != nil {
+			return err
+		}
+		rows := make([]map[string]interface{}, 0, len(existing))
+		for _, userID := range existing {
+			rows = append(rows, map[string]interface{}{"user_id": userID, "group_id": groupID})
+		}
+		return tx.Table("user_groups").Create(&rows).Error
+	})
+}
+
+// ErrGroupMembership 写入组成员关系失败，调用方据此区分组数据的问题与用户本身的错误
+var ErrGroupMembership = errors.New("update group membership")
+
+// ReplaceUserGroups 将用户的组成员关系整体替换为 names，不存在的组会被创建
+func (d *groupDAOImpl) ReplaceUserGroups(ctx context.Context, userID uint, names []string) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_groups WHERE user_id = ?", userID).Error; err != nil {
+			return err
+		}
+		return addUserGroups(tx, userID, names)
+	})
+}
+
+// addUserGroups 在 tx 中把用户加入 names 各组；按组 ID 去重，大小写不敏感的排序规则下 a 与 A 是同一组
+func addUserGroups(tx *gorm.DB, userID uint, names []string) error {
+	seen := make(map[uint]bool, len(names))
+	var rows []map[string]interface{}
+	for _, name := range names {
+		group := models.Group{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&group).Error; err != nil {
+			return fmt.Errorf("%w: group %q: %v", ErrGroupMembership, name, err)
+		}
+		if seen[group.ID] {
+			continue
+		}
+		seen[group.ID] = true
+		rows = append(rows, map[string]interface{}{"user_id": userID, "group_id": group.ID})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := tx.Table("user_groups").Create(&rows).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrGroupMembership, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type GuestRequestDAO interface {
+	Create(ctx context.Context, req *models.GuestRequest) error
+	GetByID(ctx context.Context, id uint) (*models.GuestRequest, error)
+	ListBySponsor(ctx context.Context, sponsorID uint, status string, offset, limit int) ([]models.GuestRequest, int64, error)
+	CountPendingByUsername(ctx context.Context, username string) (int64, error)
+	Approve(ctx context.Context, req *models.GuestRequest, guest *models.User) error
+	UpdateStatus(ctx context.Context, id uint, status, note string) error
+	ExpirePending(ctx context.Context, before time.Time) (int64, error)
+}
+
+type guestRequestDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewGuestRequestDAO(db *gorm.DB) GuestRequestDAO {
+	return &guestRequestDAOImpl{db: db}
+}
+
+func (d *guestRequestDAOImpl) Create(ctx context.Context, req *models.GuestRequest) error {
+	return d.db.WithContext(ctx).Create(req).Error
+}
+
+func (d *guestRequestDAOImpl) GetByID(ctx context.Context, id uint) (*models.GuestRequest, error) {
+	var req models.GuestRequest
+	err := d.db.WithContext(ctx).First(&req, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (d *guestRequestDAOImpl) ListBySponsor(ctx context.Context, sponsorID uint, status string, offset, limit int) ([]models.GuestRequest, int64, error) {
+	var reqs []models.GuestRequest
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&models.GuestRequest{}).Where("sponsor_id = ?", sponsorID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&reqs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return reqs, total, nil
+}
+
+func (d *guestRequestDAOImpl) CountPendingByUsername(ctx context.Context, username string) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&models.GuestRequest{}).
+		Where("username = ? AND status = ?", username, models.GuestRequestPending).
+		Count(&count).Error
+	return count, err
+}
+
+// Approve 在同一事务中创建访客账号并更新申请状态
+func (d *guestRequestDAOImpl) Approve(ctx context.Context, req *models.GuestRequest, guest *models.User) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(guest).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		req.Status = models.GuestRequestApproved
+		req.GuestUserID = &guest.ID
+		req.ExpiresAt = guest.ExpiresAt
+		req.DecidedAt = &now
+
+		return tx.Model(&models.GuestRequest{}).
+			Where("id = ? AND status = ?", req.ID, models.GuestRequestPending).
+			Updates(map[string]interface{}{
+				"status":        req.Status,
+				"guest_user_id": guest.ID,
+				"expires_at":    req.ExpiresAt,
+				"decided_at":    now,
+			}).Error
+	})
+}
+
+func (d *guestRequestDAOImpl) UpdateStatus(ctx context.Context, id uint, status, note string) error {
+	return d.db.WithContext(ctx).Model(&models.GuestRequest{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        status,
+		"decision_note": note,
+		"decided_at":    time.Now(),
+	}).Error
+}
+
+func (d *guestRequestDAOImpl) ExpirePending(ctx context.Context, before time.Time) (int64, error) {
+	result := d.db.WithContext(ctx).Model(&models.GuestRequest{}).
+		Where("status = ? AND created_at < ?", models.GuestRequestPending, before).
+		Updates(map[string]interface{}{
+			"status":     models.GuestRequestExpired,
+			"decided_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
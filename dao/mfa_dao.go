@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type MFADAO interface {
+	GetByUserID(ctx context.Context, userID uint) (*models.UserMFA, error)
+	Save(ctx context.Context, mfa *models.UserMFA) error
+	Enable(ctx context.Context, userID uint, step int64) error
+	Delete(ctx context.Context, userID uint) error
+	DeleteTOTP(ctx context.Context, userID uint) error
+	ConsumeStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceBackupCodes(ctx context.Context, userID uint, hashes []string) error
+	ConsumeBackupCode(ctx context.Context, userID uint, hash string) (bool, error)
+	CountUnusedBackupCodes(ctx context.Context, userID uint) (int64, error)
+}
+
+type mfaDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewMFADAO(db *gorm.DB) MFADAO {
+	return &mfaDAOImpl{db: db}
+}
+
+func (d *mfaDAOImpl) GetByUserID(ctx context.Context, userID uint) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	err := d.db.WithContext(ctx).Where("user_id = ?", userID).First(&mfa).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (d *mfaDAOImpl) Save(ctx context.Context, mfa *models.UserMFA) error {
+	return d.db.WithContext(ctx).Save(mfa).Error
+}
+
+// Enable 启用 TOTP，并记录激活时使用的时间步，防止同一验证码再次使用
+func (d *mfaDAOImpl) Enable(ctx context.Context, userID uint, step int64) error {
+	return d.db.WithContext(ctx).Model(&models.UserMFA{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"enabled":        true,
+		"enabled_at":     time.Now(),
+		"last_used_step": step,
+	}).Error
+}
+
+func (d *mfaDAOImpl) Delete(ctx context.Context, userID uint) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFABackupCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+	})
+}
+
+// DeleteTOTP 只移除 TOTP 绑定，保留备用码供安全密钥用户恢复登录
+func (d *mfaDAOImpl) DeleteTOTP(ctx context.Context, userID uint) error {
+	return d.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+}
+
+// ConsumeStep 原子地推进最后使用的时间步，时间步不大于已使用值时返回 false
+func (d *mfaDAOImpl) ConsumeStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&models.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (d *mfaDAOImpl) ReplaceBackupCodes(ctx context.Context, userID uint, hashes []string) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFABackupCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.MFABackupCode, len(hashes))
+		for i, hash := range hashes {
+			codes[i] = models.MFABackupCode{UserID: userID, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (d *mfaDAOImpl) ConsumeBackupCode(ctx context.Context, userID uint, hash string) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&models.MFABackupCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+func (d *mfaDAOImpl) CountUnusedBackupCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&models.MFABackupCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
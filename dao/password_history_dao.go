@@ -0,0 +1,31 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type PasswordHistoryDAO interface {
+	ListRecent(ctx context.Context, userID uint, limit int) ([]models.PasswordHistory, error)
+}
+
+type passwordHistoryDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewPasswordHistoryDAO(db *gorm.DB) PasswordHistoryDAO {
+	return &passwordHistoryDAOImpl{db: db}
+}
+
+func (d *passwordHistoryDAOImpl) ListRecent(ctx context.Context, userID uint, limit int) ([]models.PasswordHistory, error) {
+	var history []models.PasswordHistory
+	err := d.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&history).Error
+	return history, err
+}
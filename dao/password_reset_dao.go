@@ -0,0 +1,59 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type PasswordResetDAO interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	InvalidateByUserID(ctx context.Context, userID uint) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type passwordResetDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetDAO(db *gorm.DB) PasswordResetDAO {
+	return &passwordResetDAOImpl{db: db}
+}
+
+func (d *passwordResetDAOImpl) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return d.db.WithContext(ctx).Create(token).Error
+}
+
+func (d *passwordResetDAOImpl) GetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := d.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed 原子地标记令牌已使用，返回 false 表示已被其他请求抢先使用
+func (d *passwordResetDAOImpl) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := d.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// InvalidateByUserID 作废用户所有未使用的令牌
+func (d *passwordResetDAOImpl) InvalidateByUserID(ctx context.Context, userID uint) error {
+	return d.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+func (d *passwordResetDAOImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := d.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&models.PasswordResetToken{})
+	return result.RowsAffected, result.Error
+}
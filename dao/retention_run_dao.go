@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type RetentionRunDAO interface {
+	Create(ctx context.Context, run *models.RetentionRun) error
+	Update(ctx context.Context, run *models.RetentionRun) error
+	Latest(ctx context.Context) (*models.RetentionRun, error)
+}
+
+type retentionRunDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewRetentionRunDAO(db *gorm.DB) RetentionRunDAO {
+	return &retentionRunDAOImpl{db: db}
+}
+
+func (d *retentionRunDAOImpl) Create(ctx context.Context, run *models.RetentionRun) error {
+	return d.db.WithContext(ctx).Create(run).Error
+}
+
+func (d *retentionRunDAOImpl) Update(ctx context.Context, run *models.RetentionRun) error {
+	return d.db.WithContext(ctx).Save(run).Error
+}
+
+func (d *retentionRunDAOImpl) Latest(ctx context.Context) (*models.RetentionRun, error) {
+	var run models.RetentionRun
+	err := d.db.WithContext(ctx).Order("started_at DESC, id DESC").First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
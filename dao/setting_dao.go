@@ -0,0 +1,94 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type SettingDAO interface {
+	List(ctx context.Context) ([]models.Setting, error)
+	// Apply 在一个事务中写入或删除设置项并记录历史，change.OldValue 由数据库中的当前值填充。
+	// 新值与当前值相同时不写入，返回 false
+	Apply(ctx context.Context, change *models.SettingChange) (bool, error)
+	History(ctx context.Context, key string, limit int) ([]models.SettingChange, error)
+}
+
+type settingDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewSettingDAO(db *gorm.DB) SettingDAO {
+	return &settingDAOImpl{db: db}
+}
+
+func (d *settingDAOImpl) List(ctx context.Context) ([]models.Setting, error) {
+	var settings []models.Setting
+	err := d.db.WithContext(ctx).Order("setting_key ASC").Find(&settings).Error
+	return settings, err
+}
+
+func (d *settingDAOImpl) Apply(ctx context.Context, change *models.SettingChange) (bool, error) {
+	changed := false
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Setting
+		err := tx.Where("setting_key = ?", change.Key).First(&current).Error
+		switch {
+		case err == nil:
+			change.OldValue = &current.Value
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if sameValue(change.OldValue, change.NewValue) {
+			return nil
+		}
+
+		if change.NewValue == nil {
+			if err := tx.Where("setting_key = ?", change.Key).Delete(&models.Setting{}).Error; err != nil {
+				return err
+			}
+		} else {
+			setting := models.Setting{
+				Key:       change.Key,
+				Value:     *change.NewValue,
+				UpdatedBy: change.ActorName,
+				UpdatedAt: time.Now(),
+			}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "setting_key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+			}).Create(&setting).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(change).Error; err != nil {
+			return err
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
+
+func sameValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (d *settingDAOImpl) History(ctx context.Context, key string, limit int) ([]models.SettingChange, error) {
+	var changes []models.SettingChange
+	err := d.db.WithContext(ctx).
+		Where("setting_key = ?", key).
+		Order("id DESC").
+		Limit(limit).
+		Find(&changes).Error
+	return changes, err
+}
@@ -2,6 +2,8 @@ package dao
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -10,19 +12,114 @@ import (
 
 type UserDAO interface {
 	Create(ctx context.Context, user *models.User) error
+	CreateWithGroups(ctx context.Context, user *models.User, groups []string) error
 	GetByID(ctx context.Context, id uint) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByUsernameOrEmail(ctx context.Context, username, email string) (*models.User, error)
 	GetByUsernameForAuth(ctx context.Context, username string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context, offset, limit int) ([]models.User, int64, error)
+	List(ctx context.Context, filter UserFilter) ([]models.User, int64, error)
 	CountAdmins(ctx context.Context) (int64, error)
 	UpdatePassword(ctx context.Context, id uint, password, salt string) error
+	ChangePassword(ctx context.Context, user *models.User, previous string, keepHistory int) error
 	UpdateBanned(ctx context.Context, id uint, banned bool) error
+	UpdateRequireMFA(ctx context.Context, id uint, required bool) error
 	GetTotalCount(ctx context.Context) (int64, error)
 	GetActiveCount(ctx context.Context) (int64, error)
 	GetBannedCount(ctx context.Context) (int64, error)
+	ListByStatus(ctx context.Context, status string, offset, limit int) ([]models.User, int64, error)
+	UpdateStatus(ctx context.Context, id uint, status string) error
+	ListExpired(ctx context.Context, now time.Time) ([]models.User, error)
+	GetByIDWithGroups(ctx context.Context, id uint) (*models.User, error)
+	GetConflicting(ctx context.Context, excludeID uint, username, email string, externalID *string) (*models.User, error)
+	Search(ctx context.Context, where string, args []interface{}, offset, limit int) ([]models.User, int64, error)
+	ListAfter(ctx context.Context, afterID uint, limit int) ([]models.User, error)
+	UpdateLastLogin(ctx context.Context, id uint, at time.Time) error
+}
+
+// UserSortColumns 可用于排序的列
+var UserSortColumns = map[string]bool{
+	"id": true, "username": true, "email": true, "is_admin": true, "banned": true,
+	"status": true, "auth_source": true, "expires_at": true, "last_login_at": true,
+	"created_at": true, "updated_at": true,
+}
+
+// UserFilter 管理端用户列表的查询条件，零值字段不参与过滤
+type UserFilter struct {
+	// Query 用户名或邮箱包含该子串
+	Query      string
+	IsAdmin    *bool
+	Banned     *bool
+	Status     string
+	AuthSource string
+	// Group 属于该用户组
+	Group string
+
+	CreatedAfter    *time.Time
+	CreatedBefore   *time.Time
+	LastLoginAfter  *time.Time
+	LastLoginBefore *time.Time
+
+	// SortBy 须为 UserSortColumns 中的列，为空时按 ID 排序
+	SortBy   string
+	SortDesc bool
+	Offset   int
+	Limit    int
+}
+
+func (f UserFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Query != "" {
+		pattern := "%" + EscapeLike(f.Query) + "%"
+		query = query.Where("LOWER(username) LIKE LOWER(?) ESCAPE '!' OR LOWER(email) LIKE LOWER(?) ESCAPE '!'", pattern, pattern)
+	}
+	if f.IsAdmin != nil {
+		query = query.Where("is_admin = ?", *f.IsAdmin)
+	}
+	if f.Banned != nil {
+		query = query.Where("banned = ?", *f.Banned)
+	}
+	if f.Status != "" {
+		query = query.Where("status = ?", f.Status)
+	}
+	if f.AuthSource != "" {
+		query = query.Where("auth_source = ?", f.AuthSource)
+	}
+	if f.Group != "" {
+		query = query.Where("id IN (SELECT user_groups.user_id FROM user_groups JOIN radius_groups ON radius_groups.id = user_groups.group_id WHERE radius_groups.name = ?)", f.Group)
+	}
+	if f.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *f.CreatedBefore)
+	}
+	if f.LastLoginAfter != nil {
+		query = query.Where("last_login_at >= ?", *f.LastLoginAfter)
+	}
+	if f.LastLoginBefore != nil {
+		query = query.Where("last_login_at < ?", *f.LastLoginBefore)
+	}
+	return query
+}
+
+// EscapeLike 转义 LIKE 模式中的通配符，须配合 ESCAPE '!' 使用。
+// 以 ! 作为转义符：反斜杠在 MySQL 与 PostgreSQL 字面量中的含义不同，SQLite 则没有默认转义符
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(s)
+}
+
+// order 非主键排序时追加 id 保证分页结果稳定
+func (f UserFilter) order() string {
+	direction := "ASC"
+	if f.SortDesc {
+		direction = "DESC"
+	}
+	if f.SortBy == "" || f.SortBy == "id" || !UserSortColumns[f.SortBy] {
+		return "id " + direction
+	}
+	return f.SortBy + " " + direction + ", id " + direction
 }
 
 type userDAOImpl struct {
@@ -37,6 +134,16 @@ func (d *userDAOImpl) Create(ctx context.Context, user *models.User) error {
 	return d.db.WithContext(ctx).Create(user).Error
 }
 
+// CreateWithGroups 在同一事务中创建用户并加入 groups 各组，不存在的组会被创建；任一步失败都不会留下用户
+func (d *userDAOImpl) CreateWithGroups(ctx context.Context, user *models.User, groups []string) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return addUserGroups(tx, user.ID, groups)
+	})
+}
+
 func (d *userDAOImpl) GetByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
 	err := d.db.WithContext(ctx).First(&user, id).Error
@@ -55,6 +162,15 @@ func (d *userDAOImpl) GetByUsername(ctx context.Context, username string) (*mode
 	return &user, nil
 }
 
+func (d *userDAOImpl) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := d.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (d *userDAOImpl) GetByUsernameOrEmail(ctx context.Context, username, email string) (*models.User, error) {
 	var user models.User
 	err := d.db.WithContext(ctx).Where("username = ? OR email = ?", username, email).First(&user).Error
@@ -64,24 +180,41 @@ func (d *userDAOImpl) GetByUsernameOrEmail(ctx context.Context, username, email
 	return &user, nil
 }
 
-
 func (d *userDAOImpl) Update(ctx context.Context, user *models.User) error {
 	return d.db.WithContext(ctx).Save(user).Error
 }
 
+// Delete 在同一事务中删除用户及其密码历史、组关系、MFA 凭据与重置令牌，避免 ID 被复用时残留的凭据生效
 func (d *userDAOImpl) Delete(ctx context.Context, id uint) error {
-	return d.db.WithContext(ctx).Delete(&models.User{}, id).Error
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, owned := range []interface{}{
+			&models.PasswordHistory{},
+			&models.UserMFA{},
+			&models.MFABackupCode{},
+			&models.WebAuthnCredential{},
+			&models.PasswordResetToken{},
+		} {
+			if err := tx.Where("user_id = ?", id).Delete(owned).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("DELETE FROM user_groups WHERE user_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
 }
 
-func (d *userDAOImpl) List(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+func (d *userDAOImpl) List(ctx context.Context, filter UserFilter) ([]models.User, int64, error) {
 	var users []models.User
 	var total int64
 
-	if err := d.db.WithContext(ctx).Model(&models.User{}).Count(&total).Error; err != nil {
+	query := filter.apply(d.db.WithContext(ctx).Model(&models.User{}))
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	if err := d.db.WithContext(ctx).Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+	if err := query.Preload("Groups").Order(filter.order()).Offset(filter.Offset).Limit(filter.Limit).Find(&users).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -101,10 +234,45 @@ func (d *userDAOImpl) UpdatePassword(ctx context.Context, id uint, password, sal
 	}).Error
 }
 
+// ChangePassword 保存新密码，并把旧哈希写入历史，只保留最近 keepHistory 条
+func (d *userDAOImpl) ChangePassword(ctx context.Context, user *models.User, previous string, keepHistory int) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"password":             user.Password,
+			"salt":                 user.Salt,
+			"password_changed_at":  user.PasswordChangedAt,
+			"must_change_password": user.MustChangePassword,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if keepHistory <= 0 {
+			return tx.Where("user_id = ?", user.ID).Delete(&models.PasswordHistory{}).Error
+		}
+
+		if previous != "" {
+			if err := tx.Create(&models.PasswordHistory{UserID: user.ID, Password: previous}).Error; err != nil {
+				return err
+			}
+		}
+
+		var ids []uint
+		err = tx.Model(&models.PasswordHistory{}).
+			Where("user_id = ?", user.ID).
+			Order("created_at DESC, id DESC").
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) <= keepHistory {
+			return err
+		}
+		return tx.Delete(&models.PasswordHistory{}, ids[keepHistory:]).Error
+	})
+}
 
 func (d *userDAOImpl) GetByUsernameForAuth(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := d.db.WithContext(ctx).Where("username = ? AND banned = ?", username, false).First(&user).Error
+	err := d.db.WithContext(ctx).Where("username = ? AND banned = ? AND status = ?", username, false, models.UserStatusActive).
+		Where("(expires_at IS NULL OR expires_at > ?)", time.Now()).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +283,10 @@ func (d *userDAOImpl) UpdateBanned(ctx context.Context, id uint, banned bool) er
 	return d.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("banned", banned).Error
 }
 
+func (d *userDAOImpl) UpdateRequireMFA(ctx context.Context, id uint, required bool) error {
+	return d.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("require_mfa", required).Error
+}
+
 func (d *userDAOImpl) GetTotalCount(ctx context.Context) (int64, error) {
 	var count int64
 	err := d.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
@@ -123,7 +295,7 @@ func (d *userDAOImpl) GetTotalCount(ctx context.Context) (int64, error) {
 
 func (d *userDAOImpl) GetActiveCount(ctx context.Context) (int64, error) {
 	var count int64
-	err := d.db.WithContext(ctx).Model(&models.User{}).Where("banned = ?", false).Count(&count).Error
+	err := d.db.WithContext(ctx).Model(&models.User{}).Where("banned = ? AND status = ?", false, models.UserStatusActive).Count(&count).Error
 	return count, err
 }
 
@@ -132,3 +304,95 @@ func (d *userDAOImpl) GetBannedCount(ctx context.Context) (int64, error) {
 	err := d.db.WithContext(ctx).Model(&models.User{}).Where("banned = ?", true).Count(&count).Error
 	return count, err
 }
+
+func (d *userDAOImpl) ListByStatus(ctx context.Context, status string, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&models.User{}).Where("status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at ASC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (d *userDAOImpl) UpdateStatus(ctx context.Context, id uint, status string) error {
+	return d.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// ListExpired 返回已过有效期但仍处于正常状态的账号
+func (d *userDAOImpl) ListExpired(ctx context.Context, now time.Time) ([]models.User, error) {
+	var users []models.User
+	err := d.db.WithContext(ctx).
+		Where("status = ? AND expires_at IS NOT NULL AND expires_at <= ?", models.UserStatusActive, now).
+		Find(&users).Error
+	return users, err
+}
+
+func (d *userDAOImpl) GetByIDWithGroups(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := d.db.WithContext(ctx).Preload("Groups").First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetConflicting 查找除 excludeID 外用户名、邮箱或外部 ID 重复的用户
+func (d *userDAOImpl) GetConflicting(ctx context.Context, excludeID uint, username, email string, externalID *string) (*models.User, error) {
+	query := d.db.WithContext(ctx).Where("username = ?", username)
+	if email != "" {
+		query = query.Or("email = ?", email)
+	}
+	if externalID != nil {
+		query = query.Or("external_id = ?", *externalID)
+	}
+
+	var user models.User
+	err := d.db.WithContext(ctx).Where(query).Where("id <> ?", excludeID).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Search 按 where 条件分页查询并预加载用户组，where 为空时查询全部
+func (d *userDAOImpl) Search(ctx context.Context, where string, args []interface{}, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := d.db.WithContext(ctx).Model(&models.User{})
+	if where != "" {
+		query = query.Where(where, args...)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		return users, total, nil
+	}
+
+	if err := query.Preload("Groups").Order("id ASC").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// ListAfter 按 ID 顺序返回 afterID 之后的一批用户并预加载用户组，用于分批导出
+func (d *userDAOImpl) ListAfter(ctx context.Context, afterID uint, limit int) ([]models.User, error) {
+	var users []models.User
+	err := d.db.WithContext(ctx).Preload("Groups").
+		Where("id > ?", afterID).Order("id ASC").Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// UpdateLastLogin 只更新登录时间，不改变 updated_at
+func (d *userDAOImpl) UpdateLastLogin(ctx context.Context, id uint, at time.Time) error {
+	return d.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).UpdateColumn("last_login_at", at).Error
+}
@@ -0,0 +1,121 @@
+package dao_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// createUser 以预先计算的哈希建号，避免测试中执行 Argon2
+func createUser(t *testing.T, username, email string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, Email: email}
+	if err := user.SetPasswordHash("{SHA}5en6G6MezRroT3XKqkdPOmY/BfQ="); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DAO.User.Create(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func usernames(users []models.User) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Username
+	}
+	return names
+}
+
+func TestUserListQueryMatchesWildcardsLiterally(t *testing.T) {
+	dbtest.Open(t)
+	createUser(t, "alice", "alice@example.com")
+	createUser(t, "bob_smith", "bob@example.com")
+	createUser(t, "bobxsmith", "bobx@example.com")
+	createUser(t, "carol", "100%carol@example.com")
+	createUser(t, "dave!", "dave@example.com")
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"bob_", []string{"bob_smith"}},
+		{"100%", []string{"carol"}},
+		{"e!", []string{"dave!"}},
+		{"ALICE", []string{"alice"}},
+		{"example.com", []string{"alice", "bob_smith", "bobxsmith", "carol", "dave!"}},
+	}
+	for _, tc := range cases {
+		users, total, err := database.DAO.User.List(context.Background(), dao.UserFilter{Query: tc.query, Limit: 10})
+		if err != nil {
+			t.Fatalf("List(%q): %v", tc.query, err)
+		}
+		got := usernames(users)
+		if int(total) != len(tc.want) || len(got) != len(tc.want) {
+			t.Errorf("List(%q) = %v (total %d), want %v", tc.query, got, total, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("List(%q) = %v, want %v", tc.query, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestUserListSortAndPage(t *testing.T) {
+	dbtest.Open(t)
+	for _, name := range []string{"erin", "alice", "dave", "bob", "carol"} {
+		createUser(t, name, name+"@example.com")
+	}
+
+	users, total, err := database.DAO.User.List(context.Background(), dao.UserFilter{SortBy: "username", SortDesc: true, Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := usernames(users); total != 5 || len(got) != 2 || got[0] != "dave" || got[1] != "carol" {
+		t.Fatalf("page = %v (total %d), want [dave carol] of 5", got, total)
+	}
+}
+
+func TestUserDeleteRemovesCredentials(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	user := createUser(t, "alice", "alice@example.com")
+	other := createUser(t, "bob", "bob@example.com")
+
+	for _, u := range []*models.User{user, other} {
+		if err := database.DAO.MFA.Save(ctx, &models.UserMFA{UserID: u.ID, Secret: "JBSWY3DPEHPK3PXP"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.DAO.MFA.ReplaceBackupCodes(ctx, u.ID, []string{"hash-a", "hash-b"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.DAO.WebAuthn.Create(ctx, &models.WebAuthnCredential{UserID: u.ID, CredentialID: "cred-" + u.Username, Data: "{}"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := database.DAO.PasswordReset.Create(ctx, &models.PasswordResetToken{UserID: u.ID, TokenHash: "token-" + u.Username, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := database.DAO.User.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range []interface{}{&models.UserMFA{}, &models.MFABackupCode{}, &models.WebAuthnCredential{}, &models.PasswordResetToken{}} {
+		var deleted, kept int64
+		if err := db.Model(model).Where("user_id = ?", user.ID).Count(&deleted).Error; err != nil {
+			t.Fatal(err)
+		}
+		db.Model(model).Where("user_id = ?", other.ID).Count(&kept)
+		if deleted != 0 || kept == 0 {
+			t.Errorf("%T: %d rows left for the deleted user, %d for the other user", model, deleted, kept)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type WebAuthnDAO interface {
+	Create(ctx context.Context, cred *models.WebAuthnCredential) error
+	ListByUserID(ctx context.Context, userID uint) ([]models.WebAuthnCredential, error)
+	CountByUserID(ctx context.Context, userID uint) (int64, error)
+	UpdateData(ctx context.Context, id uint, data string) error
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+	DeleteByUserID(ctx context.Context, userID uint) error
+}
+
+type webAuthnDAOImpl struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnDAO(db *gorm.DB) WebAuthnDAO {
+	return &webAuthnDAOImpl{db: db}
+}
+
+func (d *webAuthnDAOImpl) Create(ctx context.Context, cred *models.WebAuthnCredential) error {
+	return d.db.WithContext(ctx).Create(cred).Error
+}
+
+func (d *webAuthnDAOImpl) ListByUserID(ctx context.Context, userID uint) ([]models.WebAuthnCredential, error) {
+	var creds []models.WebAuthnCredential
+	err := d.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&creds).Error
+	return creds, err
+}
+
+func (d *webAuthnDAOImpl) CountByUserID(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := d.db.WithContext(ctx).Model(&models.WebAuthnCredential{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// UpdateData 登录成功后保存更新过签名计数的凭据
+func (d *webAuthnDAOImpl) UpdateData(ctx context.Context, id uint, data string) error {
+	return d.db.WithContext(ctx).Model(&models.WebAuthnCredential{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"data":         data,
+		"last_used_at": time.Now(),
+	}).Error
+}
+
+func (d *webAuthnDAOImpl) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	result := d.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (d *webAuthnDAOImpl) DeleteByUserID(ctx context.Context, userID uint) error {
+	return d.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.WebAuthnCredential{}).Error
+}
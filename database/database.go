@@ -2,17 +2,19 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/dao"
 	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passhash"
+	"github.com/Gaojianli/raduis_mgnt/tracing"
 )
 
 var (
@@ -20,22 +22,37 @@ var (
 	DAO *dao.DAOManager
 )
 
+// Connect 连接数据库，按 DB_AUTO_MIGRATE 执行或检查表结构迁移，并创建默认管理员
 func Connect() error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		config.AppConfig.DBUser,
-		config.AppConfig.DBPassword,
-		config.AppConfig.DBHost,
-		config.AppConfig.DBPort,
-		config.AppConfig.DBName,
-	)
+	if err := Open(); err != nil {
+		return err
+	}
+
+	if err := migrateOnStartup(context.Background()); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	err := createDefaultAdmin()
+	if err != nil {
+		log.Printf("Warning: failed to create default admin: %v", err)
+	}
 
-	var err error
+	log.Println("Database connected successfully")
+	return nil
+}
+
+// Open 只建立连接，不检查表结构，供 admin_tool migrate 等需要在迁移前连接的命令使用
+func Open() error {
+	dialect, err := dialector()
+	if err != nil {
+		return err
+	}
 
 	// 重试连接数据库，最多重试 30 次，每次间隔 2 秒
 	maxRetries := 30
 	for i := 0; i < maxRetries; i++ {
-		DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-			Logger: logger.Default.LogMode(logger.Info),
+		DB, err = gorm.Open(dialect, &gorm.Config{
+			Logger: logger.Default.LogMode(gormLogLevel()),
 		})
 
 		if err == nil {
@@ -43,7 +60,7 @@ func Connect() error {
 			sqlDB, testErr := DB.DB()
 			if testErr == nil {
 				if pingErr := sqlDB.Ping(); pingErr == nil {
-					log.Printf("Database connected successfully on attempt %d/%d", i+1, maxRetries)
+					log.Printf("Database (%s) connected successfully on attempt %d/%d", config.AppConfig.DBDriver, i+1, maxRetries)
 					break
 				}
 			}
@@ -66,24 +83,56 @@ func Connect() error {
 		return fmt.Errorf("failed to get database instance: %w", err)
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-
-	err = DB.AutoMigrate(&models.User{}, &models.AuthLog{})
-	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	if err := DB.Use(tracing.GormPlugin{}); err != nil {
+		return fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+	if config.AppConfig.DBDriver == DriverSQLite {
+		if err := DB.Use(sqliteUTC{}); err != nil {
+			return fmt.Errorf("failed to register sqlite plugin: %w", err)
+		}
 	}
 
+	sqlDB.SetMaxIdleConns(config.AppConfig.DBPoolMaxIdle)
+	sqlDB.SetMaxOpenConns(config.AppConfig.DBPoolMaxOpen)
+	sqlDB.SetConnMaxLifetime(config.AppConfig.DBPoolMaxLifetime)
+
 	DAO = dao.NewDAOManager(DB)
+	return nil
+}
+
+// gormLogLevel SQL 语句只在 LOG_LEVEL=debug 时记录，其余级别只记录慢查询与错误
+func gormLogLevel() logger.LogLevel {
+	switch config.AppConfig.LogLevel {
+	case "debug":
+		return logger.Info
+	case "error":
+		return logger.Error
+	}
+	return logger.Warn
+}
 
-	err = createDefaultAdmin()
+// Ping 检查数据库连接是否可用
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not connected")
+	}
+	sqlDB, err := DB.DB()
 	if err != nil {
-		log.Printf("Warning: failed to create default admin: %v", err)
+		return err
 	}
+	return sqlDB.PingContext(ctx)
+}
 
-	log.Println("Database connected successfully")
-	return nil
+// Close 关闭连接池，应在所有使用数据库的组件停止后调用
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
 
 func createDefaultAdmin() error {
@@ -114,3 +163,16 @@ func createDefaultAdmin() error {
 
 	return nil
 }
+
+// DefaultAdminHasBuiltinPassword 默认管理员账号是否仍能用内置密码登录，账号不存在时返回 false
+func DefaultAdminHasBuiltinPassword(ctx context.Context) (bool, error) {
+	user, err := DAO.User.GetByUsername(ctx, config.AppConfig.DefaultAdminUser)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	ok, _, err := passhash.Verify(config.DefaultAdminPassword, user.PasswordHash())
+	return ok && err == nil, nil
+}
@@ -0,0 +1,43 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+func TestDefaultAdminHasBuiltinPassword(t *testing.T) {
+	config.AppConfig = &config.Config{DefaultAdminUser: "admin"}
+	dbtest.Open(t)
+	ctx := context.Background()
+
+	if stillDefault, err := database.DefaultAdminHasBuiltinPassword(ctx); err != nil || stillDefault {
+		t.Fatalf("without admin: %v, %v, want false", stillDefault, err)
+	}
+
+	// 预先计算的 {SHA} 哈希，分别对应 admin123 与其他密码
+	admin := &models.User{Username: "admin", Email: "admin@example.com", IsAdmin: true}
+	if err := admin.SetPasswordHash("{SHA}+GW1NiOxIf007lQmx5Llwzr4wic="); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DAO.User.Create(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+	if stillDefault, err := database.DefaultAdminHasBuiltinPassword(ctx); err != nil || !stillDefault {
+		t.Fatalf("with admin123: %v, %v, want true", stillDefault, err)
+	}
+
+	if err := admin.SetPasswordHash("{SHA}5hyTx1i6XJciA6MsVnIztkGdWi8="); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DAO.User.Update(ctx, admin); err != nil {
+		t.Fatal(err)
+	}
+	if stillDefault, err := database.DefaultAdminHasBuiltinPassword(ctx); err != nil || stillDefault {
+		t.Fatalf("after password change: %v, %v, want false", stillDefault, err)
+	}
+}
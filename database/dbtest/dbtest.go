@@ -0,0 +1,38 @@
+// Package dbtest 为测试提供临时的 SQLite 数据库，表结构由 migrations 中的迁移脚本建立，与生产环境一致
+package dbtest
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+)
+
+// Open 在 t.TempDir() 中新建 SQLite 库并迁移到最新版本，设置 database.DB 与 database.DAO，测试结束时关闭连接。
+// config.AppConfig 为空时以零值配置代替，已有配置只覆盖数据库相关字段
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	cfg := config.Config{}
+	if config.AppConfig != nil {
+		cfg = *config.AppConfig
+	}
+	cfg.DBDriver = database.DriverSQLite
+	cfg.DBDSN = filepath.Join(t.TempDir(), "test.db")
+	cfg.DBPoolMaxOpen, cfg.DBPoolMaxIdle = 4, 4
+	cfg.LogLevel = "error"
+	config.AppConfig = &cfg
+
+	if err := database.Open(); err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.MigrateUp(context.Background(), 0); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return database.DB
+}
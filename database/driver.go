@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlite 未指定参数时启用 WAL 与忙等待，并发写入时等待锁而不是立即报错
+const sqliteDefaultParams = "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
+// dialector 按 DB_DRIVER 选择驱动，DB_DSN 为空时由 DB_HOST 等配置拼接
+func dialector() (gorm.Dialector, error) {
+	cfg := config.AppConfig
+	dsn := cfg.DBDSN
+
+	switch cfg.DBDriver {
+	case DriverMySQL:
+		if dsn == "" {
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+				cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
+		}
+		return mysql.Open(dsn), nil
+
+	case DriverPostgres:
+		if dsn == "" {
+			u := url.URL{
+				Scheme:   "postgres",
+				User:     url.UserPassword(cfg.DBUser, cfg.DBPassword),
+				Host:     fmt.Sprintf("%s:%d", cfg.DBHost, cfg.DBPort),
+				Path:     "/" + cfg.DBName,
+				RawQuery: "sslmode=prefer",
+			}
+			dsn = u.String()
+		}
+		return postgres.Open(dsn), nil
+
+	case DriverSQLite:
+		if dsn == "" {
+			dsn = cfg.DBName + ".db"
+		}
+		if !strings.Contains(dsn, "?") {
+			dsn += "?" + sqliteDefaultParams
+		}
+		return sqlite.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("unsupported DB_DRIVER %q, expected mysql, postgres or sqlite", cfg.DBDriver)
+}
+
+// sqliteUTC SQLite 以文本保存时间并按字符串比较，时区偏移不同的值无法正确比较范围；
+// 因此所有时间参数在执行前统一转换为 UTC
+type sqliteUTC struct{}
+
+func (sqliteUTC) Name() string {
+	return "sqlite_utc"
+}
+
+// Initialize 只在执行 SQL 的回调前后替换连接，不影响默认事务的开始与提交
+func (sqliteUTC) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		before, after func(string, func(*gorm.DB)) error
+	}{
+		{cb.Create().After("gorm:begin_transaction").Before("gorm:create").Register, cb.Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").Register},
+		{cb.Update().After("gorm:begin_transaction").Before("gorm:update").Register, cb.Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").Register},
+		{cb.Delete().After("gorm:begin_transaction").Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Before("gorm:commit_or_rollback_transaction").Register},
+		{cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, hook := range hooks {
+		if err := hook.before("sqlite_utc:wrap", wrapUTC); err != nil {
+			return err
+		}
+		if err := hook.after("sqlite_utc:unwrap", unwrapUTC); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func wrapUTC(db *gorm.DB) {
+	if _, ok := db.Statement.ConnPool.(utcConnPool); !ok {
+		db.Statement.ConnPool = utcConnPool{db.Statement.ConnPool}
+	}
+}
+
+func unwrapUTC(db *gorm.DB) {
+	if pool, ok := db.Statement.ConnPool.(utcConnPool); ok {
+		db.Statement.ConnPool = pool.ConnPool
+	}
+}
+
+type utcConnPool struct {
+	gorm.ConnPool
+}
+
+func toUTC(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			args[i] = v.UTC()
+		case *time.Time:
+			if v != nil {
+				utc := v.UTC()
+				args[i] = &utc
+			}
+		}
+	}
+	return args
+}
+
+func (p utcConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.ConnPool.ExecContext(ctx, query, toUTC(args)...)
+}
+
+func (p utcConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.ConnPool.QueryContext(ctx, query, toUTC(args)...)
+}
+
+func (p utcConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.ConnPool.QueryRowContext(ctx, query, toUTC(args)...)
+}
@@ -0,0 +1,40 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+)
+
+// poolStats 连接尚未建立时返回 nil，对应指标不输出
+func poolStats(read func(sql.DBStats) float64) func() []metrics.Sample {
+	return func() []metrics.Sample {
+		if DB == nil {
+			return nil
+		}
+		sqlDB, err := DB.DB()
+		if err != nil {
+			return nil
+		}
+		return metrics.Value(read(sqlDB.Stats()))
+	}
+}
+
+func init() {
+	metrics.NewGaugeFunc("radius_manager_db_max_open_connections", "Maximum number of open database connections.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	metrics.NewGaugeFunc("radius_manager_db_open_connections", "Established database connections, in use and idle.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	metrics.NewGaugeFunc("radius_manager_db_in_use_connections", "Database connections currently in use.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	metrics.NewGaugeFunc("radius_manager_db_idle_connections", "Idle database connections.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	metrics.NewCounterFunc("radius_manager_db_wait_count_total", "Times a query waited for a free database connection.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metrics.NewCounterFunc("radius_manager_db_wait_duration_seconds_total", "Total time spent waiting for a free database connection.",
+		poolStats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	metrics.NewCounterFunc("radius_manager_db_max_idle_closed_total", "Connections closed because the idle pool was full.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	metrics.NewCounterFunc("radius_manager_db_max_lifetime_closed_total", "Connections closed because they reached their maximum lifetime.",
+		poolStats(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
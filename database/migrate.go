@@ -0,0 +1,406 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/migrations"
+	baselinemodels "github.com/Gaojianli/raduis_mgnt/migrations/baseline"
+)
+
+const (
+	// 同一数据库的所有实例共用的迁移锁：MySQL 为 GET_LOCK 的锁名，PostgreSQL 为 advisory lock 的键
+	migrationLockName = "radius_mgnt_schema_migrations"
+	migrationLockKey  = 0x72616469757300
+	// 其他实例正在迁移时最多等待的时间
+	migrationLockTimeout = 10 * time.Minute
+)
+
+// schemaMigration 每个已执行的迁移一行。Dirty 表示迁移中途失败，MySQL 的 DDL 无法回滚，须人工修复后用 migrate force 标记
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	Dirty     bool   `gorm:"not null;default:false"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// SchemaState 数据库当前的迁移版本与本程序包含的最新版本
+type SchemaState struct {
+	Version int64 `json:"version"`
+	Latest  int64 `json:"latest"`
+	Dirty   bool  `json:"dirty"`
+}
+
+func (s SchemaState) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// check 数据库处于中断状态或版本比本程序新时拒绝继续
+func (s SchemaState) check() error {
+	if s.Dirty {
+		return fmt.Errorf("migration %d failed part way and is marked dirty; repair the schema by hand, then run `admin_tool migrate force <version>`", s.Version)
+	}
+	if s.Version > s.Latest {
+		return fmt.Errorf("database schema version %d is newer than this build supports (%d); run a newer release, or roll back with the newer release's `admin_tool migrate down`", s.Version, s.Latest)
+	}
+	return nil
+}
+
+// MigrationInfo 单个迁移的状态。AppliedAt 为空表示尚未执行；Known 为 false 表示数据库中有本程序不认识的版本
+type MigrationInfo struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Dirty     bool
+	Known     bool
+}
+
+func loadMigrations() ([]migrations.Migration, error) {
+	return migrations.Load(DB.Dialector.Name())
+}
+
+// applied 按版本升序读取已执行的迁移，schema_migrations 不存在时返回空
+func applied(db *gorm.DB) ([]schemaMigration, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return nil, nil
+	}
+	var rows []schemaMigration
+	err := db.Order("version").Find(&rows).Error
+	return rows, err
+}
+
+func stateOf(list []migrations.Migration, rows []schemaMigration) SchemaState {
+	state := SchemaState{Latest: int64(len(list))}
+	for _, row := range rows {
+		if row.Version > state.Version {
+			state.Version = row.Version
+		}
+		state.Dirty = state.Dirty || row.Dirty
+	}
+	return state
+}
+
+// SchemaVersion 读取数据库当前的迁移版本，不加锁
+func SchemaVersion(ctx context.Context) (SchemaState, error) {
+	list, err := loadMigrations()
+	if err != nil {
+		return SchemaState{}, err
+	}
+	rows, err := applied(DB.WithContext(ctx))
+	if err != nil {
+		return SchemaState{}, err
+	}
+	return stateOf(list, rows), nil
+}
+
+// MigrationStatus 返回数据库版本以及每个迁移的执行情况
+func MigrationStatus(ctx context.Context) (SchemaState, []MigrationInfo, error) {
+	list, err := loadMigrations()
+	if err != nil {
+		return SchemaState{}, nil, err
+	}
+	rows, err := applied(DB.WithContext(ctx))
+	if err != nil {
+		return SchemaState{}, nil, err
+	}
+
+	byVersion := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		byVersion[row.Version] = row
+	}
+	infos := make([]MigrationInfo, 0, len(list))
+	for _, m := range list {
+		info := MigrationInfo{Version: m.Version, Name: m.Name, Known: true}
+		if row, ok := byVersion[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			info.AppliedAt = &appliedAt
+			info.Dirty = row.Dirty
+		}
+		infos = append(infos, info)
+	}
+	for _, row := range rows {
+		if row.Version > int64(len(list)) {
+			appliedAt := row.AppliedAt
+			infos = append(infos, MigrationInfo{Version: row.Version, Name: row.Name, AppliedAt: &appliedAt, Dirty: row.Dirty})
+		}
+	}
+	return stateOf(list, rows), infos, nil
+}
+
+// migrateOnStartup DB_AUTO_MIGRATE 开启时执行全部待执行的迁移，否则只检查版本，有待执行的迁移时拒绝启动
+func migrateOnStartup(ctx context.Context) error {
+	if config.AppConfig.DBAutoMigrate {
+		return MigrateUp(ctx, 0)
+	}
+
+	state, err := SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if err := state.check(); err != nil {
+		return err
+	}
+	if state.Pending() {
+		return fmt.Errorf("database schema is at version %d but this build needs %d; run `admin_tool migrate up` or set DB_AUTO_MIGRATE=true", state.Version, state.Latest)
+	}
+	return nil
+}
+
+// MigrateUp 依次执行版本不超过 target 的待执行迁移，target 为 0 时迁移到最新版本
+func MigrateUp(ctx context.Context, target int64) error {
+	list, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if target == 0 {
+		target = int64(len(list))
+	}
+	if target < 0 || target > int64(len(list)) {
+		return fmt.Errorf("target version %d is out of range, latest is %d", target, len(list))
+	}
+
+	return withMigrationLock(ctx, func(conn *gorm.DB) error {
+		state, err := lockedState(conn, list)
+		if err != nil {
+			return err
+		}
+		if state.Version == 0 && target > 0 {
+			adopted, err := adoptLegacySchema(conn, list[0])
+			if err != nil {
+				return err
+			}
+			if adopted {
+				state.Version = 1
+			}
+		}
+
+		for _, m := range list[min(state.Version, target):target] {
+			start := time.Now()
+			if err := applyMigration(conn, m, true); err != nil {
+				return err
+			}
+			log.Printf("Applied migration %06d_%s in %s", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+		}
+		return nil
+	})
+}
+
+// MigrateDown 从当前版本起依次回滚 steps 个迁移
+func MigrateDown(ctx context.Context, steps int) error {
+	list, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return withMigrationLock(ctx, func(conn *gorm.DB) error {
+		state, err := lockedState(conn, list)
+		if err != nil {
+			return err
+		}
+
+		for i := 0; i < steps && state.Version > 0; i++ {
+			m := list[state.Version-1]
+			start := time.Now()
+			if err := applyMigration(conn, m, false); err != nil {
+				return err
+			}
+			log.Printf("Rolled back migration %06d_%s in %s", m.Version, m.Name, time.Since(start).Round(time.Millisecond))
+			state.Version--
+		}
+		return nil
+	})
+}
+
+// ForceVersion 人工修复中断的迁移后，把数据库记为 version 且不执行任何脚本
+func ForceVersion(ctx context.Context, version int64) error {
+	list, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if version < 0 || version > int64(len(list)) {
+		return fmt.Errorf("version %d is out of range, latest is %d", version, len(list))
+	}
+
+	return withMigrationLock(ctx, func(conn *gorm.DB) error {
+		if err := createMigrationTable(conn); err != nil {
+			return err
+		}
+		if err := conn.Exec("DELETE FROM schema_migrations WHERE version > ?", version).Error; err != nil {
+			return err
+		}
+		if err := conn.Exec("UPDATE schema_migrations SET dirty = ?", false).Error; err != nil {
+			return err
+		}
+		for _, m := range list[:version] {
+			var count int64
+			if err := conn.Raw("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.Version).Scan(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				if err := recordMigration(conn, m, false); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// lockedState 持锁后创建 schema_migrations 并检查当前版本
+func lockedState(conn *gorm.DB, list []migrations.Migration) (SchemaState, error) {
+	if err := createMigrationTable(conn); err != nil {
+		return SchemaState{}, err
+	}
+	rows, err := applied(conn)
+	if err != nil {
+		return SchemaState{}, err
+	}
+	state := stateOf(list, rows)
+	return state, state.check()
+}
+
+func createMigrationTable(conn *gorm.DB) error {
+	if conn.Migrator().HasTable(&schemaMigration{}) {
+		return nil
+	}
+	if err := conn.Migrator().CreateTable(&schemaMigration{}); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// withMigrationLock 在一个单独的连接上持有迁移锁执行 fn。多个实例同时启动时只有一个执行迁移，
+// 其余等待锁释放后看到已是最新版本。SQLite 只支持单实例，改由每个迁移的 BEGIN IMMEDIATE 串行化
+func withMigrationLock(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return DB.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		// Connection 传入的实例会累积链式调用的条件，换成新会话后每条语句互不影响，仍使用同一连接
+		conn = conn.Session(&gorm.Session{})
+		switch conn.Dialector.Name() {
+		case DriverMySQL:
+			var locked sql.NullInt64
+			if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, int(migrationLockTimeout.Seconds())).Scan(&locked).Error; err != nil {
+				return fmt.Errorf("acquire migration lock: %w", err)
+			}
+			if locked.Int64 != 1 {
+				return fmt.Errorf("timed out after %s waiting for another instance to finish migrating", migrationLockTimeout)
+			}
+			defer func() {
+				var released sql.NullInt64
+				conn.WithContext(context.Background()).Raw("SELECT RELEASE_LOCK(?)", migrationLockName).Scan(&released)
+			}()
+
+		case DriverPostgres:
+			deadline := time.Now().Add(migrationLockTimeout)
+			for {
+				var locked bool
+				if err := conn.Raw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(&locked).Error; err != nil {
+					return fmt.Errorf("acquire migration lock: %w", err)
+				}
+				if locked {
+					break
+				}
+				if time.Now().After(deadline) {
+					return fmt.Errorf("timed out after %s waiting for another instance to finish migrating", migrationLockTimeout)
+				}
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Second):
+				}
+			}
+			defer func() {
+				var released bool
+				conn.WithContext(context.Background()).Raw("SELECT pg_advisory_unlock(?)", migrationLockKey).Scan(&released)
+			}()
+		}
+		return fn(conn)
+	})
+}
+
+// adoptLegacySchema 旧版本在启动时用 AutoMigrate 建表，这类数据库先按冻结的基线模型补齐，再记为已执行基线迁移。
+// 不能使用 models 中的当前定义，否则会提前建出后续迁移才引入的结构
+func adoptLegacySchema(conn *gorm.DB, baseline migrations.Migration) (bool, error) {
+	if !conn.Migrator().HasTable("users") {
+		return false, nil
+	}
+	log.Printf("Found tables created before versioned migrations, upgrading them to %06d_%s", baseline.Version, baseline.Name)
+	if err := conn.AutoMigrate(baselinemodels.Models()...); err != nil {
+		return false, fmt.Errorf("upgrade existing tables to the baseline: %w", err)
+	}
+	return true, recordMigration(conn, baseline, false)
+}
+
+// applyMigration 执行一个迁移并更新 schema_migrations。PostgreSQL 与 SQLite 的 DDL 可以回滚，
+// 整个迁移在一个事务中完成；MySQL 的 DDL 会隐式提交，先记为 dirty，全部语句成功后再清除
+func applyMigration(conn *gorm.DB, m migrations.Migration, up bool) error {
+	transactional := conn.Dialector.Name() != DriverMySQL
+	if transactional {
+		begin := "BEGIN"
+		if conn.Dialector.Name() == DriverSQLite {
+			begin = "BEGIN IMMEDIATE"
+		}
+		if err := conn.Exec(begin).Error; err != nil {
+			return err
+		}
+	}
+
+	err := runMigration(conn, m, up)
+	if transactional {
+		if err != nil {
+			conn.Exec("ROLLBACK")
+			return err
+		}
+		return conn.Exec("COMMIT").Error
+	}
+	return err
+}
+
+func runMigration(conn *gorm.DB, m migrations.Migration, up bool) error {
+	// SQLite 未加全局锁，其他进程可能已经执行过
+	var count int64
+	if err := conn.Raw("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.Version).Scan(&count).Error; err != nil {
+		return err
+	}
+	if (count > 0) == up {
+		return nil
+	}
+
+	direction, script := "up", m.Up
+	if up {
+		if err := recordMigration(conn, m, true); err != nil {
+			return err
+		}
+	} else {
+		direction, script = "down", m.Down
+		if err := conn.Exec("UPDATE schema_migrations SET dirty = ? WHERE version = ?", true, m.Version).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, statement := range migrations.Statements(script) {
+		if err := conn.Exec(statement).Error; err != nil {
+			return fmt.Errorf("migration %06d_%s %s: %w", m.Version, m.Name, direction, err)
+		}
+	}
+
+	if up {
+		return conn.Exec("UPDATE schema_migrations SET dirty = ? WHERE version = ?", false, m.Version).Error
+	}
+	return conn.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version).Error
+}
+
+// recordMigration 不使用 Create，避免在手动开启的事务中再嵌套默认事务
+func recordMigration(conn *gorm.DB, m migrations.Migration, dirty bool) error {
+	return conn.Exec("INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, ?, ?)",
+		m.Version, m.Name, dirty, time.Now()).Error
+}
@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+)
+
+// 每个迁移的 down 脚本须能完整撤销 up，且撤销后可重新执行
+func TestMigrationsRoundTrip(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+
+	state, err := database.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != state.Latest || state.Dirty {
+		t.Fatalf("after MigrateUp: %+v, want version %d", state, state.Latest)
+	}
+
+	if err := database.MigrateDown(ctx, int(state.Latest)); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if state, _ = database.SchemaVersion(ctx); state.Version != 0 {
+		t.Fatalf("after MigrateDown: version %d, want 0", state.Version)
+	}
+	for _, table := range []string{"users", "auth_logs", "settings"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s still exists after rolling back every migration", table)
+		}
+	}
+
+	if err := database.MigrateUp(ctx, 0); err != nil {
+		t.Fatalf("MigrateUp after rollback: %v", err)
+	}
+	if state, _ = database.SchemaVersion(ctx); state.Version != state.Latest {
+		t.Fatalf("after second MigrateUp: version %d, want %d", state.Version, state.Latest)
+	}
+}
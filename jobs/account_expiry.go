@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// NewAccountExpiryJob 过期的账号包括访客与导入或管理员设置了 expires_at 的普通账号
+func NewAccountExpiryJob() Job {
+	return Job{
+		Name:     "account-expiry",
+		Interval: config.AppConfig.GuestExpiryInterval,
+		Run:      expireAccounts,
+	}
+}
+
+// expireAccounts 停用已过 expires_at 的账号，并使长时间未处理的访客申请失效
+func expireAccounts(ctx context.Context) error {
+	now := time.Now()
+
+	users, err := database.DAO.User.ListExpired(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := database.DAO.User.UpdateStatus(ctx, user.ID, models.UserStatusExpired); err != nil {
+			return err
+		}
+
+		detail := fmt.Sprintf("account expired at %s", user.ExpiresAt.Format(time.RFC3339))
+		if user.SponsorID != nil {
+			detail += fmt.Sprintf(", sponsor_id=%d", *user.SponsorID)
+		}
+		database.DAO.AuditLog.Create(ctx, &models.AuditLog{
+			ActorName:  "system",
+			Action:     "user.expired",
+			TargetType: "user",
+			TargetID:   user.ID,
+			Detail:     detail,
+		})
+	}
+
+	expired, err := database.DAO.GuestRequest.ExpirePending(ctx, now.Add(-config.AppConfig.GuestRequestTTL))
+	if err != nil {
+		return err
+	}
+
+	if len(users) > 0 || expired > 0 {
+		log.Printf("Account expiry: %d accounts expired, %d pending requests expired", len(users), expired)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/retention"
+)
+
+func NewAuthLogRetentionJob() Job {
+	return Job{
+		Name:     "auth-log-retention",
+		Interval: config.AppConfig.AuthLogRetentionInterval,
+		Run:      purgeAuthLogs,
+	}
+}
+
+// purgeAuthLogs 与 admin_tool purge-auth-logs 同时执行时跳过本轮
+func purgeAuthLogs(ctx context.Context) error {
+	_, err := retention.Run(ctx, retention.ConfiguredPolicy(), retention.TriggerSchedule)
+	if errors.Is(err, retention.ErrInProgress) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	"github.com/Gaojianli/raduis_mgnt/authstats"
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+func NewAuthStatsRollupJob() Job {
+	return Job{
+		Name:     "auth-stats-rollup",
+		Interval: config.AppConfig.AuthStatsRollupInterval,
+		Run:      authstats.Aggregate,
+	}
+}
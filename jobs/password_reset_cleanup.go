@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+)
+
+func NewPasswordResetCleanupJob() Job {
+	return Job{
+		Name:     "password-reset-cleanup",
+		Interval: time.Hour,
+		Run:      cleanupPasswordResetTokens,
+	}
+}
+
+// cleanupPasswordResetTokens 删除过期一天以上的重置令牌，保留近期记录便于排查
+func cleanupPasswordResetTokens(ctx context.Context) error {
+	deleted, err := database.DAO.PasswordReset.DeleteExpired(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("Password reset cleanup: %d expired tokens deleted", deleted)
+	}
+	return nil
+}
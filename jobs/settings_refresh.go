@@ -0,0 +1,15 @@
+package jobs
+
+import (
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/settings"
+)
+
+// NewSettingsRefreshJob 使其他实例通过管理接口所做的修改在本实例生效
+func NewSettingsRefreshJob() Job {
+	return Job{
+		Name:     "settings-refresh",
+		Interval: config.AppConfig.SettingsRefreshInterval,
+		Run:      settings.Refresh,
+	}
+}
@@ -0,0 +1,110 @@
+// Package lifecycle 协调进程退出：收到 SIGINT / SIGTERM 后先让就绪检查失败，再按启动的相反顺序停止各组件。
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var shuttingDown atomic.Bool
+
+// ShuttingDown 是否已开始退出，此后 /readyz 返回 503
+func ShuttingDown() bool {
+	return shuttingDown.Load()
+}
+
+type component struct {
+	name string
+	stop func(context.Context) error
+}
+
+// Manager 按注册的相反顺序停止组件：先启动的组件（如数据库）最后关闭
+type Manager struct {
+	mu         sync.Mutex
+	components []component
+	errs       chan error
+}
+
+func New() *Manager {
+	return &Manager{errs: make(chan error, 1)}
+}
+
+// OnStop 登记组件的停止函数，应在组件启动成功后调用
+func (m *Manager) OnStop(name string, stop func(context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component{name: name, stop: stop})
+}
+
+// Go 在后台运行长期服务（如 HTTP 监听），返回错误时触发退出
+func (m *Manager) Go(name string, run func() error) {
+	go func() {
+		if err := run(); err != nil {
+			select {
+			case m.errs <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// Wait 阻塞直到收到退出信号或后台服务出错，然后执行 Shutdown。
+// 退出过程中再次收到信号时立即结束进程
+func (m *Manager) Wait(delay, timeout time.Duration) error {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	var cause error
+	select {
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down", sig)
+	case cause = <-m.errs:
+		log.Printf("Shutting down after error: %v", cause)
+	}
+
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s again, exiting immediately", sig)
+		os.Exit(1)
+	}()
+
+	return errors.Join(cause, m.Shutdown(delay, timeout))
+}
+
+// Shutdown 标记为正在退出，等待 delay 让负载均衡摘除本实例，
+// 再在 timeout 内依次停止各组件；某个组件失败不影响后续组件
+func (m *Manager) Shutdown(delay, timeout time.Duration) error {
+	shuttingDown.Store(true)
+	if delay > 0 {
+		log.Printf("Readiness is failing, waiting %s before draining", delay)
+		time.Sleep(delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m.mu.Lock()
+	components := append([]component(nil), m.components...)
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		start := time.Now()
+		if err := c.stop(ctx); err != nil {
+			log.Printf("Failed to stop %s: %v", c.name, err)
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.name, err))
+			continue
+		}
+		log.Printf("Stopped %s in %s", c.name, time.Since(start).Round(time.Millisecond))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Sender 邮件发送接口，便于替换为测试用的实现
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var Default Sender = &LogSender{}
+
+// Init 根据配置选择发送方式，未配置 SMTP 时仅写日志
+func Init() {
+	if config.AppConfig.SMTPHost == "" {
+		Default = &LogSender{}
+		return
+	}
+
+	Default = &SMTPSender{
+		Host:     config.AppConfig.SMTPHost,
+		Port:     config.AppConfig.SMTPPort,
+		Username: config.AppConfig.SMTPUsername,
+		Password: config.AppConfig.SMTPPassword,
+		From:     config.AppConfig.SMTPFrom,
+	}
+}
+
+func Send(ctx context.Context, msg Message) error {
+	return Default.Send(ctx, msg)
+}
+
+// SendAsync 经后台队列发送邮件，失败只记录日志
+func SendAsync(msg Message) {
+	Submit(func(ctx context.Context) {
+		if err := Send(ctx, msg); err != nil {
+			log.Printf("Failed to send mail to %v: %v", msg.To, err)
+		}
+	})
+}
+
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("mail has no recipients")
+	}
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(addr, auth, s.From, msg.To, buildMessage(s.From, msg))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// LogSender 未配置 SMTP 时使用，只记录收件人与主题；正文可能含重置令牌等凭据，不写入日志
+type LogSender struct{}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail not sent (SMTP not configured) to=%v subject=%q body=%d bytes", msg.To, msg.Subject, len(msg.Body))
+	return nil
+}
@@ -0,0 +1,73 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+func TestLogSenderOmitsBody(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	msg := Message{To: []string{"alice@example.com"}, Subject: "Password reset request", Body: "Your reset token is:\n\nsecret-token"}
+	if err := (&LogSender{}).Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "secret-token") {
+		t.Errorf("log contains the mail body: %q", out)
+	}
+	if !strings.Contains(out, "alice@example.com") || !strings.Contains(out, "Password reset request") {
+		t.Errorf("log lacks recipient or subject: %q", out)
+	}
+}
+
+func TestSubmitDropsWhenQueueIsFull(t *testing.T) {
+	config.AppConfig = &config.Config{MailQueueSize: 1, MailWorkers: 1}
+	Start()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	Submit(func(context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	ran := make(chan int, 2)
+	before := dropped.Load()
+	if !Submit(func(context.Context) { ran <- 1 }) {
+		t.Fatal("job rejected although the queue had room")
+	}
+	if Submit(func(context.Context) { ran <- 2 }) {
+		t.Fatal("job accepted although the queue was full")
+	}
+	if dropped.Load() != before+1 {
+		t.Errorf("dropped = %d, want %d", dropped.Load(), before+1)
+	}
+
+	close(release)
+	if err := Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(ran)
+	var got []int
+	for n := range ran {
+		got = append(got, n)
+	}
+	if len(got) != 1 || got[0] != 1 {
+		t.Errorf("ran jobs %v, want [1]", got)
+	}
+
+	// 停止后直接执行，不丢弃
+	inline := false
+	Submit(func(context.Context) { inline = true })
+	if !inline {
+		t.Error("job not run after Stop")
+	}
+}
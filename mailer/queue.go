@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+)
+
+// 单个后台邮件任务（含查询与发送）的超时
+const jobTimeout = 30 * time.Second
+
+var (
+	// mu 保护队列的关闭，Submit 持读锁入队，Stop 持写锁关闭
+	mu      sync.RWMutex
+	queue   chan func(context.Context)
+	closed  bool
+	workers sync.WaitGroup
+	dropped atomic.Int64
+)
+
+func init() {
+	metrics.NewGaugeFunc("radius_manager_mail_queue_depth", "Mail jobs waiting to be sent.", func() []metrics.Sample {
+		return metrics.Value(float64(len(queue)))
+	})
+	metrics.NewCounterFunc("radius_manager_mail_dropped_total", "Mail jobs dropped because the queue was full.", func() []metrics.Sample {
+		return metrics.Value(float64(dropped.Load()))
+	})
+}
+
+// Start 创建有界队列并启动 MAIL_WORKERS 个发送协程，容量由 MAIL_QUEUE_SIZE 决定
+func Start() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	queue = make(chan func(context.Context), config.AppConfig.MailQueueSize)
+	closed = false
+	for i := 0; i < config.AppConfig.MailWorkers; i++ {
+		workers.Add(1)
+		go func(queue <-chan func(context.Context)) {
+			defer workers.Done()
+			for job := range queue {
+				run(job)
+			}
+		}(queue)
+	}
+}
+
+// Stop 关闭队列并等待已入队的邮件发送完毕
+func Stop(ctx context.Context) error {
+	mu.Lock()
+	if queue == nil || closed {
+		mu.Unlock()
+		return nil
+	}
+	closed = true
+	close(queue)
+	mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d mail jobs not sent: %w", len(queue), ctx.Err())
+	}
+}
+
+// Submit 不阻塞地提交后台邮件任务，队列已满时丢弃并返回 false；队列未启动（如命令行工具）或已关闭时直接执行
+func Submit(job func(ctx context.Context)) bool {
+	mu.RLock()
+	if queue != nil && !closed {
+		defer mu.RUnlock()
+		select {
+		case queue <- job:
+			return true
+		default:
+			if dropped.Add(1)%100 == 1 {
+				log.Printf("Mail queue is full, %d jobs dropped so far", dropped.Load())
+			}
+			return false
+		}
+	}
+	mu.RUnlock()
+
+	run(job)
+	return true
+}
+
+func run(job func(context.Context)) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	defer cancel()
+	job(ctx)
+}
@@ -1,33 +1,157 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
 
 	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 
+	"github.com/Gaojianli/raduis_mgnt/authlog"
+	"github.com/Gaojianli/raduis_mgnt/authn"
 	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/jobs"
+	"github.com/Gaojianli/raduis_mgnt/lifecycle"
+	"github.com/Gaojianli/raduis_mgnt/mailer"
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+	"github.com/Gaojianli/raduis_mgnt/mfa"
 	"github.com/Gaojianli/raduis_mgnt/middleware"
+	"github.com/Gaojianli/raduis_mgnt/passhash"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/retention"
 	"github.com/Gaojianli/raduis_mgnt/routes"
+	"github.com/Gaojianli/raduis_mgnt/settings"
+	"github.com/Gaojianli/raduis_mgnt/sso"
+	"github.com/Gaojianli/raduis_mgnt/tracing"
 )
 
 func main() {
 	if err := config.LoadConfig(); err != nil {
 		log.Fatal("Failed to load config:", err)
 	}
+	if err := config.AppConfig.RequireSafeSecrets(); err != nil {
+		log.Fatal(err)
+	}
+	hlog.SetLevel(hertzLogLevel(config.AppConfig.LogLevel))
+
+	// 组件按启动顺序登记，退出时按相反顺序停止
+	lc := lifecycle.New()
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatal("Failed to initialize tracing:", err)
+	}
+	lc.OnStop("tracing", shutdownTracing)
+
+	if err := passhash.Init(); err != nil {
+		log.Fatal("Failed to initialize password hashing:", err)
+	}
+
+	if err := passpolicy.Init(); err != nil {
+		log.Fatal("Failed to initialize password policy:", err)
+	}
 
 	if err := database.Connect(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	lc.OnStop("database", func(context.Context) error { return database.Close() })
+
+	stillDefault, err := database.DefaultAdminHasBuiltinPassword(context.Background())
+	if err != nil {
+		log.Fatal("Failed to check the default admin password:", err)
+	}
+	if err := config.AppConfig.RequireChangedAdminPassword(stillDefault); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := settings.Init(); err != nil {
+		log.Fatal("Failed to load runtime settings:", err)
+	}
+
+	if err := authn.Init(); err != nil {
+		log.Fatal("Failed to initialize authentication backends:", err)
+	}
+
+	if err := sso.Init(); err != nil {
+		log.Fatal("Failed to initialize OIDC login:", err)
+	}
+
+	if err := mfa.InitWebAuthn(); err != nil {
+		log.Fatal("Failed to initialize WebAuthn:", err)
+	}
 
 	if err := middleware.InitJWT(); err != nil {
 		log.Fatal("Failed to initialize JWT middleware:", err)
 	}
 
-	h := server.Default(server.WithHostPorts(config.AppConfig.ServerPort))
+	mailer.Init()
+	mailer.Start()
+	lc.OnStop("mail queue", mailer.Stop)
+
+	if err := retention.Init(); err != nil {
+		log.Fatal("Failed to initialize auth log retention:", err)
+	}
+
+	authlog.Start()
+	lc.OnStop("auth log queue", authlog.Stop)
+
+	scheduler := jobs.NewScheduler()
+	scheduler.Add(jobs.NewAccountExpiryJob())
+	scheduler.Add(jobs.NewPasswordResetCleanupJob())
+	scheduler.Add(jobs.NewAuthStatsRollupJob())
+	scheduler.Add(jobs.NewSettingsRefreshJob())
+	if retention.ConfiguredPolicy().Enabled() {
+		scheduler.Add(jobs.NewAuthLogRetentionJob())
+	}
+	scheduler.Start()
+	lc.OnStop("background jobs", scheduler.Shutdown)
+
+	if config.AppConfig.MetricsAddr != "" {
+		metricsServer := metrics.NewServer()
+		log.Printf("Metrics server starting on %s", metricsServer.Addr)
+		lc.Go("metrics server", func() error {
+			if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+		lc.OnStop("metrics server", metricsServer.Shutdown)
+	}
+
+	h := server.Default(
+		server.WithHostPorts(config.AppConfig.ServerPort),
+		server.WithMaxRequestBodySize(config.AppConfig.MaxRequestBodySize),
+		server.WithExitWaitTime(config.AppConfig.ShutdownTimeout),
+		server.WithReadTimeout(config.AppConfig.ServerReadTimeout),
+		server.WithWriteTimeout(config.AppConfig.ServerWriteTimeout),
+		server.WithIdleTimeout(config.AppConfig.ServerIdleTimeout),
+	)
 
+	h.SetClientIPFunc(middleware.ClientIP(config.AppConfig.TrustedProxies))
 	routes.SetupRoutes(h)
 
+	// 不使用 h.Spin()：其关闭钩子先于连接排空执行，无法保证排空后再写完认证日志
 	log.Printf("Server starting on port %s", config.AppConfig.ServerPort)
-	h.Spin()
+	lc.Go("http server", h.Run)
+	lc.OnStop("http server", h.Shutdown)
+
+	if err := lc.Wait(config.AppConfig.ShutdownDelay, config.AppConfig.ShutdownTimeout); err != nil {
+		log.Fatal("Shutdown finished with errors: ", err)
+	}
+	log.Println("Shutdown complete")
+}
+
+func hertzLogLevel(level string) hlog.Level {
+	switch level {
+	case "debug":
+		return hlog.LevelDebug
+	case "warn":
+		return hlog.LevelWarn
+	case "error":
+		return hlog.LevelError
+	}
+	return hlog.LevelInfo
 }
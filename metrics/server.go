@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 由请求处理与认证流程直接更新的指标，队列、连接池等状态由所属包注册为 GaugeFunc
+var (
+	HTTPRequests = NewCounterVec("radius_manager_http_requests_total",
+		"HTTP requests by route pattern, method and status code.", "method", "route", "status")
+	HTTPDuration = NewHistogramVec("radius_manager_http_request_duration_seconds",
+		"HTTP request latency by route pattern and method.", DefaultBuckets, "method", "route")
+	RadiusAuths = NewCounterVec("radius_manager_radius_auth_total",
+		"RADIUS authentication outcomes; reason is empty on success.", "result", "reason")
+	StoreLookups = NewCounterVec("radius_manager_state_store_lookups_total",
+		"Lookups in in-memory state stores (MFA challenges, WebAuthn and OIDC login state) by result.", "store", "result")
+)
+
+var (
+	storesMu sync.Mutex
+	stores   = make(map[string]func() int)
+)
+
+func init() {
+	NewGaugeFunc("radius_manager_active_sessions", "Pending login and MFA flows held in memory, by store.", func() []Sample {
+		storesMu.Lock()
+		defer storesMu.Unlock()
+
+		samples := make([]Sample, 0, len(stores))
+		for _, name := range sortedKeys(stores) {
+			samples = append(samples, Sample{Labels: []string{name}, Value: float64(stores[name]())})
+		}
+		return samples
+	}, "store")
+}
+
+// RegisterStore 登记内存中的状态存储，size 返回当前保存的条目数（可能包括尚未清理的过期条目）
+func RegisterStore(name string, size func() int) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = size
+}
+
+// LookupResult StoreLookups 的 result 标签
+func LookupResult(hit bool) string {
+	if hit {
+		return "hit"
+	}
+	return "miss"
+}
+
+// Enabled 配置了 METRICS_ADDR 或 METRICS_TOKEN 才导出指标，避免未加保护地公开
+func Enabled() bool {
+	return config.AppConfig.MetricsAddr != "" || config.AppConfig.MetricsToken != ""
+}
+
+// Authorized 校验 Authorization 头；独立监听地址且未配置 METRICS_TOKEN 时不校验
+func Authorized(authorization string) bool {
+	expected := config.AppConfig.MetricsToken
+	if expected == "" {
+		return config.AppConfig.MetricsAddr != ""
+	}
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) == 1
+}
+
+// NewServer 在 METRICS_ADDR 上单独提供 /metrics，不经过业务端口
+func NewServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if !Authorized(r.Header.Get("Authorization")) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", ContentType)
+		WriteTo(w)
+	})
+	return &http.Server{
+		Addr:              config.AppConfig.MetricsAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
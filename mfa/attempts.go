@@ -0,0 +1,54 @@
+package mfa
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	maxLoginFailures   = 5
+	loginFailureWindow = 15 * time.Minute
+)
+
+type failureRecord struct {
+	count   int
+	resetAt time.Time
+}
+
+// LoginAttempts 限制 Web 登录第二步的失败次数，防止穷举 6 位验证码
+type LoginAttempts struct {
+	mu       sync.Mutex
+	failures map[uint]*failureRecord
+}
+
+var Attempts = &LoginAttempts{failures: make(map[uint]*failureRecord)}
+
+func (a *LoginAttempts) Allowed(userID uint) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record, ok := a.failures[userID]
+	if !ok || time.Now().After(record.resetAt) {
+		return true
+	}
+	return record.count < maxLoginFailures
+}
+
+func (a *LoginAttempts) Fail(userID uint) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	record, ok := a.failures[userID]
+	if !ok || now.After(record.resetAt) {
+		record = &failureRecord{resetAt: now.Add(loginFailureWindow)}
+		a.failures[userID] = record
+	}
+	record.count++
+}
+
+func (a *LoginAttempts) Reset(userID uint) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.failures, userID)
+}
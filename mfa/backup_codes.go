@@ -0,0 +1,42 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+const (
+	backupCodeCount = 10
+	backupCodeChars = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// GenerateBackupCodes 生成一次性备用码，格式为 xxxxx-xxxxx
+func GenerateBackupCodes() ([]string, error) {
+	codes := make([]string, backupCodeCount)
+	max := big.NewInt(int64(len(backupCodeChars)))
+	for i := range codes {
+		var b strings.Builder
+		for j := 0; j < 10; j++ {
+			if j == 5 {
+				b.WriteByte('-')
+			}
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteByte(backupCodeChars[n.Int64()])
+		}
+		codes[i] = b.String()
+	}
+	return codes, nil
+}
+
+// HashBackupCode 备用码只保存哈希，比较前统一格式
+func HashBackupCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
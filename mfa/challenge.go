@@ -0,0 +1,76 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+)
+
+const challengeTTL = 2 * time.Minute
+
+type challenge struct {
+	userID    uint
+	username  string
+	expiresAt time.Time
+}
+
+// ChallengeStore 保存 Access-Challenge 的 State，仅存在于当前进程内存中，
+// 多实例部署时需要让 FreeRADIUS 将同一会话转发到同一实例
+type ChallengeStore struct {
+	mu         sync.Mutex
+	challenges map[string]challenge
+}
+
+var Challenges = &ChallengeStore{challenges: make(map[string]challenge)}
+
+func init() {
+	metrics.RegisterStore("mfa_challenge", Challenges.Len)
+}
+
+// Len 当前保存的 State 数，包括尚未清理的过期 State
+func (s *ChallengeStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.challenges)
+}
+
+// Issue 为已通过密码校验的用户生成一次性 State
+func (s *ChallengeStore) Issue(userID uint, username string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, ch := range s.challenges {
+		if now.After(ch.expiresAt) {
+			delete(s.challenges, key)
+		}
+	}
+	s.challenges[state] = challenge{userID: userID, username: username, expiresAt: now.Add(challengeTTL)}
+	return state, nil
+}
+
+// Take 取出并删除 State，State 不存在、过期或用户名不匹配时返回 false
+func (s *ChallengeStore) Take(state, username string) (uint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch, ok := s.challenges[state]
+	if ok {
+		delete(s.challenges, state)
+		ok = !time.Now().After(ch.expiresAt) && ch.username == username
+	}
+	metrics.StoreLookups.Inc("mfa_challenge", metrics.LookupResult(ok))
+	if !ok {
+		return 0, false
+	}
+	return ch.userID, true
+}
@@ -0,0 +1,125 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// IsRequired 账号本身或其所属用户组要求多因素认证
+func IsRequired(ctx context.Context, user *models.User) (bool, error) {
+	if user.RequireMFA {
+		return true, nil
+	}
+	return database.DAO.Group.IsMemberOfAny(ctx, user.ID, config.AppConfig.MFARequiredGroups)
+}
+
+// IsEnrolled 用户已完成 TOTP 绑定
+func IsEnrolled(ctx context.Context, userID uint) (bool, error) {
+	m, err := database.DAO.MFA.GetByUserID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return m.Enabled, nil
+}
+
+// Verify 校验 TOTP 验证码或备用码，已使用过的验证码会被拒绝
+func Verify(ctx context.Context, userID uint, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	m, err := database.DAO.MFA.GetByUserID(ctx, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	if m != nil && m.Enabled {
+		if step, ok := ValidateTOTP(m.Secret, code, time.Now()); ok {
+			return database.DAO.MFA.ConsumeStep(ctx, userID, step)
+		}
+	}
+
+	// 备用码同时作为 Web 登录的恢复码，不依赖 TOTP 是否启用
+	if looksLikeBackupCode(code) {
+		return database.DAO.MFA.ConsumeBackupCode(ctx, userID, HashBackupCode(code))
+	}
+
+	return false, nil
+}
+
+// Web 登录可用的第二因素
+const (
+	MethodTOTP         = "totp"
+	MethodWebAuthn     = "webauthn"
+	MethodRecoveryCode = "recovery_code"
+)
+
+// WebMethods 返回用户 Web 登录可用的第二因素，恢复码不单独视为已绑定
+func WebMethods(ctx context.Context, userID uint) ([]string, error) {
+	var methods []string
+
+	enrolled, err := IsEnrolled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrolled {
+		methods = append(methods, MethodTOTP)
+	}
+
+	keys, err := database.DAO.WebAuthn.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if keys > 0 {
+		methods = append(methods, MethodWebAuthn)
+	}
+
+	if len(methods) == 0 {
+		return nil, nil
+	}
+
+	remaining, err := database.DAO.MFA.CountUnusedBackupCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if remaining > 0 {
+		methods = append(methods, MethodRecoveryCode)
+	}
+	return methods, nil
+}
+
+// SplitPassword 拆分 "password+123456" 形式的口令，后缀不像验证码时返回 false
+func SplitPassword(password string) (string, string, bool) {
+	i := strings.LastIndex(password, "+")
+	if i <= 0 {
+		return password, "", false
+	}
+
+	code := password[i+1:]
+	if (len(code) == totpDigits && isDigits(code)) || looksLikeBackupCode(code) {
+		return password[:i], code, true
+	}
+	return password, "", false
+}
+
+func looksLikeBackupCode(code string) bool {
+	normalized := strings.ReplaceAll(code, "-", "")
+	if len(normalized) != 10 {
+		return false
+	}
+	for _, r := range strings.ToLower(normalized) {
+		if !strings.ContainsRune(backupCodeChars, r) {
+			return false
+		}
+	}
+	return true
+}
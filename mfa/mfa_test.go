@@ -0,0 +1,140 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// RFC 6238 附录 B 的 SHA1 密钥，T=59 时 8 位验证码为 94287082
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPKnownAnswer(t *testing.T) {
+	step, ok := ValidateTOTP(rfcSecret, "287082", time.Unix(59, 0))
+	if !ok || step != 1 {
+		t.Fatalf("ValidateTOTP = %d, %v, want step 1", step, ok)
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	key, _ := secretEncoding.DecodeString(rfcSecret)
+	now := time.Unix(1_700_000_000, 0)
+	current := now.Unix() / totpPeriod
+
+	for offset := int64(-3); offset <= 3; offset++ {
+		code := generateCode(key, current+offset)
+		step, ok := ValidateTOTP(rfcSecret, code, now)
+		want := offset >= -totpSkew && offset <= totpSkew
+		if ok != want {
+			t.Errorf("code for step offset %d accepted = %v, want %v", offset, ok, want)
+		}
+		if ok && step != current+offset {
+			t.Errorf("code for step offset %d matched step %d", offset, step-current)
+		}
+	}
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("ValidateTOTP accepted malformed code %q", code)
+		}
+	}
+}
+
+func enroll(t *testing.T, userID uint) {
+	t.Helper()
+	ctx := context.Background()
+	if err := database.DAO.MFA.Save(ctx, &models.UserMFA{UserID: userID, Secret: rfcSecret}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.DAO.MFA.Enable(ctx, userID, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyRejectsReplayedCode(t *testing.T) {
+	dbtest.Open(t)
+	enroll(t, 1)
+	ctx := context.Background()
+
+	key, _ := secretEncoding.DecodeString(rfcSecret)
+	current := time.Now().Unix() / totpPeriod
+	code := generateCode(key, current)
+
+	if ok, err := Verify(ctx, 1, code); err != nil || !ok {
+		t.Fatalf("first use = %v, %v, want accepted", ok, err)
+	}
+	if ok, err := Verify(ctx, 1, code); err != nil || ok {
+		t.Fatalf("replay = %v, %v, want rejected", ok, err)
+	}
+	// 窗口内更早的时间步也不能在较新的验证码之后使用
+	if ok, err := Verify(ctx, 1, generateCode(key, current-1)); err != nil || ok {
+		t.Fatalf("older step after newer = %v, %v, want rejected", ok, err)
+	}
+}
+
+func TestVerifyConsumesBackupCodeOnce(t *testing.T) {
+	dbtest.Open(t)
+	enroll(t, 1)
+	enroll(t, 2)
+	ctx := context.Background()
+
+	codes, err := GenerateBackupCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = HashBackupCode(code)
+	}
+	if err := database.DAO.MFA.ReplaceBackupCodes(ctx, 1, hashes); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := Verify(ctx, 2, codes[0]); ok {
+		t.Fatal("backup code accepted for another user")
+	}
+	// 大小写与分隔符不影响匹配
+	if ok, err := Verify(ctx, 1, " "+codes[0][:5]+codes[0][6:]+" "); err != nil || !ok {
+		t.Fatalf("first use = %v, %v, want accepted", ok, err)
+	}
+	if ok, err := Verify(ctx, 1, codes[0]); err != nil || ok {
+		t.Fatalf("second use = %v, %v, want rejected", ok, err)
+	}
+	if n, err := database.DAO.MFA.CountUnusedBackupCodes(ctx, 1); err != nil || n != backupCodeCount-1 {
+		t.Fatalf("unused backup codes = %d, %v, want %d", n, err, backupCodeCount-1)
+	}
+}
+
+func TestChallengeIsSingleUseAndExpires(t *testing.T) {
+	store := &ChallengeStore{challenges: make(map[string]challenge)}
+
+	state, err := store.Issue(7, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Take(state, "bob"); ok {
+		t.Fatal("challenge accepted for another username")
+	}
+	// 用户名不匹配时 State 同样作废
+	if _, ok := store.Take(state, "alice"); ok {
+		t.Fatal("challenge reusable after a mismatched attempt")
+	}
+
+	state, _ = store.Issue(7, "alice")
+	if id, ok := store.Take(state, "alice"); !ok || id != 7 {
+		t.Fatalf("Take = %d, %v, want 7", id, ok)
+	}
+	if _, ok := store.Take(state, "alice"); ok {
+		t.Fatal("challenge accepted twice")
+	}
+
+	state, _ = store.Issue(7, "alice")
+	store.challenges[state] = challenge{userID: 7, username: "alice", expiresAt: time.Now().Add(-time.Second)}
+	if _, ok := store.Take(state, "alice"); ok {
+		t.Fatal("expired challenge accepted")
+	}
+}
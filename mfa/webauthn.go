@@ -0,0 +1,242 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+var (
+	webAuthn *webauthn.WebAuthn
+
+	ErrNoWebAuthnSession = errors.New("webauthn ceremony not started or expired")
+)
+
+// InitWebAuthn 根据配置初始化 WebAuthn 依赖方信息
+func InitWebAuthn() error {
+	var err error
+	webAuthn, err = webauthn.New(&webauthn.Config{
+		RPID:          config.AppConfig.WebAuthnRPID,
+		RPDisplayName: config.AppConfig.WebAuthnRPName,
+		RPOrigins:     config.AppConfig.WebAuthnRPOrigins,
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        webauthn.TimeoutConfig{Enforce: true, Timeout: config.AppConfig.MFALoginTimeout},
+			Registration: webauthn.TimeoutConfig{Enforce: true, Timeout: config.AppConfig.MFALoginTimeout},
+		},
+	})
+	return err
+}
+
+type webAuthnUser struct {
+	user  *models.User
+	creds []models.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(u.user.ID))
+	return id
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.creds))
+	for _, stored := range u.creds {
+		var cred webauthn.Credential
+		if err := json.Unmarshal([]byte(stored.Data), &cred); err == nil {
+			creds = append(creds, cred)
+		}
+	}
+	return creds
+}
+
+func loadWebAuthnUser(ctx context.Context, user *models.User) (*webAuthnUser, error) {
+	creds, err := database.DAO.WebAuthn.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnUser{user: user, creds: creds}, nil
+}
+
+// BeginWebAuthnRegistration 返回浏览器 navigator.credentials.create() 所需参数
+func BeginWebAuthnRegistration(ctx context.Context, user *models.User) (*protocol.CredentialCreation, error) {
+	waUser, err := loadWebAuthnUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(waUser.creds))
+	for _, cred := range waUser.WebAuthnCredentials() {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	creation, session, err := webAuthn.BeginRegistration(waUser, webauthn.WithExclusions(exclusions))
+	if err != nil {
+		return nil, err
+	}
+
+	webAuthnSessions.put(sessionKey("register", user.ID), session)
+	return creation, nil
+}
+
+// FinishWebAuthnRegistration 校验浏览器返回的注册结果并保存凭据
+func FinishWebAuthnRegistration(ctx context.Context, user *models.User, body []byte, name string) (*models.WebAuthnCredential, error) {
+	session, ok := webAuthnSessions.take(sessionKey("register", user.ID))
+	if !ok {
+		return nil, ErrNoWebAuthnSession
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(body)
+	if err != nil {
+		return nil, err
+	}
+
+	waUser, err := loadWebAuthnUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := webAuthn.CreateCredential(waUser, *session, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("Security key %d", len(waUser.creds)+1)
+	}
+
+	stored := &models.WebAuthnCredential{
+		UserID:       user.ID,
+		CredentialID: base64.RawURLEncoding.EncodeToString(cred.ID),
+		Name:         name,
+		Data:         string(data),
+	}
+	if err := database.DAO.WebAuthn.Create(ctx, stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// BeginWebAuthnLogin 返回浏览器 navigator.credentials.get() 所需参数
+func BeginWebAuthnLogin(ctx context.Context, user *models.User) (*protocol.CredentialAssertion, error) {
+	waUser, err := loadWebAuthnUser(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	assertion, session, err := webAuthn.BeginLogin(waUser)
+	if err != nil {
+		return nil, err
+	}
+
+	webAuthnSessions.put(sessionKey("login", user.ID), session)
+	return assertion, nil
+}
+
+// FinishWebAuthnLogin 校验断言并更新签名计数
+func FinishWebAuthnLogin(ctx context.Context, user *models.User, body []byte) error {
+	session, ok := webAuthnSessions.take(sessionKey("login", user.ID))
+	if !ok {
+		return ErrNoWebAuthnSession
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(body)
+	if err != nil {
+		return err
+	}
+
+	waUser, err := loadWebAuthnUser(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	cred, err := webAuthn.ValidateLogin(waUser, *session, parsed)
+	if err != nil {
+		return err
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(cred.ID)
+	for _, stored := range waUser.creds {
+		if stored.CredentialID != credentialID {
+			continue
+		}
+		data, err := json.Marshal(cred)
+		if err != nil {
+			return err
+		}
+		return database.DAO.WebAuthn.UpdateData(ctx, stored.ID, string(data))
+	}
+	return nil
+}
+
+func sessionKey(ceremony string, userID uint) string {
+	return fmt.Sprintf("%s:%d", ceremony, userID)
+}
+
+// sessionStore 保存进行中的 WebAuthn 流程，每个用户每种流程只保留最新一次
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*webauthn.SessionData
+}
+
+var webAuthnSessions = &sessionStore{sessions: make(map[string]*webauthn.SessionData)}
+
+func init() {
+	metrics.RegisterStore("webauthn", webAuthnSessions.len)
+}
+
+func (s *sessionStore) put(key string, session *webauthn.SessionData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, sess := range s.sessions {
+		if !sess.Expires.IsZero() && now.After(sess.Expires) {
+			delete(s.sessions, k)
+		}
+	}
+	s.sessions[key] = session
+}
+
+func (s *sessionStore) take(key string) (*webauthn.SessionData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if ok {
+		delete(s.sessions, key)
+	}
+	metrics.StoreLookups.Inc("webauthn", metrics.LookupResult(ok))
+	return session, ok
+}
+
+func (s *sessionStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
@@ -3,26 +3,47 @@ package middleware
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	jwtv4 "github.com/golang-jwt/jwt/v4"
 	"github.com/hertz-contrib/jwt"
 
+	"github.com/Gaojianli/raduis_mgnt/authn"
 	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/mfa"
 	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passpolicy"
+	"github.com/Gaojianli/raduis_mgnt/settings"
 )
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
+	UserID     uint   `json:"user_id"`
+	Username   string `json:"username"`
+	IsAdmin    bool   `json:"is_admin"`
+	MFAPending bool   `json:"mfa_pending"`
+}
+
+// pendingLogin 密码已校验但尚未完成第二因素的登录，只签发短期中间令牌
+type pendingLogin struct {
+	user    *models.User
+	methods []string
 }
 
 var (
 	JWTMiddleware *jwt.HertzJWTMiddleware
 	identityKey   = "user_id"
+	mfaPendingKey = "mfa_pending"
+	loginStateKey = "login_state"
+
+	passwordChangeKey   = "password_change_required"
+	passwordChangePaths = map[string]bool{
+		"/api/v1/user/profile":         true,
+		"/api/v1/user/change-password": true,
+	}
 )
 
 func InitJWT() error {
@@ -30,16 +51,29 @@ func InitJWT() error {
 	JWTMiddleware, err = jwt.New(&jwt.HertzJWTMiddleware{
 		Realm:       "radius_mgnt",
 		Key:         []byte(config.AppConfig.JWTSecret),
-		Timeout:     time.Hour * 24,
-		MaxRefresh:  time.Hour * 24,
+		Timeout:     config.AppConfig.JWTLifetime,
+		MaxRefresh:  config.AppConfig.JWTMaxRefresh,
 		IdentityKey: identityKey,
+		TimeoutFunc: func(claims jwtv4.MapClaims) time.Duration {
+			if pending, _ := claims[mfaPendingKey].(bool); pending {
+				return config.AppConfig.MFALoginTimeout
+			}
+			return settings.JWTLifetime.Get()
+		},
 		PayloadFunc: func(data interface{}) jwt.MapClaims {
-			if v, ok := data.(*models.User); ok {
+			switch v := data.(type) {
+			case *models.User:
 				return jwt.MapClaims{
 					identityKey: v.ID,
 					"username":  v.Username,
 					"is_admin":  v.IsAdmin,
 				}
+			case *pendingLogin:
+				return jwt.MapClaims{
+					identityKey:   v.user.ID,
+					"username":    v.user.Username,
+					mfaPendingKey: true,
+				}
 			}
 			return jwt.MapClaims{}
 		},
@@ -48,11 +82,13 @@ func InitJWT() error {
 			userID, _ := claims[identityKey].(float64)
 			username, _ := claims["username"].(string)
 			isAdmin, _ := claims["is_admin"].(bool)
+			mfaPending, _ := claims[mfaPendingKey].(bool)
 
 			return &Claims{
-				UserID:   uint(userID),
-				Username: username,
-				IsAdmin:  isAdmin,
+				UserID:     uint(userID),
+				Username:   username,
+				IsAdmin:    isAdmin,
+				MFAPending: mfaPending,
 			}
 		},
 		Authenticator: func(ctx context.Context, c *app.RequestContext) (interface{}, error) {
@@ -65,21 +101,66 @@ func InitJWT() error {
 				return nil, jwt.ErrMissingLoginValues
 			}
 
-			user, err := database.DAO.User.GetByUsernameForAuth(ctx, loginReq.Username)
+			user, err := authn.Authenticate(ctx, loginReq.Username, loginReq.Password)
 			if err != nil {
+				if !errors.Is(err, authn.ErrUnknownUser) && !errors.Is(err, authn.ErrInvalidCredentials) {
+					log.Printf("Authentication backend error for %s: %v", loginReq.Username, err)
+				}
 				return nil, jwt.ErrFailedAuthentication
 			}
 
-			if !user.CheckPassword(loginReq.Password) {
-				return nil, jwt.ErrFailedAuthentication
+			methods, err := mfa.WebMethods(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			if len(methods) > 0 {
+				pending := &pendingLogin{user: user, methods: methods}
+				c.Set(loginStateKey, pending)
+				return pending, nil
 			}
 
+			c.Set(loginStateKey, user)
 			return user, nil
 		},
+		LoginResponse: func(ctx context.Context, c *app.RequestContext, code int, token string, expire time.Time) {
+			resp := map[string]interface{}{
+				"code":   code,
+				"token":  token,
+				"expire": expire.Format(time.RFC3339),
+			}
+
+			state, _ := c.Get(loginStateKey)
+			switch v := state.(type) {
+			case *pendingLogin:
+				resp["mfa_required"] = true
+				resp["mfa_methods"] = v.methods
+			case *models.User:
+				RecordLogin(v.ID)
+				if v.IsAdmin && config.AppConfig.WebMFARequireAdmin && v.AuthSource != models.AuthSourceOIDC {
+					resp["mfa_enroll_required"] = true
+				}
+				if passpolicy.ChangeRequired(v) {
+					resp["password_change_required"] = true
+				} else if warning, ok := passpolicy.ExpiryWarning(v); ok {
+					resp["password_expiry_warning"] = warning
+				}
+			}
+
+			c.JSON(code, resp)
+		},
 		Authorizator: func(data interface{}, ctx context.Context, c *app.RequestContext) bool {
 			if claims, ok := data.(*Claims); ok {
+				// 中间令牌只能用于完成第二因素校验
+				if claims.MFAPending {
+					return false
+				}
 				user, err := database.DAO.User.GetByID(ctx, claims.UserID)
-				if err != nil || user.Banned {
+				if err != nil || user.Banned || user.Status != models.UserStatusActive || user.IsExpired() {
+					return false
+				}
+				// 需要修改密码时只开放个人资料与修改密码接口
+				if passpolicy.ChangeRequired(user) && !passwordChangePaths[c.FullPath()] {
+					c.Set(passwordChangeKey, true)
 					return false
 				}
 				return true
@@ -87,6 +168,14 @@ func InitJWT() error {
 			return false
 		},
 		Unauthorized: func(ctx context.Context, c *app.RequestContext, code int, message string) {
+			if c.GetBool(passwordChangeKey) {
+				c.JSON(code, map[string]interface{}{
+					"code":                     code,
+					"message":                  "Password change required",
+					"password_change_required": true,
+				})
+				return
+			}
 			c.JSON(code, map[string]interface{}{
 				"code":    code,
 				"message": message,
@@ -112,10 +201,85 @@ func RequireAdmin() app.HandlerFunc {
 			c.Abort()
 			return
 		}
+
+		// 管理员必须先绑定第二因素才能使用管理接口；OIDC 账号的第二因素由 IdP 负责
+		if config.AppConfig.WebMFARequireAdmin {
+			userID, _ := claims[identityKey].(float64)
+			if !ssoAccount(ctx, uint(userID)) {
+				methods, err := mfa.WebMethods(ctx, uint(userID))
+				if err != nil || len(methods) == 0 {
+					c.JSON(consts.StatusForbidden, map[string]interface{}{
+						"code":                consts.StatusForbidden,
+						"message":             "Two-factor enrollment required",
+						"mfa_enroll_required": true,
+					})
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Next(ctx)
+	}
+}
+
+func ssoAccount(ctx context.Context, userID uint) bool {
+	user, err := database.DAO.User.GetByID(ctx, userID)
+	return err == nil && user.AuthSource == models.AuthSourceOIDC
+}
+
+// RequireMFAPending 只接受登录第一步签发的中间令牌，用于第二因素校验接口
+func RequireMFAPending() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		claims, err := JWTMiddleware.GetClaimsFromJWT(ctx, c)
+		if err != nil {
+			c.JSON(consts.StatusUnauthorized, map[string]interface{}{
+				"code":    consts.StatusUnauthorized,
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+
+		if pending, _ := claims[mfaPendingKey].(bool); !pending {
+			c.JSON(consts.StatusBadRequest, map[string]interface{}{
+				"code":    consts.StatusBadRequest,
+				"message": "No pending two-factor login",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("JWT_PAYLOAD", claims)
 		c.Next(ctx)
 	}
 }
 
+// RecordLogin 异步更新最近登录时间，失败只记录日志
+func RecordLogin(userID uint) {
+	now := time.Now()
+	go func() {
+		if err := database.DAO.User.UpdateLastLogin(context.Background(), userID, now); err != nil {
+			log.Printf("Failed to record last login for user %d: %v", userID, err)
+		}
+	}()
+}
+
+// IssueToken 第二因素校验通过后签发正式令牌，响应格式与登录接口一致
+func IssueToken(ctx context.Context, c *app.RequestContext, user *models.User) {
+	token, expire, err := JWTMiddleware.TokenGenerator(user)
+	if err != nil {
+		c.JSON(consts.StatusInternalServerError, map[string]interface{}{
+			"code":    consts.StatusInternalServerError,
+			"message": jwt.ErrFailedTokenCreation.Error(),
+		})
+		return
+	}
+
+	c.Set(loginStateKey, user)
+	JWTMiddleware.LoginResponse(ctx, c, consts.StatusOK, token, expire)
+}
+
 func GetCurrentUser(ctx context.Context, c *app.RequestContext) (*Claims, error) {
 	claims := jwt.ExtractClaims(ctx, c)
 	if claims == nil {
@@ -129,10 +293,12 @@ func GetCurrentUser(ctx context.Context, c *app.RequestContext) (*Claims, error)
 
 	username, _ := claims["username"].(string)
 	isAdmin, _ := claims["is_admin"].(bool)
+	mfaPending, _ := claims[mfaPendingKey].(bool)
 
 	return &Claims{
-		UserID:   uint(userID),
-		Username: username,
-		IsAdmin:  isAdmin,
+		UserID:     uint(userID),
+		Username:   username,
+		IsAdmin:    isAdmin,
+		MFAPending: mfaPending,
 	}, nil
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// ClientIP 只有连接来自可信代理时才采信 X-Forwarded-For / X-Real-IP，否则使用连接的对端地址；
+// Hertz 默认信任任意来源，客户端可伪造请求头绕过按 IP 的限流
+func ClientIP(trustedProxies []string) app.ClientIP {
+	var cidrs []*net.IPNet
+	for _, proxy := range trustedProxies {
+		if _, cidr, err := net.ParseCIDR(proxy); err == nil {
+			cidrs = append(cidrs, cidr)
+			continue
+		}
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return app.ClientIPWithOption(app.ClientIPOptions{
+		RemoteIPHeaders: []string{"X-Forwarded-For", "X-Real-IP"},
+		TrustedCIDRs:    cidrs,
+	})
+}
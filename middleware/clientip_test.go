@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/common/test/mock"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// remoteConn 为测试请求指定连接的对端地址
+type remoteConn struct {
+	*mock.Conn
+	addr net.Addr
+}
+
+func (c remoteConn) RemoteAddr() net.Addr { return c.addr }
+
+func TestClientIPTrustsHeadersOnlyFromProxies(t *testing.T) {
+	cases := []struct {
+		name    string
+		proxies []string
+		remote  string
+		want    string
+	}{
+		{"no proxies configured", nil, "203.0.113.7", "203.0.113.7"},
+		{"untrusted remote", []string{"10.0.0.0/8"}, "203.0.113.7", "203.0.113.7"},
+		{"trusted CIDR", []string{"10.0.0.0/8"}, "10.1.2.3", "198.51.100.9"},
+		{"trusted single IP", []string{"10.1.2.3"}, "10.1.2.3", "198.51.100.9"},
+		{"trusted IPv6", []string{"::1"}, "::1", "198.51.100.9"},
+	}
+	for _, tc := range cases {
+		c := ut.CreateUtRequestContext(consts.MethodPost, "/api/v1/auth/password/forgot", nil,
+			ut.Header{Key: "X-Forwarded-For", Value: "198.51.100.9"})
+		c.SetConn(remoteConn{Conn: mock.NewConn(""), addr: &net.TCPAddr{IP: net.ParseIP(tc.remote), Port: 40000}})
+		c.SetClientIPFunc(ClientIP(tc.proxies))
+		if got := c.ClientIP(); got != tc.want {
+			t.Errorf("%s: ClientIP() = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
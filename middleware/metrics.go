@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+)
+
+// Metrics 按路由模板统计请求次数与耗时，避免路径参数产生大量标签；未匹配任何路由的请求记为 unmatched。
+// 流式响应只统计到处理函数返回为止
+func Metrics() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		start := time.Now()
+		c.Next(ctx)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := methodLabel(c.Method())
+		metrics.HTTPRequests.Inc(method, route, strconv.Itoa(c.Response.StatusCode()))
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// methodLabel 只保留标准方法名，其余任意方法记为 other，防止客户端构造方法名撑爆标签基数
+func methodLabel(method []byte) string {
+	switch m := string(method); m {
+	case consts.MethodGet, consts.MethodHead, consts.MethodPost, consts.MethodPut, consts.MethodPatch,
+		consts.MethodDelete, consts.MethodConnect, consts.MethodOptions, consts.MethodTrace:
+		return m
+	}
+	return "other"
+}
+
+// RequireMetricsToken 业务端口上的 /metrics 需要 METRICS_TOKEN
+func RequireMetricsToken() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		if !metrics.Authorized(string(c.GetHeader("Authorization"))) {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.String(consts.StatusUnauthorized, "Unauthorized")
+			c.Abort()
+			return
+		}
+		c.Next(ctx)
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+
+	"github.com/Gaojianli/raduis_mgnt/metrics"
+)
+
+func TestMetricsFoldsUnknownMethods(t *testing.T) {
+	for _, method := range []string{"GET", "FOO1", "get", "X-RANDOM-7f3a"} {
+		c := ut.CreateUtRequestContext(method, "/metrics-method-test", nil)
+		c.SetFullPath("/metrics-method-test")
+		c.SetHandlers(app.HandlersChain{Metrics(), func(ctx context.Context, c *app.RequestContext) {}})
+		c.Next(context.Background())
+	}
+
+	var buf bytes.Buffer
+	metrics.WriteTo(&buf)
+	var methods []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "radius_manager_http_requests_total{") && strings.Contains(line, `route="/metrics-method-test"`) {
+			methods = append(methods, line)
+		}
+	}
+	if len(methods) != 2 || !strings.Contains(methods[0]+methods[1], `method="GET"`) || !strings.Contains(methods[0]+methods[1], `method="other"`) {
+		t.Fatalf("request counters = %q, want one GET and one other series", methods)
+	}
+	if !strings.Contains(buf.String(), `method="other",route="/metrics-method-test",status="200"} 3`) {
+		t.Fatalf("other series should count the 3 non-standard requests:\n%s", strings.Join(methods, "\n"))
+	}
+}
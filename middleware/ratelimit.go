@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// RateLimiter 固定窗口计数的内存限流器，多实例部署时各实例独立计数
+type RateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow 记录一次请求，超出窗口内配额时返回 false
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.prune(now)
+		l.windows[key] = &rateWindow{start: now, count: 1}
+		return true
+	}
+
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+func (l *RateLimiter) prune(now time.Time) {
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// RateLimitByIP 按客户端 IP 限流，超限返回 429
+func RateLimitByIP(l *RateLimiter) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		if !l.Allow(c.ClientIP()) {
+			c.Header("Retry-After", strconv.Itoa(int(l.window.Seconds())))
+			c.JSON(consts.StatusTooManyRequests, map[string]interface{}{
+				"code":    consts.StatusTooManyRequests,
+				"message": "Too many requests, please try again later",
+			})
+			c.Abort()
+			return
+		}
+		c.Next(ctx)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/scim"
+)
+
+// RequireSCIMToken 校验 SCIM 客户端的静态 Bearer 令牌，未配置 SCIM_TOKEN 时接口不可用
+func RequireSCIMToken() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		expected := config.AppConfig.SCIMToken
+		if expected == "" {
+			abortSCIM(c, &scim.Error{Status: consts.StatusNotFound, Detail: "SCIM provisioning is not enabled"})
+			return
+		}
+
+		header := string(c.GetHeader("Authorization"))
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(expected)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			abortSCIM(c, &scim.Error{Status: consts.StatusUnauthorized, Detail: "Invalid bearer token"})
+			return
+		}
+
+		c.Next(ctx)
+	}
+}
+
+func abortSCIM(c *app.RequestContext, err *scim.Error) {
+	body, _ := json.Marshal(err)
+	c.Data(err.Status, scim.ContentType, body)
+	c.Abort()
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Gaojianli/raduis_mgnt/tracing"
+)
+
+// requestHeaderCarrier 让 OpenTelemetry 传播器读取 Hertz 请求头
+type requestHeaderCarrier struct {
+	header *protocol.RequestHeader
+}
+
+func (h requestHeaderCarrier) Get(key string) string {
+	return string(h.header.Peek(key))
+}
+
+func (h requestHeaderCarrier) Set(key, value string) {
+	h.header.Set(key, value)
+}
+
+func (h requestHeaderCarrier) Keys() []string {
+	var keys []string
+	h.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Tracing 为每个请求创建服务端 span，并从 traceparent 等请求头继续上游链路；
+// 后续处理函数使用的 ctx 携带该 span，DAO 查询与认证日志写入都会挂在其下
+func Tracing() app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, requestHeaderCarrier{&c.Request.Header})
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := string(c.Method())
+		ctx, span := tracing.Tracer().Start(ctx, method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", string(c.Path())),
+				attribute.String("client.address", c.ClientIP()),
+				attribute.String("user_agent.original", string(c.UserAgent())),
+			),
+		)
+		defer span.End()
+
+		c.Next(ctx)
+
+		status := c.Response.StatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, strconv.Itoa(status))
+		}
+	}
+}
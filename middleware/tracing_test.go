@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Gaojianli/raduis_mgnt/tracing"
+)
+
+func TestTracingContinuesUpstreamTrace(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.Install(exporter)
+	defer provider.Shutdown(context.Background())
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	c := ut.CreateUtRequestContext(consts.MethodGet, "/api/v1/users/7", nil,
+		ut.Header{Key: "traceparent", Value: "00-" + traceID + "-00f067aa0ba902b7-01"})
+	c.SetFullPath("/api/v1/users/:id")
+
+	var handlerSpan trace.SpanContext
+	c.SetHandlers(app.HandlersChain{Tracing(), func(ctx context.Context, c *app.RequestContext) {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		c.SetStatusCode(consts.StatusInternalServerError)
+	}})
+	c.Next(context.Background())
+	provider.ForceFlush(context.Background())
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /api/v1/users/:id" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span = %q (%s), want server span GET /api/v1/users/:id", span.Name, span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != traceID || !span.Parent.IsRemote() {
+		t.Errorf("span did not continue the upstream trace: %s", span.SpanContext.TraceID())
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Errorf("handler context does not carry the request span")
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("status = %v, want error for a 500 response", span.Status.Code)
+	}
+}
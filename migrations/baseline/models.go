@@ -0,0 +1,176 @@
+// Package baseline 冻结 000001_baseline 迁移对应的模型，只用于把版本化迁移之前由 AutoMigrate 建表的数据库补齐到基线。
+// 这里的定义不随 models 包修改：之后的表结构变更只能通过新增迁移脚本完成，否则补齐时会提前建出后续版本的结构。
+// 类型名与字段名决定列名、索引名和多对多关联表的外键名，须与当时的 models 保持一致
+package baseline
+
+import "time"
+
+type User struct {
+	ID                 uint       `gorm:"primarykey"`
+	Username           string     `gorm:"unique;not null"`
+	Password           string     `gorm:"not null"`
+	Salt               string     `gorm:"not null"`
+	IsAdmin            bool       `gorm:"default:false"`
+	Email              string     `gorm:"unique"`
+	Banned             bool       `gorm:"default:false"`
+	RequireMFA         bool       `gorm:"default:false"`
+	Status             string     `gorm:"size:20;not null;default:active;index"`
+	ExpiresAt          *time.Time `gorm:"index"`
+	SponsorID          *uint
+	Groups             []Group `gorm:"many2many:user_groups"`
+	PasswordChangedAt  *time.Time
+	MustChangePassword bool       `gorm:"default:false"`
+	AuthSource         string     `gorm:"size:32;not null;default:local;index"`
+	ExternalID         *string    `gorm:"size:255;uniqueIndex"`
+	LastLoginAt        *time.Time `gorm:"index"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type AuthLog struct {
+	ID         uint   `gorm:"primarykey;index:idx_auth_logs_created_id,priority:2;index:idx_auth_logs_username_created_id,priority:3;index:idx_auth_logs_device_mac_created_id,priority:3"`
+	Username   string `gorm:"not null;index;index:idx_auth_logs_username_created_id,priority:1"`
+	AuthType   string `gorm:"not null"`
+	Success    bool   `gorm:"not null;index"`
+	IPAddress  string `gorm:"not null"`
+	UserAgent  string
+	DeviceMAC  string `gorm:"size:255;index:idx_auth_logs_device_mac_created_id,priority:1"`
+	TargetSSID string
+	Reason     string    `gorm:"size:32;index"`
+	CreatedAt  time.Time `gorm:"index:idx_auth_logs_created_id,priority:1;index:idx_auth_logs_username_created_id,priority:2;index:idx_auth_logs_device_mac_created_id,priority:2"`
+}
+
+type Group struct {
+	ID          uint   `gorm:"primarykey"`
+	Name        string `gorm:"size:100;unique;not null"`
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type GuestRequest struct {
+	ID           uint   `gorm:"primarykey"`
+	Username     string `gorm:"size:50;not null;index"`
+	Email        string `gorm:"not null"`
+	FullName     string
+	Reason       string
+	SponsorID    uint   `gorm:"not null;index"`
+	Status       string `gorm:"size:20;not null;default:pending;index"`
+	GuestUserID  *uint
+	ExpiresAt    *time.Time
+	DecidedAt    *time.Time
+	DecisionNote string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type AuditLog struct {
+	ID         uint  `gorm:"primarykey"`
+	ActorID    *uint `gorm:"index"`
+	ActorName  string
+	Action     string `gorm:"size:64;not null;index"`
+	TargetType string `gorm:"size:32"`
+	TargetID   uint   `gorm:"index"`
+	Detail     string `gorm:"type:text"`
+	CreatedAt  time.Time
+}
+
+type UserMFA struct {
+	ID           uint   `gorm:"primarykey"`
+	UserID       uint   `gorm:"uniqueIndex;not null"`
+	Secret       string `gorm:"not null"`
+	Enabled      bool   `gorm:"default:false"`
+	LastUsedStep int64  `gorm:"not null;default:0"`
+	EnabledAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type MFABackupCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type WebAuthnCredential struct {
+	ID           uint   `gorm:"primarykey"`
+	UserID       uint   `gorm:"index;not null"`
+	CredentialID string `gorm:"size:255;uniqueIndex;not null"`
+	Name         string
+	Data         string `gorm:"type:text;not null"`
+	LastUsedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+type PasswordHistory struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"not null;index"`
+	Password  string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RequestIP string `gorm:"size:64"`
+	CreatedAt time.Time
+}
+
+type RetentionRun struct {
+	ID         uint   `gorm:"primarykey"`
+	Trigger    string `gorm:"size:16;not null"`
+	Status     string `gorm:"size:16;not null"`
+	Archived   int64
+	Deleted    int64
+	Files      int
+	Error      string    `gorm:"size:1024"`
+	StartedAt  time.Time `gorm:"index"`
+	FinishedAt *time.Time
+}
+
+type AuthLogRollup struct {
+	ID          uint      `gorm:"primarykey"`
+	Period      string    `gorm:"size:8;not null;uniqueIndex:idx_auth_log_rollups_key,priority:1"`
+	BucketStart time.Time `gorm:"not null;uniqueIndex:idx_auth_log_rollups_key,priority:2"`
+	Username    string    `gorm:"size:191;not null;uniqueIndex:idx_auth_log_rollups_key,priority:3;index:idx_auth_log_rollups_username"`
+	IPAddress   string    `gorm:"size:64;not null;uniqueIndex:idx_auth_log_rollups_key,priority:4"`
+	TargetSSID  string    `gorm:"column:target_ssid;size:128;not null;uniqueIndex:idx_auth_log_rollups_key,priority:5"`
+	AuthType    string    `gorm:"size:16;not null;uniqueIndex:idx_auth_log_rollups_key,priority:6"`
+	Success     bool      `gorm:"not null;uniqueIndex:idx_auth_log_rollups_key,priority:7"`
+	Reason      string    `gorm:"size:32;not null;uniqueIndex:idx_auth_log_rollups_key,priority:8"`
+	Count       int64     `gorm:"not null;default:0"`
+}
+
+type AuthStatsState struct {
+	ID        uint `gorm:"primarykey"`
+	LastLogID uint `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+func (User) TableName() string               { return "users" }
+func (AuthLog) TableName() string            { return "auth_logs" }
+func (Group) TableName() string              { return "radius_groups" }
+func (GuestRequest) TableName() string       { return "guest_requests" }
+func (AuditLog) TableName() string           { return "audit_logs" }
+func (UserMFA) TableName() string            { return "user_mfa" }
+func (MFABackupCode) TableName() string      { return "mfa_backup_codes" }
+func (WebAuthnCredential) TableName() string { return "webauthn_credentials" }
+func (PasswordHistory) TableName() string    { return "password_histories" }
+func (PasswordResetToken) TableName() string { return "password_reset_tokens" }
+func (RetentionRun) TableName() string       { return "retention_runs" }
+func (AuthLogRollup) TableName() string      { return "auth_log_rollups" }
+func (AuthStatsState) TableName() string     { return "auth_stats_state" }
+
+// Models 按 AutoMigrate 的参数顺序返回全部基线模型
+func Models() []interface{} {
+	return []interface{}{
+		&User{}, &AuthLog{}, &Group{}, &GuestRequest{}, &AuditLog{},
+		&UserMFA{}, &MFABackupCode{}, &WebAuthnCredential{}, &PasswordHistory{}, &PasswordResetToken{}, &RetentionRun{},
+		&AuthLogRollup{}, &AuthStatsState{},
+	}
+}
@@ -0,0 +1,81 @@
+package baseline_test
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/Gaojianli/raduis_mgnt/migrations"
+	"github.com/Gaojianli/raduis_mgnt/migrations/baseline"
+)
+
+func openSQLite(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name)), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+var (
+	spaces       = regexp.MustCompile(`\s+`)
+	paddedSymbol = regexp.MustCompile(` ?([(),]) ?`)
+)
+
+// normalize 忽略换行与缩进，sqlite_master 中保存的是建表时的原文
+func normalize(sql string) string {
+	return paddedSymbol.ReplaceAllString(spaces.ReplaceAllString(strings.TrimSpace(sql), " "), "$1")
+}
+
+func schema(t *testing.T, db *gorm.DB) map[string]string {
+	t.Helper()
+	var rows []struct {
+		Name string
+		SQL  string `gorm:"column:sql"`
+	}
+	if err := db.Raw("SELECT name, sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'").Scan(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]string, len(rows))
+	for _, row := range rows {
+		out[row.Name] = normalize(row.SQL)
+	}
+	return out
+}
+
+// 冻结的模型必须与 000001_baseline 的 SQL 建出完全相同的表结构，否则补齐旧数据库后与新建的数据库不一致
+func TestModelsMatchBaselineSQL(t *testing.T) {
+	list, err := migrations.Load("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromSQL := openSQLite(t, "sql.db")
+	for _, statement := range migrations.Statements(list[0].Up) {
+		if err := fromSQL.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	fromModels := openSQLite(t, "models.db")
+	if err := fromModels.AutoMigrate(baseline.Models()...); err != nil {
+		t.Fatal(err)
+	}
+
+	want, got := schema(t, fromSQL), schema(t, fromModels)
+	for name, sql := range want {
+		if got[name] != sql {
+			t.Errorf("%s differs:\n baseline SQL: %s\n models:       %s", name, sql, got[name])
+		}
+	}
+	for name := range got {
+		if _, ok := want[name]; !ok {
+			t.Errorf("%s is created by the models but not by the baseline SQL", name)
+		}
+	}
+}
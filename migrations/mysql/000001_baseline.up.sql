@@ -0,0 +1,197 @@
+-- 版本化迁移之前由 AutoMigrate 创建的表结构
+CREATE TABLE `users` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `username` varchar(191) NOT NULL,
+    `password` longtext NOT NULL,
+    `salt` longtext NOT NULL,
+    `is_admin` boolean DEFAULT false,
+    `email` varchar(191),
+    `banned` boolean DEFAULT false,
+    `require_mfa` boolean DEFAULT false,
+    `status` varchar(20) NOT NULL DEFAULT 'active',
+    `expires_at` datetime(3) NULL,
+    `sponsor_id` bigint unsigned,
+    `password_changed_at` datetime(3) NULL,
+    `must_change_password` boolean DEFAULT false,
+    `auth_source` varchar(32) NOT NULL DEFAULT 'local',
+    `external_id` varchar(255),
+    `last_login_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_users_status` (`status`),
+    INDEX `idx_users_expires_at` (`expires_at`),
+    INDEX `idx_users_auth_source` (`auth_source`),
+    UNIQUE INDEX `idx_users_external_id` (`external_id`),
+    INDEX `idx_users_last_login_at` (`last_login_at`),
+    CONSTRAINT `uni_users_username` UNIQUE (`username`),
+    CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+
+CREATE TABLE `radius_groups` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `name` varchar(100) NOT NULL,
+    `description` longtext,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `uni_radius_groups_name` UNIQUE (`name`)
+);
+
+CREATE TABLE `user_groups` (
+    `user_id` bigint unsigned,
+    `group_id` bigint unsigned,
+    PRIMARY KEY (`user_id`,`group_id`),
+    CONSTRAINT `fk_user_groups_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+    CONSTRAINT `fk_user_groups_group` FOREIGN KEY (`group_id`) REFERENCES `radius_groups`(`id`)
+);
+
+CREATE TABLE `auth_logs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `username` varchar(191) NOT NULL,
+    `auth_type` longtext NOT NULL,
+    `success` boolean NOT NULL,
+    `ip_address` longtext NOT NULL,
+    `user_agent` longtext,
+    `device_mac` varchar(255),
+    `target_ss_id` longtext,
+    `reason` varchar(32),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_auth_logs_created_id` (`created_at`,`id`),
+    INDEX `idx_auth_logs_username_created_id` (`username`,`created_at`,`id`),
+    INDEX `idx_auth_logs_device_mac_created_id` (`device_mac`,`created_at`,`id`),
+    INDEX `idx_auth_logs_username` (`username`),
+    INDEX `idx_auth_logs_success` (`success`),
+    INDEX `idx_auth_logs_reason` (`reason`)
+);
+
+CREATE TABLE `guest_requests` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `username` varchar(50) NOT NULL,
+    `email` longtext NOT NULL,
+    `full_name` longtext,
+    `reason` longtext,
+    `sponsor_id` bigint unsigned NOT NULL,
+    `status` varchar(20) NOT NULL DEFAULT 'pending',
+    `guest_user_id` bigint unsigned,
+    `expires_at` datetime(3) NULL,
+    `decided_at` datetime(3) NULL,
+    `decision_note` longtext,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_guest_requests_username` (`username`),
+    INDEX `idx_guest_requests_sponsor_id` (`sponsor_id`),
+    INDEX `idx_guest_requests_status` (`status`)
+);
+
+CREATE TABLE `audit_logs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `actor_id` bigint unsigned,
+    `actor_name` longtext,
+    `action` varchar(64) NOT NULL,
+    `target_type` varchar(32),
+    `target_id` bigint unsigned,
+    `detail` text,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_audit_logs_actor_id` (`actor_id`),
+    INDEX `idx_audit_logs_action` (`action`),
+    INDEX `idx_audit_logs_target_id` (`target_id`)
+);
+
+CREATE TABLE `user_mfa` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `secret` longtext NOT NULL,
+    `enabled` boolean DEFAULT false,
+    `last_used_step` bigint NOT NULL DEFAULT 0,
+    `enabled_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_user_mfa_user_id` (`user_id`)
+);
+
+CREATE TABLE `mfa_backup_codes` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `code_hash` varchar(64) NOT NULL,
+    `used_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_mfa_backup_codes_user_id` (`user_id`)
+);
+
+CREATE TABLE `webauthn_credentials` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `credential_id` varchar(255) NOT NULL,
+    `name` longtext,
+    `data` text NOT NULL,
+    `last_used_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_webauthn_credentials_user_id` (`user_id`),
+    UNIQUE INDEX `idx_webauthn_credentials_credential_id` (`credential_id`)
+);
+
+CREATE TABLE `password_histories` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `password` longtext NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_password_histories_user_id` (`user_id`)
+);
+
+CREATE TABLE `password_reset_tokens` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `token_hash` varchar(64) NOT NULL,
+    `expires_at` datetime(3) NOT NULL,
+    `used_at` datetime(3) NULL,
+    `request_ip` varchar(64),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_password_reset_tokens_user_id` (`user_id`),
+    UNIQUE INDEX `idx_password_reset_tokens_token_hash` (`token_hash`)
+);
+
+CREATE TABLE `retention_runs` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `trigger` varchar(16) NOT NULL,
+    `status` varchar(16) NOT NULL,
+    `archived` bigint,
+    `deleted` bigint,
+    `files` bigint,
+    `error` varchar(1024),
+    `started_at` datetime(3) NULL,
+    `finished_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_retention_runs_started_at` (`started_at`)
+);
+
+CREATE TABLE `auth_log_rollups` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `period` varchar(8) NOT NULL,
+    `bucket_start` datetime(3) NOT NULL,
+    `username` varchar(191) NOT NULL,
+    `ip_address` varchar(64) NOT NULL,
+    `target_ssid` varchar(128) NOT NULL,
+    `auth_type` varchar(16) NOT NULL,
+    `success` boolean NOT NULL,
+    `reason` varchar(32) NOT NULL,
+    `count` bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_auth_log_rollups_key` (`period`,`bucket_start`,`username`,`ip_address`,`target_ssid`,`auth_type`,`success`,`reason`),
+    INDEX `idx_auth_log_rollups_username` (`username`)
+);
+
+CREATE TABLE `auth_stats_state` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `last_log_id` bigint unsigned NOT NULL DEFAULT 0,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`)
+);
@@ -0,0 +1,19 @@
+CREATE TABLE `settings` (
+    `setting_key` varchar(128),
+    `value` text NOT NULL,
+    `updated_by` varchar(255),
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`setting_key`)
+);
+
+CREATE TABLE `setting_changes` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `setting_key` varchar(128) NOT NULL,
+    `old_value` text,
+    `new_value` text,
+    `actor_id` bigint unsigned,
+    `actor_name` varchar(255),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_setting_changes_setting_key` (`setting_key`)
+);
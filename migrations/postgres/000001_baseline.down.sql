@@ -0,0 +1,14 @@
+DROP TABLE "auth_stats_state";
+DROP TABLE "auth_log_rollups";
+DROP TABLE "retention_runs";
+DROP TABLE "password_reset_tokens";
+DROP TABLE "password_histories";
+DROP TABLE "webauthn_credentials";
+DROP TABLE "mfa_backup_codes";
+DROP TABLE "user_mfa";
+DROP TABLE "audit_logs";
+DROP TABLE "guest_requests";
+DROP TABLE "auth_logs";
+DROP TABLE "user_groups";
+DROP TABLE "radius_groups";
+DROP TABLE "users";
@@ -0,0 +1,197 @@
+-- 版本化迁移之前由 AutoMigrate 创建的表结构
+CREATE TABLE "users" (
+    "id" bigserial,
+    "username" text NOT NULL,
+    "password" text NOT NULL,
+    "salt" text NOT NULL,
+    "is_admin" boolean DEFAULT false,
+    "email" text,
+    "banned" boolean DEFAULT false,
+    "require_mfa" boolean DEFAULT false,
+    "status" varchar(20) NOT NULL DEFAULT 'active',
+    "expires_at" timestamptz,
+    "sponsor_id" bigint,
+    "password_changed_at" timestamptz,
+    "must_change_password" boolean DEFAULT false,
+    "auth_source" varchar(32) NOT NULL DEFAULT 'local',
+    "external_id" varchar(255),
+    "last_login_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_users_username" UNIQUE ("username"),
+    CONSTRAINT "uni_users_email" UNIQUE ("email")
+);
+CREATE INDEX "idx_users_last_login_at" ON "users" ("last_login_at");
+CREATE UNIQUE INDEX "idx_users_external_id" ON "users" ("external_id");
+CREATE INDEX "idx_users_auth_source" ON "users" ("auth_source");
+CREATE INDEX "idx_users_expires_at" ON "users" ("expires_at");
+CREATE INDEX "idx_users_status" ON "users" ("status");
+
+CREATE TABLE "radius_groups" (
+    "id" bigserial,
+    "name" varchar(100) NOT NULL,
+    "description" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "uni_radius_groups_name" UNIQUE ("name")
+);
+
+CREATE TABLE "user_groups" (
+    "user_id" bigint,
+    "group_id" bigint,
+    PRIMARY KEY ("user_id","group_id"),
+    CONSTRAINT "fk_user_groups_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_user_groups_group" FOREIGN KEY ("group_id") REFERENCES "radius_groups"("id")
+);
+
+CREATE TABLE "auth_logs" (
+    "id" bigserial,
+    "username" text NOT NULL,
+    "auth_type" text NOT NULL,
+    "success" boolean NOT NULL,
+    "ip_address" text NOT NULL,
+    "user_agent" text,
+    "device_mac" varchar(255),
+    "target_ss_id" text,
+    "reason" varchar(32),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_auth_logs_reason" ON "auth_logs" ("reason");
+CREATE INDEX "idx_auth_logs_success" ON "auth_logs" ("success");
+CREATE INDEX "idx_auth_logs_username" ON "auth_logs" ("username");
+CREATE INDEX "idx_auth_logs_device_mac_created_id" ON "auth_logs" ("device_mac","created_at","id");
+CREATE INDEX "idx_auth_logs_username_created_id" ON "auth_logs" ("username","created_at","id");
+CREATE INDEX "idx_auth_logs_created_id" ON "auth_logs" ("created_at","id");
+
+CREATE TABLE "guest_requests" (
+    "id" bigserial,
+    "username" varchar(50) NOT NULL,
+    "email" text NOT NULL,
+    "full_name" text,
+    "reason" text,
+    "sponsor_id" bigint NOT NULL,
+    "status" varchar(20) NOT NULL DEFAULT 'pending',
+    "guest_user_id" bigint,
+    "expires_at" timestamptz,
+    "decided_at" timestamptz,
+    "decision_note" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_guest_requests_status" ON "guest_requests" ("status");
+CREATE INDEX "idx_guest_requests_sponsor_id" ON "guest_requests" ("sponsor_id");
+CREATE INDEX "idx_guest_requests_username" ON "guest_requests" ("username");
+
+CREATE TABLE "audit_logs" (
+    "id" bigserial,
+    "actor_id" bigint,
+    "actor_name" text,
+    "action" varchar(64) NOT NULL,
+    "target_type" varchar(32),
+    "target_id" bigint,
+    "detail" text,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_audit_logs_target_id" ON "audit_logs" ("target_id");
+CREATE INDEX "idx_audit_logs_action" ON "audit_logs" ("action");
+CREATE INDEX "idx_audit_logs_actor_id" ON "audit_logs" ("actor_id");
+
+CREATE TABLE "user_mfa" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "secret" text NOT NULL,
+    "enabled" boolean DEFAULT false,
+    "last_used_step" bigint NOT NULL DEFAULT 0,
+    "enabled_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_user_mfa_user_id" ON "user_mfa" ("user_id");
+
+CREATE TABLE "mfa_backup_codes" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "code_hash" varchar(64) NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_mfa_backup_codes_user_id" ON "mfa_backup_codes" ("user_id");
+
+CREATE TABLE "webauthn_credentials" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "credential_id" varchar(255) NOT NULL,
+    "name" text,
+    "data" text NOT NULL,
+    "last_used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_webauthn_credentials_credential_id" ON "webauthn_credentials" ("credential_id");
+CREATE INDEX "idx_webauthn_credentials_user_id" ON "webauthn_credentials" ("user_id");
+
+CREATE TABLE "password_histories" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "password" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_password_histories_user_id" ON "password_histories" ("user_id");
+
+CREATE TABLE "password_reset_tokens" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "token_hash" varchar(64) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "request_ip" varchar(64),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX "idx_password_reset_tokens_token_hash" ON "password_reset_tokens" ("token_hash");
+CREATE INDEX "idx_password_reset_tokens_user_id" ON "password_reset_tokens" ("user_id");
+
+CREATE TABLE "retention_runs" (
+    "id" bigserial,
+    "trigger" varchar(16) NOT NULL,
+    "status" varchar(16) NOT NULL,
+    "archived" bigint,
+    "deleted" bigint,
+    "files" bigint,
+    "error" varchar(1024),
+    "started_at" timestamptz,
+    "finished_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_retention_runs_started_at" ON "retention_runs" ("started_at");
+
+CREATE TABLE "auth_log_rollups" (
+    "id" bigserial,
+    "period" varchar(8) NOT NULL,
+    "bucket_start" timestamptz NOT NULL,
+    "username" varchar(191) NOT NULL,
+    "ip_address" varchar(64) NOT NULL,
+    "target_ssid" varchar(128) NOT NULL,
+    "auth_type" varchar(16) NOT NULL,
+    "success" boolean NOT NULL,
+    "reason" varchar(32) NOT NULL,
+    "count" bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_auth_log_rollups_username" ON "auth_log_rollups" ("username");
+CREATE UNIQUE INDEX "idx_auth_log_rollups_key" ON "auth_log_rollups" ("period","bucket_start","username","ip_address","target_ssid","auth_type","success","reason");
+
+CREATE TABLE "auth_stats_state" (
+    "id" bigserial,
+    "last_log_id" bigint NOT NULL DEFAULT 0,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
@@ -0,0 +1,19 @@
+CREATE TABLE "settings" (
+    "setting_key" varchar(128),
+    "value" text NOT NULL,
+    "updated_by" varchar(255),
+    "updated_at" timestamptz,
+    PRIMARY KEY ("setting_key")
+);
+
+CREATE TABLE "setting_changes" (
+    "id" bigserial,
+    "setting_key" varchar(128) NOT NULL,
+    "old_value" text,
+    "new_value" text,
+    "actor_id" bigint,
+    "actor_name" varchar(255),
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_setting_changes_setting_key" ON "setting_changes" ("setting_key");
@@ -0,0 +1,184 @@
+-- 版本化迁移之前由 AutoMigrate 创建的表结构
+CREATE TABLE `users` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `username` text NOT NULL,
+    `password` text NOT NULL,
+    `salt` text NOT NULL,
+    `is_admin` numeric DEFAULT false,
+    `email` text,
+    `banned` numeric DEFAULT false,
+    `require_mfa` numeric DEFAULT false,
+    `status` text NOT NULL DEFAULT "active",
+    `expires_at` datetime,
+    `sponsor_id` integer,
+    `password_changed_at` datetime,
+    `must_change_password` numeric DEFAULT false,
+    `auth_source` text NOT NULL DEFAULT "local",
+    `external_id` text,
+    `last_login_at` datetime,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `uni_users_username` UNIQUE (`username`),
+    CONSTRAINT `uni_users_email` UNIQUE (`email`)
+);
+CREATE INDEX `idx_users_last_login_at` ON `users`(`last_login_at`);
+CREATE UNIQUE INDEX `idx_users_external_id` ON `users`(`external_id`);
+CREATE INDEX `idx_users_auth_source` ON `users`(`auth_source`);
+CREATE INDEX `idx_users_expires_at` ON `users`(`expires_at`);
+CREATE INDEX `idx_users_status` ON `users`(`status`);
+
+CREATE TABLE `radius_groups` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `description` text,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `uni_radius_groups_name` UNIQUE (`name`)
+);
+
+CREATE TABLE `user_groups` (
+    `user_id` integer,
+    `group_id` integer,
+    PRIMARY KEY (`user_id`,`group_id`),
+    CONSTRAINT `fk_user_groups_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`),
+    CONSTRAINT `fk_user_groups_group` FOREIGN KEY (`group_id`) REFERENCES `radius_groups`(`id`)
+);
+
+CREATE TABLE `auth_logs` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `username` text NOT NULL,
+    `auth_type` text NOT NULL,
+    `success` numeric NOT NULL,
+    `ip_address` text NOT NULL,
+    `user_agent` text,
+    `device_mac` text,
+    `target_ss_id` text,
+    `reason` text,
+    `created_at` datetime
+);
+CREATE INDEX `idx_auth_logs_reason` ON `auth_logs`(`reason`);
+CREATE INDEX `idx_auth_logs_success` ON `auth_logs`(`success`);
+CREATE INDEX `idx_auth_logs_username` ON `auth_logs`(`username`);
+CREATE INDEX `idx_auth_logs_device_mac_created_id` ON `auth_logs`(`device_mac`,`created_at`,`id`);
+CREATE INDEX `idx_auth_logs_username_created_id` ON `auth_logs`(`username`,`created_at`,`id`);
+CREATE INDEX `idx_auth_logs_created_id` ON `auth_logs`(`created_at`,`id`);
+
+CREATE TABLE `guest_requests` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `username` text NOT NULL,
+    `email` text NOT NULL,
+    `full_name` text,
+    `reason` text,
+    `sponsor_id` integer NOT NULL,
+    `status` text NOT NULL DEFAULT "pending",
+    `guest_user_id` integer,
+    `expires_at` datetime,
+    `decided_at` datetime,
+    `decision_note` text,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE INDEX `idx_guest_requests_status` ON `guest_requests`(`status`);
+CREATE INDEX `idx_guest_requests_sponsor_id` ON `guest_requests`(`sponsor_id`);
+CREATE INDEX `idx_guest_requests_username` ON `guest_requests`(`username`);
+
+CREATE TABLE `audit_logs` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `actor_id` integer,
+    `actor_name` text,
+    `action` text NOT NULL,
+    `target_type` text,
+    `target_id` integer,
+    `detail` text,
+    `created_at` datetime
+);
+CREATE INDEX `idx_audit_logs_target_id` ON `audit_logs`(`target_id`);
+CREATE INDEX `idx_audit_logs_action` ON `audit_logs`(`action`);
+CREATE INDEX `idx_audit_logs_actor_id` ON `audit_logs`(`actor_id`);
+
+CREATE TABLE `user_mfa` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `secret` text NOT NULL,
+    `enabled` numeric DEFAULT false,
+    `last_used_step` integer NOT NULL DEFAULT 0,
+    `enabled_at` datetime,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX `idx_user_mfa_user_id` ON `user_mfa`(`user_id`);
+
+CREATE TABLE `mfa_backup_codes` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `code_hash` text NOT NULL,
+    `used_at` datetime,
+    `created_at` datetime
+);
+CREATE INDEX `idx_mfa_backup_codes_user_id` ON `mfa_backup_codes`(`user_id`);
+
+CREATE TABLE `webauthn_credentials` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `credential_id` text NOT NULL,
+    `name` text,
+    `data` text NOT NULL,
+    `last_used_at` datetime,
+    `created_at` datetime
+);
+CREATE UNIQUE INDEX `idx_webauthn_credentials_credential_id` ON `webauthn_credentials`(`credential_id`);
+CREATE INDEX `idx_webauthn_credentials_user_id` ON `webauthn_credentials`(`user_id`);
+
+CREATE TABLE `password_histories` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `password` text NOT NULL,
+    `created_at` datetime
+);
+CREATE INDEX `idx_password_histories_user_id` ON `password_histories`(`user_id`);
+
+CREATE TABLE `password_reset_tokens` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `token_hash` text NOT NULL,
+    `expires_at` datetime NOT NULL,
+    `used_at` datetime,
+    `request_ip` text,
+    `created_at` datetime
+);
+CREATE UNIQUE INDEX `idx_password_reset_tokens_token_hash` ON `password_reset_tokens`(`token_hash`);
+CREATE INDEX `idx_password_reset_tokens_user_id` ON `password_reset_tokens`(`user_id`);
+
+CREATE TABLE `retention_runs` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `trigger` text NOT NULL,
+    `status` text NOT NULL,
+    `archived` integer,
+    `deleted` integer,
+    `files` integer,
+    `error` text,
+    `started_at` datetime,
+    `finished_at` datetime
+);
+CREATE INDEX `idx_retention_runs_started_at` ON `retention_runs`(`started_at`);
+
+CREATE TABLE `auth_log_rollups` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `period` text NOT NULL,
+    `bucket_start` datetime NOT NULL,
+    `username` text NOT NULL,
+    `ip_address` text NOT NULL,
+    `target_ssid` text NOT NULL,
+    `auth_type` text NOT NULL,
+    `success` numeric NOT NULL,
+    `reason` text NOT NULL,
+    `count` integer NOT NULL DEFAULT 0
+);
+CREATE INDEX `idx_auth_log_rollups_username` ON `auth_log_rollups`(`username`);
+CREATE UNIQUE INDEX `idx_auth_log_rollups_key` ON `auth_log_rollups`(`period`,`bucket_start`,`username`,`ip_address`,`target_ssid`,`auth_type`,`success`,`reason`);
+
+CREATE TABLE `auth_stats_state` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `last_log_id` integer NOT NULL DEFAULT 0,
+    `updated_at` datetime
+);
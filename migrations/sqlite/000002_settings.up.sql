@@ -0,0 +1,18 @@
+CREATE TABLE `settings` (
+    `setting_key` text,
+    `value` text NOT NULL,
+    `updated_by` text,
+    `updated_at` datetime,
+    PRIMARY KEY (`setting_key`)
+);
+
+CREATE TABLE `setting_changes` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `setting_key` text NOT NULL,
+    `old_value` text,
+    `new_value` text,
+    `actor_id` integer,
+    `actor_name` text,
+    `created_at` datetime
+);
+CREATE INDEX `idx_setting_changes_setting_key` ON `setting_changes`(`setting_key`);
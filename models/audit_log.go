@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AuditLog 记录管理类操作，例如担保人批准访客
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	ActorID    *uint     `json:"actor_id" gorm:"index"`
+	ActorName  string    `json:"actor_name"`
+	Action     string    `json:"action" gorm:"size:64;not null;index"`
+	TargetType string    `json:"target_type" gorm:"size:32"`
+	TargetID   uint      `json:"target_id" gorm:"index"`
+	Detail     string    `json:"detail" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
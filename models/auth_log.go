@@ -6,18 +6,31 @@ import (
 	"gorm.io/gorm"
 )
 
+// 复合索引都以 created_at, id 结尾，与列表的 ORDER BY created_at DESC, id DESC 和游标分页条件一致
 type AuthLog struct {
-	ID         uint      `json:"id" gorm:"primarykey"`
-	Username   string    `json:"username" gorm:"not null;index"`
+	ID         uint      `json:"id" gorm:"primarykey;index:idx_auth_logs_created_id,priority:2;index:idx_auth_logs_username_created_id,priority:3;index:idx_auth_logs_device_mac_created_id,priority:3"`
+	Username   string    `json:"username" gorm:"not null;index;index:idx_auth_logs_username_created_id,priority:1"`
 	AuthType   string    `json:"auth_type" gorm:"not null"` // "authorize" or "authenticate"
 	Success    bool      `json:"success" gorm:"not null;index"`
 	IPAddress  string    `json:"ip_address" gorm:"not null"`
 	UserAgent  string    `json:"user_agent"`
-	DeviceMAC  string    `json:"device_mac"`  // 设备 MAC 地址
-	TargetSSID string    `json:"target_ssid"` // 目标 SSID
-	CreatedAt  time.Time `json:"created_at"`
+	DeviceMAC  string    `json:"device_mac" gorm:"size:255;index:idx_auth_logs_device_mac_created_id,priority:1"` // 设备 MAC 地址
+	TargetSSID string    `json:"target_ssid"`                                                                     // 目标 SSID
+	Reason     string    `json:"reason" gorm:"size:32;index"`                                                     // 失败原因，成功时为空
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_auth_logs_created_id,priority:1;index:idx_auth_logs_username_created_id,priority:2;index:idx_auth_logs_device_mac_created_id,priority:2"`
 }
 
+// 认证失败原因
+const (
+	AuthReasonUnknownUser      = "unknown_user"
+	AuthReasonInvalidPassword  = "invalid_password"
+	AuthReasonChallengeExpired = "challenge_expired"
+	AuthReasonMFANotEnrolled   = "mfa_not_enrolled"
+	AuthReasonMFACodeRequired  = "mfa_code_required"
+	AuthReasonInvalidMFACode   = "invalid_mfa_code"
+	AuthReasonPasswordExpired  = "password_expired"
+)
+
 func (AuthLog) TableName() string {
 	return "auth_logs"
 }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// 汇总粒度
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// AuthLogRollup 按小时、按天（UTC）汇总的认证次数，由后台任务从 auth_logs 增量生成，
+// 删除或归档原始日志不影响汇总结果
+type AuthLogRollup struct {
+	ID          uint      `json:"-" gorm:"primarykey"`
+	Period      string    `json:"period" gorm:"size:8;not null;uniqueIndex:idx_auth_log_rollups_key,priority:1"`
+	BucketStart time.Time `json:"bucket_start" gorm:"not null;uniqueIndex:idx_auth_log_rollups_key,priority:2"`
+	Username    string    `json:"username" gorm:"size:191;not null;uniqueIndex:idx_auth_log_rollups_key,priority:3;index:idx_auth_log_rollups_username"`
+	IPAddress   string    `json:"ip_address" gorm:"size:64;not null;uniqueIndex:idx_auth_log_rollups_key,priority:4"`
+	TargetSSID  string    `json:"target_ssid" gorm:"column:target_ssid;size:128;not null;uniqueIndex:idx_auth_log_rollups_key,priority:5"`
+	AuthType    string    `json:"auth_type" gorm:"size:16;not null;uniqueIndex:idx_auth_log_rollups_key,priority:6"`
+	Success     bool      `json:"success" gorm:"not null;uniqueIndex:idx_auth_log_rollups_key,priority:7"`
+	Reason      string    `json:"reason" gorm:"size:32;not null;uniqueIndex:idx_auth_log_rollups_key,priority:8"`
+	Count       int64     `json:"count" gorm:"not null;default:0"`
+}
+
+func (AuthLogRollup) TableName() string {
+	return "auth_log_rollups"
+}
+
+// AuthStatsState 汇总进度，只有一行。LastLogID 之前（含）的日志都已计入汇总
+type AuthStatsState struct {
+	ID        uint `gorm:"primarykey"`
+	LastLogID uint `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+func (AuthStatsState) TableName() string {
+	return "auth_stats_state"
+}
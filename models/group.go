@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Group 用户组，表名避开 MySQL 8 的保留字 GROUPS
+type Group struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	Name        string    `json:"name" gorm:"size:100;unique;not null"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Group) TableName() string {
+	return "radius_groups"
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// 访客申请状态
+const (
+	GuestRequestPending  = "pending"
+	GuestRequestApproved = "approved"
+	GuestRequestRejected = "rejected"
+	GuestRequestExpired  = "expired"
+	GuestRequestRevoked  = "revoked"
+)
+
+// GuestRequest 访客向员工（担保人）提交的访问申请
+type GuestRequest struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	Username     string     `json:"username" gorm:"size:50;not null;index"`
+	Email        string     `json:"email" gorm:"not null"`
+	FullName     string     `json:"full_name"`
+	Reason       string     `json:"reason"`
+	SponsorID    uint       `json:"sponsor_id" gorm:"not null;index"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:pending;index"`
+	GuestUserID  *uint      `json:"guest_user_id"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	DecidedAt    *time.Time `json:"decided_at"`
+	DecisionNote string     `json:"decision_note"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (GuestRequest) TableName() string {
+	return "guest_requests"
+}
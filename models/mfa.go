@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// UserMFA 用户的 TOTP 第二因素
+type UserMFA struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	UserID       uint       `json:"user_id" gorm:"uniqueIndex;not null"`
+	Secret       string     `json:"-" gorm:"not null"`
+	Enabled      bool       `json:"enabled" gorm:"default:false"`
+	LastUsedStep int64      `json:"-" gorm:"not null;default:0"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// MFABackupCode 一次性备用码，只保存哈希
+type MFABackupCode struct {
+	ID        uint       `json:"id" gorm:"primarykey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (MFABackupCode) TableName() string {
+	return "mfa_backup_codes"
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// PasswordHistory 保存用户用过的密码哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Password  string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	RetentionRunning   = "running"
+	RetentionCompleted = "completed"
+	RetentionFailed    = "failed"
+)
+
+// RetentionRun 认证日志保留任务的一次执行记录，定时任务与 admin_tool 共用
+type RetentionRun struct {
+	ID         uint       `json:"id" gorm:"primarykey"`
+	Trigger    string     `json:"trigger" gorm:"size:16;not null"` // "schedule" 或 "cli"
+	Status     string     `json:"status" gorm:"size:16;not null"`
+	Archived   int64      `json:"archived"`
+	Deleted    int64      `json:"deleted"`
+	Files      int        `json:"files"`
+	Error      string     `json:"error,omitempty" gorm:"size:1024"`
+	StartedAt  time.Time  `json:"started_at" gorm:"index"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (RetentionRun) TableName() string {
+	return "retention_runs"
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Setting 管理员在运行时修改过的设置项，表中没有的键使用默认值。
+// 列名不用 key，它在 MySQL 中是保留字
+type Setting struct {
+	Key       string    `json:"key" gorm:"column:setting_key;primaryKey;size:128"`
+	Value     string    `json:"value" gorm:"type:text;not null"`
+	UpdatedBy string    `json:"updated_by" gorm:"size:255"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Setting) TableName() string {
+	return "settings"
+}
+
+// SettingChange 设置项的修改历史，OldValue / NewValue 为空表示修改前 / 后使用默认值
+type SettingChange struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Key       string    `json:"key" gorm:"column:setting_key;size:128;not null;index"`
+	OldValue  *string   `json:"old_value" gorm:"type:text"`
+	NewValue  *string   `json:"new_value" gorm:"type:text"`
+	ActorID   *uint     `json:"actor_id"`
+	ActorName string    `json:"actor_name" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (SettingChange) TableName() string {
+	return "setting_changes"
+}
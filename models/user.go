@@ -1,80 +1,170 @@
 package models
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
 	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/Gaojianli/raduis_mgnt/passhash"
+)
+
+// 账号来源，外部目录的账号在本地只保留影子记录，不保存密码
+const (
+	AuthSourceLocal = "local"
+	AuthSourceLDAP  = "ldap"
+	AuthSourceOIDC  = "oidc"
+)
+
+// 用户状态
+const (
+	UserStatusActive   = "active"
+	UserStatusPending  = "pending"
+	UserStatusRejected = "rejected"
+	UserStatusExpired  = "expired"
 )
 
 type User struct {
-	ID        uint      `json:"id" gorm:"primarykey"`
-	Username  string    `json:"username" gorm:"unique;not null"`
-	Password  string    `json:"-" gorm:"not null"`
-	Salt      string    `json:"-" gorm:"not null"`
-	IsAdmin   bool      `json:"is_admin" gorm:"default:false"`
-	Email     string    `json:"email" gorm:"unique"`
-	Banned    bool      `json:"banned" gorm:"default:false"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uint   `json:"id" gorm:"primarykey"`
+	Username string `json:"username" gorm:"unique;not null"`
+	Password string `json:"-" gorm:"not null"`
+	Salt     string `json:"-" gorm:"not null"`
+	IsAdmin  bool   `json:"is_admin" gorm:"default:false"`
+	Email    string `json:"email" gorm:"unique"`
+	Banned   bool   `json:"banned" gorm:"default:false"`
+	// RequireMFA 强制该账号在 RADIUS 认证时提供 TOTP 验证码
+	RequireMFA bool       `json:"require_mfa" gorm:"default:false"`
+	Status     string     `json:"status" gorm:"size:20;not null;default:active;index"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"index"`
+	SponsorID  *uint      `json:"sponsor_id"`
+	Groups     []Group    `json:"-" gorm:"many2many:user_groups"`
+	// PasswordChangedAt 为空时按 CreatedAt 计算密码有效期
+	PasswordChangedAt  *time.Time `json:"password_changed_at"`
+	MustChangePassword bool       `json:"must_change_password" gorm:"default:false"`
+	AuthSource         string     `json:"auth_source" gorm:"size:32;not null;default:local;index"`
+	// ExternalID 由 SCIM 客户端（如 HR 系统）分配的标识，为空时存 NULL 以免触发唯一约束
+	ExternalID *string `json:"external_id,omitempty" gorm:"size:255;uniqueIndex"`
+	// LastLoginAt 最近一次成功登录 Web 或通过 RADIUS 认证的时间
+	LastLoginAt *time.Time `json:"last_login_at" gorm:"index"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// preHashed Password 已是哈希值，创建时无需再次哈希
+	preHashed bool
+	rehashed  bool
 }
 
-func (u *User) generateSalt() (string, error) {
-	saltBytes := make([]byte, 32)
-	_, err := rand.Read(saltBytes)
-	if err != nil {
-		return "", err
-	}
-	return hex.EncodeToString(saltBytes), nil
+// IsLocal 密码由本系统管理
+func (u *User) IsLocal() bool {
+	return u.AuthSource == "" || u.AuthSource == AuthSourceLocal
+}
+
+// IsExpired 有效期已过的账号（如访客）不再允许认证
+func (u *User) IsExpired() bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(time.Now())
 }
 
+// HashPassword 使用当前默认方案生成密码哈希，旧版的独立盐列随之清空
 func (u *User) HashPassword(password string) error {
-	salt, err := u.generateSalt()
+	encoded, err := passhash.Hash(password)
 	if err != nil {
 		return err
 	}
-	u.Salt = salt
+	u.Password = encoded
+	u.Salt = ""
+	u.preHashed = true
+	return nil
+}
 
-	h := sha256.New()
-	h.Write([]byte(password + salt))
-	u.Password = hex.EncodeToString(h.Sum(nil))
+// SetPasswordHash 直接设置从其他系统导入的哈希，格式须能被某个已注册方案识别
+func (u *User) SetPasswordHash(encoded string) error {
+	if _, ok := passhash.Identify(encoded); !ok {
+		return passhash.ErrUnknownScheme
+	}
+	u.Password = encoded
+	u.Salt = ""
+	u.preHashed = true
 	return nil
 }
 
+// PasswordHash 返回可被 passhash 识别的完整哈希，旧版格式会合并独立的盐列
+func (u *User) PasswordHash() string {
+	if u.Salt != "" {
+		return passhash.EncodeSaltedSHA256(u.Password, u.Salt)
+	}
+	return u.Password
+}
+
+// CheckPassword 校验密码，若哈希方案或参数已过时则在内存中重新哈希，
+// 调用方通过 PasswordRehashed 判断是否需要持久化
 func (u *User) CheckPassword(password string) bool {
-	h := sha256.New()
-	h.Write([]byte(password + u.Salt))
-	hashedPassword := hex.EncodeToString(h.Sum(nil))
-	return hashedPassword == u.Password
+	ok, needsRehash, err := passhash.Verify(password, u.PasswordHash())
+	if err != nil || !ok {
+		return false
+	}
+
+	if needsRehash && u.HashPassword(password) == nil {
+		u.rehashed = true
+	}
+	return true
+}
+
+// PasswordRehashed 上次 CheckPassword 是否升级了密码哈希
+func (u *User) PasswordRehashed() bool {
+	return u.rehashed
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	if u.Password != "" {
+	if u.PasswordChangedAt == nil {
+		now := time.Now()
+		u.PasswordChangedAt = &now
+	}
+	if u.Password != "" && !u.preHashed {
 		return u.HashPassword(u.Password)
 	}
 	return nil
 }
 
 type UserResponse struct {
-	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	IsAdmin   bool      `json:"is_admin"`
-	Banned    bool      `json:"banned"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 uint       `json:"id"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	IsAdmin            bool       `json:"is_admin"`
+	Banned             bool       `json:"banned"`
+	RequireMFA         bool       `json:"require_mfa"`
+	Status             string     `json:"status"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	SponsorID          *uint      `json:"sponsor_id,omitempty"`
+	Groups             []string   `json:"groups,omitempty"`
+	MustChangePassword bool       `json:"must_change_password"`
+	AuthSource         string     `json:"auth_source"`
+	ExternalID         *string    `json:"external_id,omitempty"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 func (u *User) ToResponse() UserResponse {
+	var groups []string
+	for _, group := range u.Groups {
+		groups = append(groups, group.Name)
+	}
+
 	return UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Email:     u.Email,
-		IsAdmin:   u.IsAdmin,
-		Banned:    u.Banned,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:                 u.ID,
+		Username:           u.Username,
+		Email:              u.Email,
+		IsAdmin:            u.IsAdmin,
+		Banned:             u.Banned,
+		RequireMFA:         u.RequireMFA,
+		Status:             u.Status,
+		ExpiresAt:          u.ExpiresAt,
+		SponsorID:          u.SponsorID,
+		Groups:             groups,
+		MustChangePassword: u.MustChangePassword,
+		AuthSource:         u.AuthSource,
+		ExternalID:         u.ExternalID,
+		LastLoginAt:        u.LastLoginAt,
+		CreatedAt:          u.CreatedAt,
+		UpdatedAt:          u.UpdatedAt,
 	}
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// WebAuthnCredential 用户注册的安全密钥，Data 保存序列化后的完整凭据
+type WebAuthnCredential struct {
+	ID           uint       `json:"id" gorm:"primarykey"`
+	UserID       uint       `json:"user_id" gorm:"index;not null"`
+	CredentialID string     `json:"-" gorm:"size:255;uniqueIndex;not null"`
+	Name         string     `json:"name"`
+	Data         string     `json:"-" gorm:"type:text;not null"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
@@ -0,0 +1,284 @@
+package passhash
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// 以下为 glibc crypt(3) 的 SHA-crypt（$5$/$6$）与 MD5-crypt（$1$）实现，仅用于校验导入的哈希
+
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+const (
+	shaCryptDefaultRounds = 5000
+	shaCryptMinRounds     = 1000
+	shaCryptMaxRounds     = 999999999
+)
+
+var errInvalidCryptHash = errors.New("invalid crypt hash")
+
+// 按 crypt 规范的字节顺序输出 SHA-256/SHA-512 摘要
+var (
+	sha256CryptOrder = [][3]int{
+		{0, 10, 20}, {21, 1, 11}, {12, 22, 2}, {3, 13, 23}, {24, 4, 14},
+		{15, 25, 5}, {6, 16, 26}, {27, 7, 17}, {18, 28, 8}, {9, 19, 29},
+	}
+	sha512CryptOrder = [][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+		{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41},
+	}
+	md5CryptOrder = [][3]int{
+		{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5},
+	}
+)
+
+func cryptEncode(buf []byte, b2, b1, b0 byte, n int) []byte {
+	w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+	for ; n > 0; n-- {
+		buf = append(buf, cryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+	return buf
+}
+
+func cryptEncodeDigest(sum []byte, order [][3]int) []byte {
+	var buf []byte
+	for _, g := range order {
+		buf = cryptEncode(buf, sum[g[0]], sum[g[1]], sum[g[2]], 4)
+	}
+	switch len(sum) {
+	case sha512.Size:
+		buf = cryptEncode(buf, 0, 0, sum[63], 2)
+	case sha256.Size:
+		buf = cryptEncode(buf, 0, sum[31], sum[30], 3)
+	case md5.Size:
+		buf = cryptEncode(buf, 0, 0, sum[11], 2)
+	}
+	return buf
+}
+
+// repeatDigest 将摘要重复写入直至 n 字节
+func repeatDigest(h hash.Hash, digest []byte, n int) {
+	for ; n > len(digest); n -= len(digest) {
+		h.Write(digest)
+	}
+	h.Write(digest[:n])
+}
+
+type shaCryptScheme struct {
+	sha512 bool
+}
+
+func (shaCryptScheme) verifyOnly() {}
+
+func (s shaCryptScheme) Name() string {
+	if s.sha512 {
+		return "sha512-crypt"
+	}
+	return "sha256-crypt"
+}
+
+func (s shaCryptScheme) magic() string {
+	if s.sha512 {
+		return "$6$"
+	}
+	return "$5$"
+}
+
+func (s shaCryptScheme) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, s.magic())
+}
+
+func (shaCryptScheme) Hash(string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (s shaCryptScheme) Verify(password, encoded string) (bool, error) {
+	rest := strings.TrimPrefix(encoded, s.magic())
+
+	rounds, customRounds := shaCryptDefaultRounds, false
+	if strings.HasPrefix(rest, "rounds=") {
+		idx := strings.IndexByte(rest, '$')
+		if idx < 0 {
+			return false, errInvalidCryptHash
+		}
+		n, err := strconv.Atoi(rest[len("rounds="):idx])
+		if err != nil {
+			return false, errInvalidCryptHash
+		}
+		rounds, customRounds = min(max(n, shaCryptMinRounds), shaCryptMaxRounds), true
+		rest = rest[idx+1:]
+	}
+
+	idx := strings.LastIndexByte(rest, '$')
+	if idx < 0 {
+		return false, errInvalidCryptHash
+	}
+	salt := rest[:idx]
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+
+	computed := s.crypt([]byte(password), []byte(salt), rounds, customRounds)
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+}
+
+func (shaCryptScheme) NeedsRehash(string) bool { return true }
+
+func (s shaCryptScheme) crypt(password, salt []byte, rounds int, customRounds bool) string {
+	newHash, order := sha256.New, sha256CryptOrder
+	if s.sha512 {
+		newHash, order = sha512.New, sha512CryptOrder
+	}
+
+	b := newHash()
+	b.Write(password)
+	b.Write(salt)
+	b.Write(password)
+	digestB := b.Sum(nil)
+
+	a := newHash()
+	a.Write(password)
+	a.Write(salt)
+	repeatDigest(a, digestB, len(password))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(digestB)
+		} else {
+			a.Write(password)
+		}
+	}
+	digestA := a.Sum(nil)
+
+	dp := newHash()
+	for range password {
+		dp.Write(password)
+	}
+	p := make([]byte, 0, len(password))
+	for digestP := dp.Sum(nil); len(p) < len(password); {
+		p = append(p, digestP[:min(len(digestP), len(password)-len(p))]...)
+	}
+
+	ds := newHash()
+	for i := 0; i < 16+int(digestA[0]); i++ {
+		ds.Write(salt)
+	}
+	saltDigest := ds.Sum(nil)[:len(salt)]
+
+	c := digestA
+	for i := 0; i < rounds; i++ {
+		h := newHash()
+		if i&1 != 0 {
+			h.Write(p)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(saltDigest)
+		}
+		if i%7 != 0 {
+			h.Write(p)
+		}
+		if i&1 != 0 {
+			h.Write(c)
+		} else {
+			h.Write(p)
+		}
+		c = h.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString(s.magic())
+	if customRounds {
+		out.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	out.Write(salt)
+	out.WriteByte('$')
+	out.Write(cryptEncodeDigest(c, order))
+	return out.String()
+}
+
+type md5CryptScheme struct{}
+
+func (md5CryptScheme) verifyOnly() {}
+
+func (md5CryptScheme) Name() string { return "md5-crypt" }
+
+func (md5CryptScheme) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$1$")
+}
+
+func (md5CryptScheme) Hash(string) (string, error) {
+	return "", ErrVerifyOnly
+}
+
+func (md5CryptScheme) Verify(password, encoded string) (bool, error) {
+	rest := strings.TrimPrefix(encoded, "$1$")
+	idx := strings.LastIndexByte(rest, '$')
+	if idx < 0 {
+		return false, errInvalidCryptHash
+	}
+	salt := rest[:idx]
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	computed := md5Crypt([]byte(password), []byte(salt))
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encoded)) == 1, nil
+}
+
+func (md5CryptScheme) NeedsRehash(string) bool { return true }
+
+func md5Crypt(password, salt []byte) string {
+	alt := md5.New()
+	alt.Write(password)
+	alt.Write(salt)
+	alt.Write(password)
+	altDigest := alt.Sum(nil)
+
+	ctx := md5.New()
+	ctx.Write(password)
+	ctx.Write([]byte("$1$"))
+	ctx.Write(salt)
+	repeatDigest(ctx, altDigest, len(password))
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(password[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		h := md5.New()
+		if i&1 != 0 {
+			h.Write(password)
+		} else {
+			h.Write(final)
+		}
+		if i%3 != 0 {
+			h.Write(salt)
+		}
+		if i%7 != 0 {
+			h.Write(password)
+		}
+		if i&1 != 0 {
+			h.Write(final)
+		} else {
+			h.Write(password)
+		}
+		final = h.Sum(nil)
+	}
+
+	return "$1$" + string(salt) + "$" + string(cryptEncodeDigest(final, md5CryptOrder))
+}
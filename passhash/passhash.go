@@ -0,0 +1,147 @@
+// Package passhash 提供可插拔的密码哈希方案注册表。
+// 新密码使用默认方案（argon2id 或 bcrypt）生成，旧系统导入的哈希由对应的校验器识别，
+// 校验成功后调用方可据 needsRehash 将其升级为当前默认方案。
+package passhash
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+var (
+	ErrUnknownScheme = errors.New("unknown password hash scheme")
+	ErrVerifyOnly    = errors.New("password hash scheme is verify-only")
+)
+
+// Scheme 一种密码哈希格式
+type Scheme interface {
+	// Name 方案名，用于配置 PASSWORD_HASH_SCHEME
+	Name() string
+	// Identify 判断编码后的哈希是否属于本方案
+	Identify(encoded string) bool
+	// Hash 生成新的哈希，仅支持校验的旧方案返回 ErrVerifyOnly
+	Hash(password string) (string, error)
+	// Verify 校验密码
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash 哈希参数低于当前配置时返回 true
+	NeedsRehash(encoded string) bool
+}
+
+// verifyOnly 标记仅用于导入校验、不能作为默认方案的旧格式
+type verifyOnly interface {
+	verifyOnly()
+}
+
+var (
+	mu            sync.RWMutex
+	schemes       []Scheme
+	defaultScheme Scheme
+)
+
+func init() {
+	Register(NewArgon2id(DefaultArgon2Params))
+	Register(NewBcrypt(DefaultBcryptCost))
+	Register(shaCryptScheme{sha512: true})
+	Register(shaCryptScheme{sha512: false})
+	Register(md5CryptScheme{})
+	Register(sha1Scheme{})
+	Register(ntlmScheme{})
+	Register(saltedSHA256Scheme{})
+	defaultScheme = schemes[0]
+}
+
+// Register 注册方案，同名方案会被替换
+func Register(s Scheme) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for i, existing := range schemes {
+		if existing.Name() == s.Name() {
+			schemes[i] = s
+			if defaultScheme != nil && defaultScheme.Name() == s.Name() {
+				defaultScheme = s
+			}
+			return
+		}
+	}
+	schemes = append(schemes, s)
+}
+
+// SetDefault 设置生成新哈希使用的方案
+func SetDefault(name string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, s := range schemes {
+		if s.Name() != name {
+			continue
+		}
+		if _, ok := s.(verifyOnly); ok {
+			return fmt.Errorf("%w: %s", ErrVerifyOnly, name)
+		}
+		defaultScheme = s
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownScheme, name)
+}
+
+// Init 按配置设置默认方案及其参数
+func Init() error {
+	cfg := config.AppConfig
+	Register(NewArgon2id(Argon2Params{
+		Memory:  uint32(cfg.PasswordArgon2Memory),
+		Time:    uint32(cfg.PasswordArgon2Time),
+		Threads: uint8(cfg.PasswordArgon2Threads),
+	}))
+	Register(NewBcrypt(cfg.PasswordBcryptCost))
+	return SetDefault(cfg.PasswordHashScheme)
+}
+
+// Default 当前默认方案名
+func Default() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultScheme.Name()
+}
+
+// Hash 使用默认方案生成哈希
+func Hash(password string) (string, error) {
+	mu.RLock()
+	s := defaultScheme
+	mu.RUnlock()
+	return s.Hash(password)
+}
+
+// Identify 返回能识别该哈希的方案
+func Identify(encoded string) (Scheme, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, s := range schemes {
+		if s.Identify(encoded) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Verify 校验密码，needsRehash 表示哈希应升级为当前默认方案或参数
+func Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	s, found := Identify(encoded)
+	if !found {
+		return false, false, ErrUnknownScheme
+	}
+
+	ok, err = s.Verify(password, encoded)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	mu.RLock()
+	current := defaultScheme
+	mu.RUnlock()
+	return true, s.Name() != current.Name() || s.NeedsRehash(encoded), nil
+}
@@ -0,0 +1,228 @@
+// Package passpolicy 校验新密码是否符合长度、字符类别、泄露密码黑名单与历史密码要求，
+// 并计算密码有效期。
+package passpolicy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/passhash"
+)
+
+// 字符类别，对应 PASSWORD_REQUIRED_CLASSES 的取值
+const (
+	ClassUpper  = "upper"
+	ClassLower  = "lower"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+var classChecks = map[string]func(rune) bool{
+	ClassUpper: unicode.IsUpper,
+	ClassLower: unicode.IsLower,
+	ClassDigit: unicode.IsDigit,
+	ClassSymbol: func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	},
+}
+
+// ErrExternalAccount 外部目录账号的密码不由本系统管理
+var ErrExternalAccount = errors.New("password is managed by an external directory")
+
+// ViolationError 列出新密码未满足的全部规则
+type ViolationError struct {
+	Violations []string
+}
+
+func (e *ViolationError) Error() string {
+	return "password does not meet policy: " + strings.Join(e.Violations, "; ")
+}
+
+var (
+	blocklistMu sync.RWMutex
+	blocklist   map[string]struct{}
+)
+
+// Init 校验配置并加载泄露密码黑名单，文件每行一个密码，# 开头为注释
+func Init() error {
+	for _, class := range config.AppConfig.PasswordRequiredClasses {
+		if _, ok := classChecks[class]; !ok {
+			return fmt.Errorf("unknown password character class %q", class)
+		}
+	}
+
+	path := config.AppConfig.PasswordBlocklistFile
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open password blocklist: %w", err)
+	}
+	defer f.Close()
+
+	list := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read password blocklist: %w", err)
+	}
+
+	blocklistMu.Lock()
+	blocklist = list
+	blocklistMu.Unlock()
+	return nil
+}
+
+func isBlocklisted(password string) bool {
+	blocklistMu.RLock()
+	defer blocklistMu.RUnlock()
+	_, ok := blocklist[strings.ToLower(password)]
+	return ok
+}
+
+// Validate 检查密码本身的规则，username 用于禁止与用户名相同
+func Validate(username, password string) error {
+	var violations []string
+
+	if n := len([]rune(password)); n < config.AppConfig.PasswordMinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", config.AppConfig.PasswordMinLength))
+	}
+
+	for _, class := range config.AppConfig.PasswordRequiredClasses {
+		if !strings.ContainsFunc(password, classChecks[class]) {
+			violations = append(violations, "must contain a "+class+" character")
+		}
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		violations = append(violations, "must not match the username")
+	}
+
+	if isBlocklisted(password) {
+		violations = append(violations, "is a known breached password")
+	}
+
+	if len(violations) > 0 {
+		return &ViolationError{Violations: violations}
+	}
+	return nil
+}
+
+// checkReuse 新密码不能与当前密码及最近 PASSWORD_HISTORY_SIZE 个旧密码相同
+func checkReuse(ctx context.Context, user *models.User, password string) error {
+	size := config.AppConfig.PasswordHistorySize
+	if size <= 0 {
+		return nil
+	}
+
+	if ok, _, _ := passhash.Verify(password, user.PasswordHash()); ok {
+		return &ViolationError{Violations: []string{"must differ from the current password"}}
+	}
+
+	if size == 1 {
+		return nil
+	}
+
+	// 当前密码占用一个名额
+	history, err := database.DAO.PasswordHistory.ListRecent(ctx, user.ID, size-1)
+	if err != nil {
+		return err
+	}
+	for _, entry := range history {
+		if ok, _, _ := passhash.Verify(password, entry.Password); ok {
+			return &ViolationError{Violations: []string{fmt.Sprintf("must not reuse any of the last %d passwords", size)}}
+		}
+	}
+	return nil
+}
+
+// Check 校验密码规则与历史复用，不修改用户
+func Check(ctx context.Context, user *models.User, password string) error {
+	if !user.IsLocal() {
+		return ErrExternalAccount
+	}
+	if err := Validate(user.Username, password); err != nil {
+		return err
+	}
+	return checkReuse(ctx, user, password)
+}
+
+// SetPassword 按策略校验后为已有用户设置新密码并保存，mustChange 为 true 时要求用户下次登录修改
+func SetPassword(ctx context.Context, user *models.User, password string, mustChange bool) error {
+	if err := Check(ctx, user, password); err != nil {
+		return err
+	}
+
+	previous := user.PasswordHash()
+	if err := user.HashPassword(password); err != nil {
+		return err
+	}
+	now := time.Now()
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = mustChange
+
+	// 当前密码也计入历史，因此历史表只需保留 size-1 条
+	return database.DAO.User.ChangePassword(ctx, user, previous, config.AppConfig.PasswordHistorySize-1)
+}
+
+// ExpiresAt 返回密码过期时间，未配置 PASSWORD_MAX_AGE 时 ok 为 false
+func ExpiresAt(user *models.User) (expiresAt time.Time, ok bool) {
+	maxAge := config.AppConfig.PasswordMaxAge
+	if maxAge <= 0 || !user.IsLocal() {
+		return time.Time{}, false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	return changedAt.Add(maxAge), true
+}
+
+// IsExpired 密码已超过最长使用期限
+func IsExpired(user *models.User) bool {
+	expiresAt, ok := ExpiresAt(user)
+	return ok && !expiresAt.After(time.Now())
+}
+
+// ExpiryWarning 密码将在 PASSWORD_EXPIRY_WARNING 内过期时返回提示文本
+func ExpiryWarning(user *models.User) (string, bool) {
+	expiresAt, ok := ExpiresAt(user)
+	if !ok {
+		return "", false
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 || remaining > config.AppConfig.PasswordExpiryWarning {
+		return "", false
+	}
+
+	days := int(remaining.Hours() / 24)
+	if days < 1 {
+		return "Your password expires within a day, please change it", true
+	}
+	return fmt.Sprintf("Your password expires in %d days, please change it", days), true
+}
+
+// ChangeRequired Web 登录后必须先修改密码
+func ChangeRequired(user *models.User) bool {
+	return user.MustChangePassword || IsExpired(user)
+}
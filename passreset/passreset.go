@@ -0,0 +1,124 @@
+// Package passreset 签发与校验忘记密码流程的一次性重置令牌。
+// 令牌格式为 <user_id>.<过期时间戳>.<随机串>.<HMAC 签名>，签名使用 JWT 密钥派生的独立密钥；
+// 数据库只保存随机串的哈希，用于保证令牌只能使用一次。
+package passreset
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired password reset token")
+
+// Token 已通过签名与有效期校验、尚未消费的令牌
+type Token struct {
+	record *models.PasswordResetToken
+	User   *models.User
+}
+
+func signingKey() []byte {
+	sum := sha256.Sum256([]byte("password-reset:" + config.AppConfig.JWTSecret))
+	return sum[:]
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue 为用户签发新令牌，此前未使用的令牌随之作废
+func Issue(ctx context.Context, user *models.User, requestIP string) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := time.Now().Add(config.AppConfig.PasswordResetTTL)
+
+	if err := database.DAO.PasswordReset.InvalidateByUserID(ctx, user.ID); err != nil {
+		return "", err
+	}
+
+	record := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashNonce(nonce),
+		ExpiresAt: expiresAt,
+		RequestIP: requestIP,
+	}
+	if err := database.DAO.PasswordReset.Create(ctx, record); err != nil {
+		return "", err
+	}
+
+	payload := fmt.Sprintf("%d.%d.%s", user.ID, expiresAt.Unix(), nonce)
+	return payload + "." + sign(payload), nil
+}
+
+// Lookup 校验签名、有效期与使用状态，不消费令牌
+func Lookup(ctx context.Context, token string) (*Token, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return nil, ErrInvalidToken
+	}
+
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(sign(payload)), []byte(parts[3])) {
+		return nil, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return nil, ErrInvalidToken
+	}
+
+	record, err := database.DAO.PasswordReset.GetByTokenHash(ctx, hashNonce(parts[2]))
+	if err != nil || record.UserID != uint(userID) || record.UsedAt != nil || !record.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := database.DAO.User.GetByID(ctx, record.UserID)
+	if err != nil || !Eligible(user) {
+		return nil, ErrInvalidToken
+	}
+
+	return &Token{record: record, User: user}, nil
+}
+
+// Consume 原子地标记令牌已使用，并发请求中只有一个会成功
+func (t *Token) Consume(ctx context.Context) error {
+	ok, err := database.DAO.PasswordReset.MarkUsed(ctx, t.record.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidToken
+	}
+	return database.DAO.PasswordReset.InvalidateByUserID(ctx, t.User.ID)
+}
+
+// Eligible 只有密码由本系统管理、可正常登录且留有邮箱的账号可以自助重置密码
+func Eligible(user *models.User) bool {
+	return user.IsLocal() && user.Email != "" && !user.Banned && user.Status == models.UserStatusActive && !user.IsExpired()
+}
@@ -0,0 +1,83 @@
+package retention
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// Archiver 保存一批已压缩的归档数据，同名对象应被覆盖，重复归档同一批日志不会产生重复文件
+type Archiver interface {
+	Put(ctx context.Context, name string, data []byte) error
+	String() string
+}
+
+// archiveName 按第一条日志的日期分目录，文件名包含首尾 ID
+func archiveName(logs []models.AuthLog) string {
+	first, last := logs[0], logs[len(logs)-1]
+	day := first.CreatedAt.UTC()
+	return path.Join(day.Format("2006"), day.Format("01"), day.Format("02"),
+		fmt.Sprintf("auth-logs-%d-%d.ndjson.gz", first.ID, last.ID))
+}
+
+// encodeBatch 每行一个 JSON 对象，整体 gzip 压缩
+func encodeBatch(logs []models.AuthLog) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for i := range logs {
+		if err := encoder.Encode(&logs[i]); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type localArchiver struct {
+	dir string
+}
+
+// Put 先写临时文件再重命名，进程中途退出不会留下不完整的归档
+func (a *localArchiver) Put(ctx context.Context, name string, data []byte) error {
+	target := filepath.Join(a.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".auth-logs-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (a *localArchiver) String() string {
+	return "local:" + a.dir
+}
+
+// archiveTimeout 单个归档文件的上传超时
+const archiveTimeout = 2 * time.Minute
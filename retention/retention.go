@@ -0,0 +1,208 @@
+// Package retention 按最长保留时间或最大行数清理认证日志，删除前可归档为 gzip 压缩的 NDJSON 文件。
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerCLI      = "cli"
+)
+
+// 批次之间的间隔，给复制与其他写入留出空间
+const batchPause = 200 * time.Millisecond
+
+var (
+	ErrDisabled   = errors.New("auth log retention is not configured")
+	ErrInProgress = errors.New("auth log retention is already running")
+)
+
+// Policy 两个条件满足其一即清理，零值表示不限制
+type Policy struct {
+	MaxAge    time.Duration
+	MaxRows   int
+	BatchSize int
+}
+
+func (p Policy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxRows > 0
+}
+
+// ConfiguredPolicy 环境变量中配置的保留策略
+func ConfiguredPolicy() Policy {
+	return Policy{
+		MaxAge:    config.AppConfig.AuthLogMaxAge,
+		MaxRows:   config.AppConfig.AuthLogMaxRows,
+		BatchSize: config.AppConfig.AuthLogRetentionBatchSize,
+	}
+}
+
+var (
+	archiver Archiver
+	// running 防止同一进程内的执行重叠；跨进程重叠时归档文件按 ID 命名会被覆盖，删除也是幂等的
+	running sync.Mutex
+)
+
+// Init 根据 AUTH_LOG_ARCHIVE 创建归档目标，none 表示直接删除不归档
+func Init() error {
+	cfg := config.AppConfig
+	switch strings.ToLower(cfg.AuthLogArchive) {
+	case "", "none":
+		archiver = nil
+	case "local":
+		if cfg.AuthLogArchiveDir == "" {
+			return errors.New("AUTH_LOG_ARCHIVE_DIR is required for local archiving")
+		}
+		archiver = &localArchiver{dir: cfg.AuthLogArchiveDir}
+	case "s3":
+		if cfg.AuthLogArchiveS3Endpoint == "" || cfg.AuthLogArchiveS3Bucket == "" ||
+			cfg.AuthLogArchiveS3AccessKey == "" || cfg.AuthLogArchiveS3SecretKey == "" {
+			return errors.New("AUTH_LOG_ARCHIVE_S3_ENDPOINT, _BUCKET, _ACCESS_KEY and _SECRET_KEY are required for S3 archiving")
+		}
+		endpoint, err := url.Parse(cfg.AuthLogArchiveS3Endpoint)
+		if err != nil || endpoint.Host == "" {
+			return fmt.Errorf("invalid AUTH_LOG_ARCHIVE_S3_ENDPOINT %q", cfg.AuthLogArchiveS3Endpoint)
+		}
+		archiver = &s3Archiver{
+			endpoint:     endpoint,
+			region:       cfg.AuthLogArchiveS3Region,
+			bucket:       cfg.AuthLogArchiveS3Bucket,
+			prefix:       cfg.AuthLogArchiveS3Prefix,
+			accessKey:    cfg.AuthLogArchiveS3AccessKey,
+			secretKey:    cfg.AuthLogArchiveS3SecretKey,
+			sessionToken: cfg.AuthLogArchiveS3SessionToken,
+			pathStyle:    cfg.AuthLogArchiveS3PathStyle,
+			client:       &http.Client{},
+		}
+	default:
+		return fmt.Errorf("unsupported AUTH_LOG_ARCHIVE %q, expected none, local or s3", cfg.AuthLogArchive)
+	}
+	return nil
+}
+
+// Run 执行一次清理并把结果写入 retention_runs。每批先归档成功再按 ID 删除，
+// 单条 DELETE 只涉及一批行，不会长时间锁表；中途失败时已删除的批次都已归档
+func Run(ctx context.Context, policy Policy, trigger string) (*models.RetentionRun, error) {
+	if !policy.Enabled() {
+		return nil, ErrDisabled
+	}
+	if !running.TryLock() {
+		return nil, ErrInProgress
+	}
+	defer running.Unlock()
+
+	run := &models.RetentionRun{
+		Trigger:   trigger,
+		Status:    models.RetentionRunning,
+		StartedAt: time.Now(),
+	}
+	if err := database.DAO.RetentionRun.Create(ctx, run); err != nil {
+		return nil, err
+	}
+
+	err := purge(ctx, policy, run)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.RetentionCompleted
+	if err != nil {
+		run.Status = models.RetentionFailed
+		run.Error = err.Error()
+		if len(run.Error) > 1024 {
+			run.Error = run.Error[:1024]
+		}
+	}
+	// ctx 可能已因关闭而取消，仍需记录本次结果
+	if updateErr := database.DAO.RetentionRun.Update(context.Background(), run); updateErr != nil {
+		log.Printf("Failed to record retention run %d: %v", run.ID, updateErr)
+	}
+
+	if run.Deleted > 0 || err != nil {
+		log.Printf("Auth log retention: %d archived in %d files, %d deleted", run.Archived, run.Files, run.Deleted)
+	}
+	return run, err
+}
+
+func purge(ctx context.Context, policy Policy, run *models.RetentionRun) error {
+	var before time.Time
+	if policy.MaxAge > 0 {
+		before = run.StartedAt.Add(-policy.MaxAge)
+	}
+
+	// 行数上限在开始时确定边界，执行期间新写入的日志不影响本次清理范围
+	var throughID uint
+	if policy.MaxRows > 0 {
+		var err error
+		if throughID, err = database.DAO.AuthLog.RowLimitBoundary(ctx, policy.MaxRows); err != nil {
+			return err
+		}
+	}
+	if before.IsZero() && throughID == 0 {
+		return nil
+	}
+
+	// 尚未计入统计汇总的日志暂不删除
+	watermark, err := database.DAO.AuthStats.Watermark(ctx)
+	if err != nil {
+		return err
+	}
+
+	batchSize := policy.BatchSize
+	if batchSize <= 0 {
+		batchSize = 5000
+	}
+
+	for {
+		logs, err := database.DAO.AuthLog.ListExpired(ctx, before, throughID, watermark, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		if archiver != nil {
+			data, err := encodeBatch(logs)
+			if err != nil {
+				return err
+			}
+			if err := archiver.Put(ctx, archiveName(logs), data); err != nil {
+				return fmt.Errorf("archive to %s: %w", archiver, err)
+			}
+			run.Archived += int64(len(logs))
+			run.Files++
+		}
+
+		ids := make([]uint, len(logs))
+		for i := range logs {
+			ids[i] = logs[i].ID
+		}
+		deleted, err := database.DAO.AuthLog.DeleteByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		run.Deleted += deleted
+
+		if len(logs) < batchSize {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(batchPause):
+		}
+	}
+}
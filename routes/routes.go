@@ -4,16 +4,36 @@ import (
 	"github.com/cloudwego/hertz/pkg/app/server"
 	"github.com/hertz-contrib/cors"
 
+	"github.com/Gaojianli/raduis_mgnt/config"
 	"github.com/Gaojianli/raduis_mgnt/controllers"
 	"github.com/Gaojianli/raduis_mgnt/middleware"
 	"github.com/Gaojianli/raduis_mgnt/static"
 )
 
 func SetupRoutes(h *server.Hertz) {
-	h.Use(cors.Default())
+	h.Use(middleware.Tracing(), middleware.Metrics(), cors.Default())
+
+	healthController := &controllers.HealthController{}
+	h.GET("/healthz", healthController.Liveness)
+	h.GET("/readyz", healthController.Readiness)
+
+	// 配置了 METRICS_ADDR 时指标只在该地址提供
+	if config.AppConfig.MetricsAddr == "" && config.AppConfig.MetricsToken != "" {
+		h.GET("/metrics", middleware.RequireMetricsToken(), controllers.Metrics)
+	}
 
 	userController := &controllers.UserController{}
 	radiusController := &controllers.RadiusController{}
+	registrationController := &controllers.RegistrationController{}
+	sponsoredGuestController := &controllers.SponsoredGuestController{}
+	mfaController := &controllers.MFAController{}
+	loginMFAController := &controllers.LoginMFAController{}
+	passwordResetController := controllers.NewPasswordResetController()
+	oidcController := &controllers.OIDCController{}
+	scimController := &controllers.SCIMController{}
+	userImportController := &controllers.UserImportController{}
+	analyticsController := &controllers.AnalyticsController{}
+	settingsController := &controllers.SettingsController{}
 
 	api := h.Group("/api")
 	{
@@ -23,14 +43,50 @@ func SetupRoutes(h *server.Hertz) {
 			{
 				auth.POST("/login", middleware.JWTMiddleware.LoginHandler)
 				auth.POST("/refresh", middleware.JWTMiddleware.RefreshHandler)
+				auth.GET("/oidc/login", oidcController.Login)
+				auth.GET("/oidc/callback", oidcController.Callback)
+			}
+
+			// 忘记密码，按 IP 限流
+			passwordReset := auth.Group("/password")
+			passwordReset.Use(middleware.RateLimitByIP(middleware.NewRateLimiter(
+				config.AppConfig.PasswordResetRateLimit, config.AppConfig.PasswordResetRateWindow)))
+			{
+				passwordReset.POST("/forgot", passwordResetController.ForgotPassword)
+				passwordReset.POST("/reset", passwordResetController.ResetPassword)
 			}
 
+			// 登录第二步，使用登录接口返回的中间令牌
+			loginMFA := auth.Group("/mfa")
+			loginMFA.Use(middleware.RequireMFAPending())
+			{
+				loginMFA.POST("/verify", loginMFAController.VerifyCode)
+				loginMFA.POST("/webauthn/begin", loginMFAController.BeginWebAuthn)
+				loginMFA.POST("/webauthn/finish", loginMFAController.FinishWebAuthn)
+			}
+
+			v1.POST("/register", registrationController.Register)
+			v1.POST("/sponsored-guests/requests", sponsoredGuestController.SubmitRequest)
+
 			user := v1.Group("/user")
 			user.Use(middleware.JWTMiddleware.MiddlewareFunc())
 			{
 				user.GET("/profile", userController.GetProfile)
 				user.PUT("/change-password", userController.ChangePassword)
 				user.GET("/stats", userController.GetStats)
+				user.GET("/sponsored-guests", sponsoredGuestController.ListRequests)
+				user.PUT("/sponsored-guests/:id/approve", sponsoredGuestController.ApproveRequest)
+				user.PUT("/sponsored-guests/:id/reject", sponsoredGuestController.RejectRequest)
+				user.PUT("/sponsored-guests/:id/revoke", sponsoredGuestController.RevokeRequest)
+				user.GET("/mfa", mfaController.GetStatus)
+				user.POST("/mfa/enroll", mfaController.Enroll)
+				user.POST("/mfa/activate", mfaController.Activate)
+				user.POST("/mfa/backup-codes", mfaController.RegenerateBackupCodes)
+				user.DELETE("/mfa", mfaController.Disable)
+				user.GET("/mfa/webauthn", mfaController.ListWebAuthnCredentials)
+				user.POST("/mfa/webauthn/register/begin", mfaController.BeginWebAuthnRegistration)
+				user.POST("/mfa/webauthn/register/finish", mfaController.FinishWebAuthnRegistration)
+				user.DELETE("/mfa/webauthn/:id", mfaController.DeleteWebAuthnCredential)
 			}
 
 			admin := v1.Group("/admin")
@@ -38,11 +94,26 @@ func SetupRoutes(h *server.Hertz) {
 			{
 				admin.GET("/users", userController.GetUsers)
 				admin.POST("/users", userController.AdminCreateUser)
+				admin.POST("/users/import", userImportController.Import)
+				admin.GET("/users/import/jobs/:id", userImportController.GetImportJob)
+				admin.GET("/users/export", userImportController.Export)
 				admin.PUT("/users/:id/password", userController.AdminChangePassword)
 				admin.PUT("/users/:id/ban", userController.AdminToggleBanUser)
 				admin.DELETE("/users/:id", userController.AdminDeleteUser)
+				admin.DELETE("/users/:id/mfa", mfaController.AdminResetMFA)
+				admin.PUT("/users/:id/mfa/required", mfaController.AdminSetMFARequired)
 				admin.GET("/auth-logs", userController.GetAuthLogs)
+				admin.GET("/auth-logs/export", userController.ExportAuthLogs)
 				admin.GET("/stats", userController.GetAdminStats)
+				admin.GET("/analytics", analyticsController.Get)
+				admin.GET("/registrations", registrationController.GetPendingRegistrations)
+				admin.PUT("/registrations/:id/approve", registrationController.ApproveRegistration)
+				admin.PUT("/registrations/:id/reject", registrationController.RejectRegistration)
+				admin.GET("/settings", settingsController.List)
+				admin.GET("/settings/:key", settingsController.Get)
+				admin.PUT("/settings/:key", settingsController.Update)
+				admin.DELETE("/settings/:key", settingsController.Reset)
+				admin.GET("/settings/:key/history", settingsController.History)
 			}
 
 			radius := v1.Group("/radius")
@@ -52,6 +123,36 @@ func SetupRoutes(h *server.Hertz) {
 				radius.POST("/accounting", radiusController.Accounting)
 			}
 		}
+
+		// v2 只包含与 v1 不兼容的接口，其余接口继续使用 v1
+		v2 := api.Group("/v2")
+		{
+			admin := v2.Group("/admin")
+			admin.Use(middleware.JWTMiddleware.MiddlewareFunc(), middleware.RequireAdmin())
+			{
+				admin.GET("/auth-logs", userController.ListAuthLogsV2)
+			}
+		}
+	}
+
+	// SCIM 2.0，供 HR 系统或 IdP 同步用户与组
+	scimV2 := h.Group("/scim/v2")
+	scimV2.Use(middleware.RequireSCIMToken())
+	{
+		scimV2.GET("/ServiceProviderConfig", scimController.ServiceProviderConfig)
+		scimV2.GET("/ResourceTypes", scimController.ResourceTypes)
+		scimV2.GET("/Users", scimController.ListUsers)
+		scimV2.POST("/Users", scimController.CreateUser)
+		scimV2.GET("/Users/:id", scimController.GetUser)
+		scimV2.PUT("/Users/:id", scimController.ReplaceUser)
+		scimV2.PATCH("/Users/:id", scimController.PatchUser)
+		scimV2.DELETE("/Users/:id", scimController.DeleteUser)
+		scimV2.GET("/Groups", scimController.ListGroups)
+		scimV2.POST("/Groups", scimController.CreateGroup)
+		scimV2.GET("/Groups/:id", scimController.GetGroup)
+		scimV2.PUT("/Groups/:id", scimController.ReplaceGroup)
+		scimV2.PATCH("/Groups/:id", scimController.PatchGroup)
+		scimV2.DELETE("/Groups/:id", scimController.DeleteGroup)
 	}
 
 	// 设置静态文件服务 (放在所有API路由之后)
@@ -0,0 +1,355 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Gaojianli/raduis_mgnt/dao"
+)
+
+// Filter 解析后的过滤表达式（RFC 7644 3.4.2.2）
+type Filter interface {
+	filter()
+}
+
+// Comparison attr op value，pr 运算符没有 Value
+type Comparison struct {
+	Attr  string
+	Op    string
+	Value interface{}
+}
+
+// Logical and / or
+type Logical struct {
+	Op          string
+	Left, Right Filter
+}
+
+type Not struct {
+	Inner Filter
+}
+
+func (Comparison) filter() {}
+func (Logical) filter()    {}
+func (Not) filter()        {}
+
+var comparisonOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenEOF
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		ch := rune(input[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokenLParen})
+			i++
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokenRParen})
+			i++
+		case ch == '"':
+			// 按 JSON 字符串规则处理转义
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, InvalidFilter("unterminated string in filter")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &value); err != nil {
+				return nil, InvalidFilter("invalid string literal %s", input[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value})
+			i = end + 1
+		case ch == '[' || ch == ']':
+			return nil, InvalidFilter("complex attribute filters are not supported")
+		default:
+			end := i
+			for end < len(input) && !unicode.IsSpace(rune(input[end])) && !strings.ContainsRune("()\"[]", rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: input[i:end]})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenWord && strings.EqualFold(t.value, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// ParseFilter 解析过滤表达式，优先级 not > and > or
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, InvalidFilter("unexpected %q in filter", p.peek().value)
+	}
+	return f, nil
+}
+
+func (p *parser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = Logical{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Filter, error) {
+	if p.keyword("not") {
+		if p.peek().kind != tokenLParen {
+			return nil, InvalidFilter("expected ( after not")
+		}
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Inner: inner}, nil
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, InvalidFilter("missing ) in filter")
+		}
+		return inner, nil
+	}
+
+	attr := p.next()
+	if attr.kind != tokenWord {
+		return nil, InvalidFilter("expected attribute name in filter")
+	}
+	op := p.next()
+	if op.kind != tokenWord || !comparisonOps[strings.ToLower(op.value)] {
+		return nil, InvalidFilter("unknown operator %q", op.value)
+	}
+	cmp := Comparison{Attr: attr.value, Op: strings.ToLower(op.value)}
+	if cmp.Op == "pr" {
+		return cmp, nil
+	}
+
+	value := p.next()
+	switch value.kind {
+	case tokenString:
+		cmp.Value = value.value
+	case tokenWord:
+		// 非字符串字面量：true、false、null 与数字
+		var literal interface{}
+		if err := json.Unmarshal([]byte(value.value), &literal); err != nil {
+			return nil, InvalidFilter("invalid value %q", value.value)
+		}
+		cmp.Value = literal
+	default:
+		return nil, InvalidFilter("missing value for %s %s", attr.value, op.value)
+	}
+	return cmp, nil
+}
+
+// ColumnType 决定比较值的转换方式
+type ColumnType int
+
+const (
+	ColumnString ColumnType = iota
+	ColumnBool
+	ColumnInt
+	ColumnTime
+)
+
+// Column 可过滤属性对应的数据库列；Negate 用于 active 这类与列含义相反的布尔属性
+type Column struct {
+	Name   string
+	Type   ColumnType
+	Negate bool
+}
+
+// ToSQL 将过滤表达式转换为参数化的 WHERE 子句，columns 的键为小写属性名
+func ToSQL(f Filter, columns map[string]Column) (string, []interface{}, error) {
+	switch v := f.(type) {
+	case Logical:
+		left, leftArgs, err := ToSQL(v.Left, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := ToSQL(v.Right, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + left + " " + strings.ToUpper(v.Op) + " " + right + ")", append(leftArgs, rightArgs...), nil
+	case Not:
+		inner, args, err := ToSQL(v.Inner, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + inner + ")", args, nil
+	case Comparison:
+		return comparisonSQL(v, columns)
+	}
+	return "", nil, InvalidFilter("unsupported filter")
+}
+
+func comparisonSQL(cmp Comparison, columns map[string]Column) (string, []interface{}, error) {
+	column, ok := columns[normalizeAttr(cmp.Attr)]
+	if !ok {
+		return "", nil, InvalidFilter("filtering on %q is not supported", cmp.Attr)
+	}
+	name := column.Name
+
+	if cmp.Op == "pr" {
+		if column.Type == ColumnString {
+			return "(" + name + " IS NOT NULL AND " + name + " <> '')", nil, nil
+		}
+		return name + " IS NOT NULL", nil, nil
+	}
+
+	value, err := columnValue(cmp, column)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch cmp.Op {
+	case "eq":
+		return name + " = ?", []interface{}{value}, nil
+	case "ne":
+		return name + " <> ?", []interface{}{value}, nil
+	case "gt", "ge", "lt", "le":
+		if column.Type == ColumnBool {
+			return "", nil, InvalidFilter("operator %s is not valid for boolean attribute %s", cmp.Op, cmp.Attr)
+		}
+		sqlOp := map[string]string{"gt": ">", "ge": ">=", "lt": "<", "le": "<="}[cmp.Op]
+		return name + " " + sqlOp + " ?", []interface{}{value}, nil
+	}
+
+	// co / sw / ew 只适用于字符串
+	s, ok := value.(string)
+	if !ok || column.Type != ColumnString {
+		return "", nil, InvalidFilter("operator %s requires a string attribute", cmp.Op)
+	}
+	s = dao.EscapeLike(s)
+	switch cmp.Op {
+	case "co":
+		s = "%" + s + "%"
+	case "sw":
+		s = s + "%"
+	case "ew":
+		s = "%" + s
+	}
+	return "LOWER(" + name + ") LIKE LOWER(?) ESCAPE '!'", []interface{}{s}, nil
+}
+
+func columnValue(cmp Comparison, column Column) (interface{}, error) {
+	switch column.Type {
+	case ColumnBool:
+		b, ok := toBool(cmp.Value)
+		if !ok {
+			return nil, InvalidFilter("%s expects a boolean", cmp.Attr)
+		}
+		if column.Negate {
+			b = !b
+		}
+		return b, nil
+	case ColumnInt:
+		switch v := cmp.Value.(type) {
+		case float64:
+			return int64(v), nil
+		case string:
+			if id, ok := ParseID(v); ok {
+				return id, nil
+			}
+		}
+		return nil, InvalidFilter("%s expects a number", cmp.Attr)
+	case ColumnTime:
+		s, _ := cmp.Value.(string)
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, InvalidFilter("%s expects an RFC 3339 timestamp", cmp.Attr)
+		}
+		return t, nil
+	}
+	s, ok := cmp.Value.(string)
+	if !ok {
+		return nil, InvalidFilter("%s expects a string", cmp.Attr)
+	}
+	return s, nil
+}
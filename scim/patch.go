@@ -0,0 +1,357 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Path PATCH 路径，如 emails[type eq "work"].value 解析为 Attr=emails、Filter、Sub=value
+type Path struct {
+	Attr   string
+	Filter Filter
+	Sub    string
+}
+
+// normalizeAttr 去掉可选的 schema URN 前缀并转为小写
+func normalizeAttr(attr string) string {
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(attr) > len(schema) && strings.EqualFold(attr[:len(schema)+1], schema+":") {
+			attr = attr[len(schema)+1:]
+			break
+		}
+	}
+	return strings.ToLower(attr)
+}
+
+func ParsePath(path string) (Path, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return Path{}, nil
+	}
+
+	open := strings.IndexByte(path, '[')
+	if open < 0 {
+		attr, sub, _ := strings.Cut(normalizeAttr(path), ".")
+		return Path{Attr: attr, Sub: sub}, nil
+	}
+
+	end := strings.LastIndexByte(path, ']')
+	if end < open {
+		return Path{}, InvalidPath("invalid path %q", path)
+	}
+	f, err := ParseFilter(path[open+1 : end])
+	if err != nil {
+		return Path{}, InvalidPath("invalid path %q: %v", path, err)
+	}
+	rest := path[end+1:]
+	if rest != "" && !strings.HasPrefix(rest, ".") {
+		return Path{}, InvalidPath("invalid path %q", path)
+	}
+	return Path{
+		Attr:   normalizeAttr(path[:open]),
+		Filter: f,
+		Sub:    strings.ToLower(strings.TrimPrefix(rest, ".")),
+	}, nil
+}
+
+func validOp(op string) (string, error) {
+	op = strings.ToLower(op)
+	switch op {
+	case "add", "replace", "remove":
+		return op, nil
+	}
+	return "", InvalidValue("unsupported patch op %q", op)
+}
+
+// forEachAttribute 无 path 的 add/replace，value 为属性对象，逐个属性处理
+func forEachAttribute(op string, value json.RawMessage, apply func(op string, path Path, value json.RawMessage) error) error {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(value, &attrs); err != nil {
+		return InvalidValue("patch value without path must be an object")
+	}
+	for name, v := range attrs {
+		path, err := ParsePath(name)
+		if err != nil {
+			return err
+		}
+		if err := apply(op, path, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeValue(value json.RawMessage, target interface{}) error {
+	if len(value) == 0 {
+		return InvalidValue("patch value is required")
+	}
+	if err := json.Unmarshal(value, target); err != nil {
+		return InvalidValue("invalid patch value: %v", err)
+	}
+	return nil
+}
+
+// decodeString 兼容 Azure AD 将单值属性包装为 [{"value": ...}] 的写法
+func decodeString(value json.RawMessage) (string, error) {
+	var s string
+	if json.Unmarshal(value, &s) == nil {
+		return s, nil
+	}
+	var wrapped []struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(value, &wrapped) == nil && len(wrapped) == 1 {
+		return wrapped[0].Value, nil
+	}
+	return "", InvalidValue("expected a string value")
+}
+
+// ApplyUserPatch 在 SCIM 表示上依次执行 PATCH 操作，调用方再按 PUT 的逻辑写回
+func ApplyUserPatch(u *User, ops []PatchOperation) error {
+	for _, operation := range ops {
+		op, err := validOp(operation.Op)
+		if err != nil {
+			return err
+		}
+		path, err := ParsePath(operation.Path)
+		if err != nil {
+			return err
+		}
+		if path.Attr == "" {
+			if op == "remove" {
+				return errorf(http.StatusBadRequest, "noTarget", "remove requires a path")
+			}
+			err = forEachAttribute(op, operation.Value, func(op string, path Path, value json.RawMessage) error {
+				return applyUserAttribute(u, op, path, value)
+			})
+		} else {
+			err = applyUserAttribute(u, op, path, operation.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyUserAttribute(u *User, op string, path Path, value json.RawMessage) error {
+	switch path.Attr {
+	case "username":
+		if op == "remove" {
+			return errorf(http.StatusBadRequest, "mutability", "userName is required")
+		}
+		s, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		u.UserName = s
+	case "externalid":
+		if op == "remove" {
+			u.ExternalID = ""
+			return nil
+		}
+		s, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		u.ExternalID = s
+	case "active":
+		if op == "remove" {
+			u.Active = nil
+			return nil
+		}
+		var active Boolean
+		if err := decodeValue(value, &active); err != nil {
+			return err
+		}
+		u.Active = &active
+	case "password":
+		if op == "remove" {
+			return errorf(http.StatusBadRequest, "mutability", "password cannot be removed")
+		}
+		s, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		u.Password = s
+	case "emails":
+		return applyEmails(u, op, path, value)
+	}
+	// 其余属性本系统不保存，忽略
+	return nil
+}
+
+// applyEmails 本地账号只有一个邮箱，所有写法最终都落到主邮箱上
+func applyEmails(u *User, op string, path Path, value json.RawMessage) error {
+	if op == "remove" {
+		u.Emails = nil
+		return nil
+	}
+
+	if path.Sub == "value" {
+		s, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		u.Emails = []Email{{Value: s, Type: "work", Primary: true}}
+		return nil
+	}
+	if path.Sub != "" {
+		return nil
+	}
+
+	var emails []Email
+	if err := decodeValue(value, &emails); err != nil {
+		return err
+	}
+	candidate := User{Emails: emails}
+	if email := candidate.PrimaryEmail(); email != "" {
+		u.Emails = []Email{{Value: email, Type: "work", Primary: true}}
+	}
+	return nil
+}
+
+// ApplyGroupPatch 在 SCIM 表示上执行 PATCH，调用方比较前后成员差异后写回
+func ApplyGroupPatch(g *Group, ops []PatchOperation) error {
+	for _, operation := range ops {
+		op, err := validOp(operation.Op)
+		if err != nil {
+			return err
+		}
+		path, err := ParsePath(operation.Path)
+		if err != nil {
+			return err
+		}
+		if path.Attr == "" {
+			if op == "remove" {
+				return errorf(http.StatusBadRequest, "noTarget", "remove requires a path")
+			}
+			err = forEachAttribute(op, operation.Value, func(op string, path Path, value json.RawMessage) error {
+				return applyGroupAttribute(g, op, path, value)
+			})
+		} else {
+			err = applyGroupAttribute(g, op, path, operation.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyGroupAttribute(g *Group, op string, path Path, value json.RawMessage) error {
+	switch path.Attr {
+	case "displayname":
+		if op == "remove" {
+			return errorf(http.StatusBadRequest, "mutability", "displayName is required")
+		}
+		s, err := decodeString(value)
+		if err != nil {
+			return err
+		}
+		g.DisplayName = s
+	case "members":
+		return applyMembers(g, op, path, value)
+	}
+	return nil
+}
+
+func applyMembers(g *Group, op string, path Path, value json.RawMessage) error {
+	if op == "remove" {
+		var targets []Reference
+		if len(value) > 0 {
+			if err := decodeValue(value, &targets); err != nil {
+				return err
+			}
+		}
+		if path.Filter == nil && len(targets) == 0 {
+			g.Members = nil
+			return nil
+		}
+
+		remove := make(map[string]bool, len(targets))
+		for _, target := range targets {
+			remove[target.Value] = true
+		}
+		kept := g.Members[:0]
+		for _, member := range g.Members {
+			if remove[member.Value] || (path.Filter != nil && matchReference(path.Filter, member)) {
+				continue
+			}
+			kept = append(kept, member)
+		}
+		g.Members = kept
+		return nil
+	}
+
+	var members []Reference
+	if err := decodeValue(value, &members); err != nil {
+		return err
+	}
+	if op == "replace" {
+		g.Members = nil
+	}
+	existing := make(map[string]bool, len(g.Members))
+	for _, member := range g.Members {
+		existing[member.Value] = true
+	}
+	for _, member := range members {
+		if !existing[member.Value] {
+			existing[member.Value] = true
+			g.Members = append(g.Members, member)
+		}
+	}
+	return nil
+}
+
+// matchReference 在内存中对成员执行 PATCH 路径中的值过滤，如 members[value eq "42"]
+func matchReference(f Filter, ref Reference) bool {
+	switch v := f.(type) {
+	case Logical:
+		if v.Op == "and" {
+			return matchReference(v.Left, ref) && matchReference(v.Right, ref)
+		}
+		return matchReference(v.Left, ref) || matchReference(v.Right, ref)
+	case Not:
+		return !matchReference(v.Inner, ref)
+	case Comparison:
+		var actual string
+		switch normalizeAttr(v.Attr) {
+		case "value":
+			actual = ref.Value
+		case "display":
+			actual = ref.Display
+		default:
+			return false
+		}
+		if v.Op == "pr" {
+			return actual != ""
+		}
+		expected, _ := v.Value.(string)
+		switch v.Op {
+		case "eq":
+			return actual == expected
+		case "ne":
+			return actual != expected
+		case "co":
+			return strings.Contains(actual, expected)
+		case "sw":
+			return strings.HasPrefix(actual, expected)
+		case "ew":
+			return strings.HasSuffix(actual, expected)
+		}
+	}
+	return false
+}
@@ -0,0 +1,286 @@
+// Package scim 实现 SCIM 2.0（RFC 7643/7644）的资源表示、过滤表达式与 PATCH 操作，
+// HTTP 接口位于 controllers 包。
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+
+	ContentType = "application/scim+json"
+)
+
+// Error SCIM 错误响应，同时实现 error 接口
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{
+		"schemas": []string{SchemaError},
+		"status":  strconv.Itoa(e.Status),
+		"detail":  e.Detail,
+	}
+	if e.ScimType != "" {
+		body["scimType"] = e.ScimType
+	}
+	return json.Marshal(body)
+}
+
+func errorf(status int, scimType, format string, args ...interface{}) *Error {
+	return &Error{Status: status, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func InvalidFilter(format string, args ...interface{}) *Error {
+	return errorf(http.StatusBadRequest, "invalidFilter", format, args...)
+}
+
+func InvalidValue(format string, args ...interface{}) *Error {
+	return errorf(http.StatusBadRequest, "invalidValue", format, args...)
+}
+
+func InvalidPath(format string, args ...interface{}) *Error {
+	return errorf(http.StatusBadRequest, "invalidPath", format, args...)
+}
+
+func Uniqueness(format string, args ...interface{}) *Error {
+	return errorf(http.StatusConflict, "uniqueness", format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return errorf(http.StatusNotFound, "", format, args...)
+}
+
+// Boolean 兼容部分客户端（如 Azure AD）以 "True"/"False" 字符串发送的布尔值
+type Boolean bool
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, ok := toBool(value)
+	if !ok {
+		return InvalidValue("expected a boolean, got %s", data)
+	}
+	*b = Boolean(parsed)
+	return nil
+}
+
+func toBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseBool(strings.ToLower(v))
+		return parsed, err == nil
+	}
+	return false, false
+}
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Reference 组成员或用户所属组
+type Reference struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+// User 只映射本系统保存的属性，其余属性（name、title 等）读写时均被忽略
+type User struct {
+	Schemas    []string    `json:"schemas"`
+	ID         string      `json:"id,omitempty"`
+	ExternalID string      `json:"externalId,omitempty"`
+	UserName   string      `json:"userName"`
+	Emails     []Email     `json:"emails,omitempty"`
+	Active     *Boolean    `json:"active,omitempty"`
+	Password   string      `json:"password,omitempty"`
+	Groups     []Reference `json:"groups,omitempty"`
+	Meta       *Meta       `json:"meta,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []Reference `json:"members,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+func NewListResponse(resources interface{}, count int, total int64, startIndex int) ListResponse {
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: count,
+		Resources:    resources,
+	}
+}
+
+// PrimaryEmail 优先取 primary 标记的邮箱，否则取第一个
+func (u *User) PrimaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// IsActive 未提供 active 时视为启用
+func (u *User) IsActive() bool {
+	return u.Active == nil || bool(*u.Active)
+}
+
+// FormatID 资源 ID 使用数据库主键
+func FormatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func ParseID(id string) (uint, bool) {
+	parsed, err := strconv.ParseUint(id, 10, 32)
+	return uint(parsed), err == nil
+}
+
+// NewUser 将本地用户转换为 SCIM 表示，baseURL 形如 https://host/scim/v2
+func NewUser(u *models.User, baseURL string) User {
+	active := Boolean(!u.Banned)
+	resource := User{
+		Schemas:  []string{SchemaUser},
+		ID:       FormatID(u.ID),
+		UserName: u.Username,
+		Active:   &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+			Location:     baseURL + "/Users/" + FormatID(u.ID),
+		},
+	}
+	if u.ExternalID != nil {
+		resource.ExternalID = *u.ExternalID
+	}
+	if u.Email != "" {
+		resource.Emails = []Email{{Value: u.Email, Type: "work", Primary: true}}
+	}
+	for _, group := range u.Groups {
+		resource.Groups = append(resource.Groups, Reference{
+			Value:   FormatID(group.ID),
+			Ref:     baseURL + "/Groups/" + FormatID(group.ID),
+			Display: group.Name,
+		})
+	}
+	return resource
+}
+
+// NewGroup members 为 nil 时不输出成员（excludedAttributes=members）
+func NewGroup(g *models.Group, members []models.User, baseURL string) Group {
+	resource := Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          FormatID(g.ID),
+		DisplayName: g.Name,
+		Meta: &Meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+			Location:     baseURL + "/Groups/" + FormatID(g.ID),
+		},
+	}
+	for _, member := range members {
+		resource.Members = append(resource.Members, Reference{
+			Value:   FormatID(member.ID),
+			Ref:     baseURL + "/Users/" + FormatID(member.ID),
+			Display: member.Username,
+		})
+	}
+	return resource
+}
+
+// ServiceProviderConfig 声明支持的功能，客户端据此决定是否使用 PATCH 与过滤
+func ServiceProviderConfig(baseURL string, maxResults int) map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":          []string{SchemaServiceProviderConfig},
+		"documentationUri": "https://datatracker.ietf.org/doc/html/rfc7644",
+		"patch":            map[string]bool{"supported": true},
+		"bulk":             map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]interface{}{"supported": true, "maxResults": maxResults},
+		"changePassword":   map[string]bool{"supported": true},
+		"sort":             map[string]bool{"supported": false},
+		"etag":             map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Static bearer token configured with SCIM_TOKEN",
+			"primary":     true,
+		}},
+		"meta": map[string]string{
+			"resourceType": "ServiceProviderConfig",
+			"location":     baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+func ResourceTypes(baseURL string) []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"schemas":  []string{SchemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   SchemaUser,
+			"meta":     map[string]string{"resourceType": "ResourceType", "location": baseURL + "/ResourceTypes/User"},
+		},
+		{
+			"schemas":  []string{SchemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   SchemaGroup,
+			"meta":     map[string]string{"resourceType": "ResourceType", "location": baseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package settings
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+// RADIUS Reply-Message 属性最长 253 字节
+const maxReplyLength = 253
+
+var (
+	JWTLifetime = registerDuration("auth.jwt_lifetime",
+		"Lifetime of web login tokens issued from now on; tokens already issued keep their expiry. Defaults to JWT_LIFETIME",
+		func() time.Duration { return config.AppConfig.JWTLifetime },
+		checkJWTLifetime)
+
+	MaxPageSize = registerInt("pagination.max_limit",
+		"Largest limit accepted by page-based lists (users, auth logs v1, registrations, sponsored guests); larger values fall back to 20",
+		100, 20, 1000)
+	MaxAuthLogPageSize = registerInt("pagination.auth_logs_max_limit",
+		"Largest limit accepted by the cursor-based auth log API (v2); larger values fall back to 50",
+		500, 50, 5000)
+
+	ReplyInvalidRequest = registerReply("radius.reply.invalid_request",
+		"Sent when the RADIUS request cannot be parsed",
+		"Invalid request format")
+	ReplyUserNotFound = registerReply("radius.reply.user_not_found",
+		"Sent when authentication is rejected because the user does not exist or is disabled",
+		"Authentication failed: user not found or disabled")
+	ReplyInvalidPassword = registerReply("radius.reply.invalid_password",
+		"Sent when the password is wrong",
+		"Authentication failed: invalid password")
+	ReplyInternalError = registerReply("radius.reply.internal_error",
+		"Sent when authentication fails because of a server-side error",
+		"Authentication failed: internal error")
+	ReplyChallengeExpired = registerReply("radius.reply.challenge_expired",
+		"Sent when the Access-Challenge state is unknown or has expired",
+		"Authentication failed: challenge expired")
+	ReplyMFAEnrollmentRequired = registerReply("radius.reply.mfa_enrollment_required",
+		"Sent when two-factor authentication is required but the user has not enrolled",
+		"Authentication failed: two-factor enrollment required")
+	ReplyMFACodeRequired = registerReply("radius.reply.mfa_code_required",
+		"Sent when no verification code was appended to the password and MFA_RADIUS_CHALLENGE is off",
+		"Authentication failed: verification code required")
+	ReplyMFAPrompt = registerReply("radius.reply.mfa_prompt",
+		"Prompt sent with the Access-Challenge for the verification code",
+		"Enter your verification code")
+	ReplyInvalidMFACode = registerReply("radius.reply.invalid_mfa_code",
+		"Sent when the verification code is wrong",
+		"Authentication failed: invalid verification code")
+	ReplyPasswordExpired = registerReply("radius.reply.password_expired",
+		"Sent when the password has expired",
+		"Authentication failed: password expired, please change it in the web portal")
+	ReplyAuthorizeRejected = registerReply("radius.reply.authorize_rejected",
+		"Sent when authorization is rejected",
+		"User not found, disabled, or banned")
+)
+
+func registerReply(key, description, defaultValue string) StringSetting {
+	return registerString(key, description, defaultValue, maxReplyLength)
+}
+
+// checkJWTLifetime 与启动时对 JWT_LIFETIME 的校验一致：须长于登录第二步的中间令牌
+func checkJWTLifetime(d time.Duration) error {
+	if d < 5*time.Minute || d > 30*24*time.Hour {
+		return fmt.Errorf("must be between 5m and 720h")
+	}
+	if d <= config.AppConfig.MFALoginTimeout {
+		return fmt.Errorf("must be longer than MFA_LOGIN_TIMEOUT (%s)", config.AppConfig.MFALoginTimeout)
+	}
+	return nil
+}
@@ -0,0 +1,333 @@
+// Package settings 管理可在运行时通过管理接口修改的设置项。
+// 设置项在代码中注册类型、默认值与取值范围；修改写入数据库并记录历史，各实例在内存中缓存当前值，
+// 并定期重新读取设置表、比较校验和，以获知其他实例上的修改。
+package settings
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+type Kind string
+
+const (
+	KindInt      Kind = "int"
+	KindDuration Kind = "duration"
+	KindString   Kind = "string"
+)
+
+var ErrUnknownKey = errors.New("unknown setting")
+
+// InvalidValueError 新值无法解析或超出取值范围
+type InvalidValueError struct {
+	Key string
+	Err error
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value for %s: %v", e.Key, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// definition 一个设置项。parse 把字符串解析为类型化的值并校验取值范围，format 返回其规范的字符串形式，
+// 数据库中只保存规范形式
+type definition struct {
+	key          string
+	kind         Kind
+	description  string
+	defaultValue func() interface{}
+	parse        func(raw string) (interface{}, error)
+	format       func(value interface{}) string
+}
+
+var registry = map[string]*definition{}
+
+func register(def *definition) *definition {
+	if _, ok := registry[def.key]; ok {
+		panic("settings: duplicate key " + def.key)
+	}
+	registry[def.key] = def
+	return def
+}
+
+type IntSetting struct{ def *definition }
+
+func (s IntSetting) Get() int { return current(s.def).(int) }
+
+type DurationSetting struct{ def *definition }
+
+func (s DurationSetting) Get() time.Duration { return current(s.def).(time.Duration) }
+
+type StringSetting struct{ def *definition }
+
+func (s StringSetting) Get() string { return current(s.def).(string) }
+
+func registerInt(key, description string, defaultValue, min, max int) IntSetting {
+	return IntSetting{register(&definition{
+		key:          key,
+		kind:         KindInt,
+		description:  description,
+		defaultValue: func() interface{} { return defaultValue },
+		parse: func(raw string) (interface{}, error) {
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, errors.New("must be an integer")
+			}
+			if n < min || n > max {
+				return nil, fmt.Errorf("must be between %d and %d", min, max)
+			}
+			return n, nil
+		},
+		format: func(value interface{}) string { return strconv.Itoa(value.(int)) },
+	})}
+}
+
+// registerDuration 的默认值在读取时才计算，以便取自启动配置
+func registerDuration(key, description string, defaultValue func() time.Duration, check func(time.Duration) error) DurationSetting {
+	return DurationSetting{register(&definition{
+		key:          key,
+		kind:         KindDuration,
+		description:  description,
+		defaultValue: func() interface{} { return defaultValue() },
+		parse: func(raw string) (interface{}, error) {
+			d, err := time.ParseDuration(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, errors.New("must be a duration such as 30m or 12h")
+			}
+			if err := check(d); err != nil {
+				return nil, err
+			}
+			return d, nil
+		},
+		format: func(value interface{}) string { return value.(time.Duration).String() },
+	})}
+}
+
+func registerString(key, description, defaultValue string, maxLen int) StringSetting {
+	return StringSetting{register(&definition{
+		key:          key,
+		kind:         KindString,
+		description:  description,
+		defaultValue: func() interface{} { return defaultValue },
+		parse: func(raw string) (interface{}, error) {
+			if strings.TrimSpace(raw) == "" {
+				return nil, errors.New("must not be empty")
+			}
+			if len(raw) > maxLen {
+				return nil, fmt.Errorf("must be at most %d bytes", maxLen)
+			}
+			return raw, nil
+		},
+		format: func(value interface{}) string { return value.(string) },
+	})}
+}
+
+// display 接口中展示的值，时长使用字符串形式
+func (def *definition) display(value interface{}) interface{} {
+	if def.kind == KindDuration {
+		return def.format(value)
+	}
+	return value
+}
+
+// override 数据库中覆盖默认值的设置项
+type override struct {
+	value     interface{}
+	updatedBy string
+	updatedAt time.Time
+}
+
+var (
+	mu        sync.RWMutex
+	overrides = map[string]override{}
+	digest    [sha256.Size]byte
+	loaded    bool
+)
+
+// current 尚未加载（如命令行工具中）或未被覆盖时返回默认值
+func current(def *definition) interface{} {
+	mu.RLock()
+	o, ok := overrides[def.key]
+	mu.RUnlock()
+	if ok {
+		return o.value
+	}
+	return def.defaultValue()
+}
+
+// Init 从数据库加载设置项，须在数据库连接之后调用
+func Init() error {
+	if err := Refresh(context.Background()); err != nil {
+		return fmt.Errorf("load settings: %w", err)
+	}
+	return nil
+}
+
+// Refresh 读取全部设置项，校验和与已加载的不同时替换缓存，由定时任务调用。
+// 不比较修改历史的最大 ID：并发事务的 ID 顺序与提交顺序可能不同，晚提交的较小 ID 会被漏掉
+func Refresh(ctx context.Context) error {
+	rows, err := database.DAO.Setting.List(ctx)
+	if err != nil {
+		return err
+	}
+	sum := checksum(rows)
+	mu.RLock()
+	upToDate := loaded && sum == digest
+	mu.RUnlock()
+	if upToDate {
+		return nil
+	}
+	load(rows, sum)
+	return nil
+}
+
+// checksum 覆盖设置项的全部字段，任何修改或删除都会改变它
+func checksum(rows []models.Setting) [sha256.Size]byte {
+	h := sha256.New()
+	for _, row := range rows {
+		fmt.Fprintf(h, "%s\x00%s\x00%s\x00%d\x00", row.Key, row.Value, row.UpdatedBy, row.UpdatedAt.UnixNano())
+	}
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// load 数据库中无法识别的键（如回退到旧版本后）与无效的值只记录日志，对应设置项使用默认值
+func load(rows []models.Setting, sum [sha256.Size]byte) {
+	next := make(map[string]override, len(rows))
+	for _, row := range rows {
+		def, ok := registry[row.Key]
+		if !ok {
+			log.Printf("Ignoring unknown setting %s stored in the database", row.Key)
+			continue
+		}
+		value, err := def.parse(row.Value)
+		if err != nil {
+			log.Printf("Ignoring stored value of setting %s: %v", row.Key, err)
+			continue
+		}
+		next[row.Key] = override{value: value, updatedBy: row.UpdatedBy, updatedAt: row.UpdatedAt}
+	}
+
+	mu.Lock()
+	reloaded := loaded
+	overrides, digest, loaded = next, sum, true
+	mu.Unlock()
+	if reloaded {
+		log.Printf("Runtime settings reloaded")
+	}
+}
+
+// View 管理接口返回的设置项
+type View struct {
+	Key         string      `json:"key"`
+	Type        Kind        `json:"type"`
+	Description string      `json:"description"`
+	Value       interface{} `json:"value"`
+	Default     interface{} `json:"default"`
+	Overridden  bool        `json:"overridden"`
+	UpdatedBy   string      `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time  `json:"updated_at,omitempty"`
+}
+
+func view(def *definition) View {
+	v := View{
+		Key:         def.key,
+		Type:        def.kind,
+		Description: def.description,
+		Default:     def.display(def.defaultValue()),
+	}
+	mu.RLock()
+	o, ok := overrides[def.key]
+	mu.RUnlock()
+	if ok {
+		v.Value = def.display(o.value)
+		v.Overridden = true
+		v.UpdatedBy = o.updatedBy
+		v.UpdatedAt = &o.updatedAt
+	} else {
+		v.Value = v.Default
+	}
+	return v
+}
+
+// List 按键名排序返回全部设置项
+func List() []View {
+	views := make([]View, 0, len(registry))
+	for _, def := range registry {
+		views = append(views, view(def))
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Key < views[j].Key })
+	return views
+}
+
+func Get(key string) (View, error) {
+	def, ok := registry[key]
+	if !ok {
+		return View{}, ErrUnknownKey
+	}
+	return view(def), nil
+}
+
+// Set 校验并保存新值，本实例立即生效，其他实例在下次 Refresh 后生效。
+// 新值与当前保存的值相同时不做修改，返回 nil
+func Set(ctx context.Context, key, raw string, actorID uint, actorName string) (*models.SettingChange, error) {
+	def, ok := registry[key]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	value, err := def.parse(raw)
+	if err != nil {
+		return nil, &InvalidValueError{Key: key, Err: err}
+	}
+	canonical := def.format(value)
+	return apply(ctx, key, &canonical, actorID, actorName)
+}
+
+// Reset 删除保存的值，恢复为默认值
+func Reset(ctx context.Context, key string, actorID uint, actorName string) (*models.SettingChange, error) {
+	if _, ok := registry[key]; !ok {
+		return nil, ErrUnknownKey
+	}
+	return apply(ctx, key, nil, actorID, actorName)
+}
+
+func apply(ctx context.Context, key string, newValue *string, actorID uint, actorName string) (*models.SettingChange, error) {
+	change := &models.SettingChange{
+		Key:       key,
+		NewValue:  newValue,
+		ActorID:   &actorID,
+		ActorName: actorName,
+	}
+	changed, err := database.DAO.Setting.Apply(ctx, change)
+	if err != nil || !changed {
+		return nil, err
+	}
+	// 保存已成功，重新加载失败时由定时任务补上
+	if err := Refresh(ctx); err != nil {
+		log.Printf("Failed to reload settings after changing %s: %v", key, err)
+	}
+	return change, nil
+}
+
+// History 按时间倒序返回设置项的修改记录
+func History(ctx context.Context, key string, limit int) ([]models.SettingChange, error) {
+	if _, ok := registry[key]; !ok {
+		return nil, ErrUnknownKey
+	}
+	return database.DAO.Setting.History(ctx, key, limit)
+}
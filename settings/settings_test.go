@@ -0,0 +1,48 @@
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+// 其他实例的修改可能以较小的历史 ID 晚提交，Refresh 须按设置表的内容而不是最大 ID 判断
+func TestRefreshPicksUpChangesWithoutNewerHistory(t *testing.T) {
+	db := dbtest.Open(t)
+	ctx := context.Background()
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := MaxPageSize.Get(); got != 100 {
+		t.Fatalf("default = %d, want 100", got)
+	}
+
+	if _, err := Set(ctx, MaxPageSize.def.key, "200", 1, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if got := MaxPageSize.Get(); got != 200 {
+		t.Fatalf("after Set = %d, want 200", got)
+	}
+
+	if err := db.Model(&models.Setting{}).Where("setting_key = ?", MaxPageSize.def.key).Update("value", "300").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := MaxPageSize.Get(); got != 300 {
+		t.Fatalf("after concurrent update = %d, want 300", got)
+	}
+
+	if err := db.Where("setting_key = ?", MaxPageSize.def.key).Delete(&models.Setting{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := MaxPageSize.Get(); got != 100 {
+		t.Fatalf("after concurrent reset = %d, want 100", got)
+	}
+}
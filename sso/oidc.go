@@ -0,0 +1,279 @@
+// Package sso 实现管理界面的 OpenID Connect 单点登录（授权码 + PKCE），
+// 登录成功后按 ID Token 中的声明即时创建或更新本地影子账号。
+// 登录流程的 state、PKCE verifier 与 nonce 签名后保存在浏览器 Cookie 中，
+// 回调时与 state 参数比对，防止登录 CSRF，多实例部署时任一实例都能完成回调。
+package sso
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/Gaojianli/raduis_mgnt/authn"
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/models"
+)
+
+const (
+	// LoginTTL 登录流程需在该时间内完成，也是状态 Cookie 的有效期
+	LoginTTL = 10 * time.Minute
+	// LoginCookie 保存登录流程状态的 Cookie 名
+	LoginCookie = "oidc_login"
+)
+
+var (
+	ErrDisabled     = errors.New("oidc login is not configured")
+	ErrInvalidState = errors.New("oidc login state is invalid or expired")
+	ErrNotAllowed   = errors.New("account is not allowed to sign in")
+)
+
+// loginState 跳转到 IdP 前生成的流程状态，编码为 <base64 JSON>.<HMAC 签名> 存入 Cookie
+type loginState struct {
+	State     string `json:"state"`
+	Verifier  string `json:"verifier"`
+	Nonce     string `json:"nonce"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type Client struct {
+	issuer     string
+	oauth2     oauth2.Config
+	roles      authn.RoleMapping
+	httpClient *http.Client
+	providerMu sync.Mutex
+	provider   *oidc.Provider
+	verifier   *oidc.IDTokenVerifier
+}
+
+// Default 未配置 OIDC_ISSUER 时为 nil
+var Default *Client
+
+// Init 按配置创建客户端，IdP 的发现文档在首次登录时再获取，IdP 暂时不可用不影响启动
+func Init() error {
+	cfg := config.AppConfig
+	if cfg.OIDCIssuer == "" {
+		Default = nil
+		return nil
+	}
+	if cfg.OIDCClientID == "" {
+		return errors.New("OIDC_CLIENT_ID is required when OIDC_ISSUER is set")
+	}
+
+	mapping, err := authn.ParseGroupMapping("OIDC_GROUP_MAPPING", cfg.OIDCGroupMapping)
+	if err != nil {
+		return err
+	}
+
+	Default = &Client{
+		issuer: cfg.OIDCIssuer,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+			Scopes:       cfg.OIDCScopes,
+		},
+		roles: authn.RoleMapping{
+			AdminGroups:  cfg.OIDCAdminGroups,
+			GroupMapping: mapping,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	return nil
+}
+
+func (c *Client) ensureProvider() error {
+	c.providerMu.Lock()
+	defer c.providerMu.Unlock()
+
+	if c.provider != nil {
+		return nil
+	}
+
+	// 密钥集合会在之后按需刷新，因此不能使用请求的 ctx
+	ctx := oidc.ClientContext(context.Background(), c.httpClient)
+	provider, err := oidc.NewProvider(ctx, c.issuer)
+	if err != nil {
+		return fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	c.provider = provider
+	c.verifier = provider.Verifier(&oidc.Config{ClientID: c.oauth2.ClientID})
+	c.oauth2.Endpoint = provider.Endpoint()
+	return nil
+}
+
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// signingKey 由 JWT 密钥派生，与其他用途的签名互不通用
+func signingKey() []byte {
+	sum := sha256.Sum256([]byte("oidc-login:" + config.AppConfig.JWTSecret))
+	return sum[:]
+}
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func encodeLoginState(ls loginState) (string, error) {
+	data, err := json.Marshal(ls)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + sign(payload), nil
+}
+
+// decodeLoginState 校验 Cookie 的签名、有效期，并要求其中的 state 与回调参数一致
+func decodeLoginState(cookie, state string) (loginState, bool) {
+	var ls loginState
+	payload, signature, found := strings.Cut(cookie, ".")
+	if !found || !hmac.Equal([]byte(signature), []byte(sign(payload))) {
+		return ls, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(data, &ls) != nil {
+		return ls, false
+	}
+	if time.Now().Unix() > ls.ExpiresAt || ls.State == "" {
+		return ls, false
+	}
+	return ls, subtle.ConstantTimeCompare([]byte(ls.State), []byte(state)) == 1
+}
+
+// AuthCodeURL 生成跳转到 IdP 的授权地址，以及需写入 LoginCookie 的流程状态
+func (c *Client) AuthCodeURL() (authURL, cookie string, err error) {
+	if err := c.ensureProvider(); err != nil {
+		return "", "", err
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	cookie, err = encodeLoginState(loginState{
+		State:     state,
+		Verifier:  verifier,
+		Nonce:     nonce,
+		ExpiresAt: time.Now().Add(LoginTTL).Unix(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return c.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), cookie, nil
+}
+
+// Exchange 校验浏览器带回的 LoginCookie 与 state 参数，再用授权码换取并校验 ID Token，返回对应的本地用户
+func (c *Client) Exchange(ctx context.Context, cookie, state, code string) (*models.User, error) {
+	pending, ok := decodeLoginState(cookie, state)
+	if !ok {
+		return nil, ErrInvalidState
+	}
+	if err := c.ensureProvider(); err != nil {
+		return nil, err
+	}
+
+	ctx = oidc.ClientContext(ctx, c.httpClient)
+	token, err := c.oauth2.Exchange(ctx, code, oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc code exchange: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("oidc token response has no id_token")
+	}
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id_token: %w", err)
+	}
+	if idToken.Nonce != pending.Nonce {
+		return nil, errors.New("oidc id_token nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	cfg := config.AppConfig
+	username, _ := claims[cfg.OIDCUsernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("oidc id_token has no %q claim", cfg.OIDCUsernameClaim)
+	}
+	email, _ := claims[cfg.OIDCEmailClaim].(string)
+	groups := stringList(claims[cfg.OIDCGroupsClaim])
+
+	if len(cfg.OIDCAllowedGroups) > 0 && !containsAny(groups, cfg.OIDCAllowedGroups) {
+		return nil, ErrNotAllowed
+	}
+
+	existing, err := authn.LookupShadow(ctx, models.AuthSourceOIDC, username)
+	if errors.Is(err, authn.ErrUnknownUser) || errors.Is(err, authn.ErrInvalidCredentials) {
+		return nil, ErrNotAllowed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return authn.Provision(ctx, existing, authn.ExternalIdentity{
+		Source:   models.AuthSourceOIDC,
+		Username: username,
+		Email:    email,
+		Groups:   groups,
+	}, c.roles)
+}
+
+// stringList 组声明可能是字符串数组，也可能是单个字符串
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if strings.EqualFold(v, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
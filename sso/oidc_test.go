@@ -0,0 +1,280 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+	"github.com/Gaojianli/raduis_mgnt/database"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+)
+
+// fakeIssuer 基于 httptest 的最小 IdP：发现文档、JWKS 与令牌端点，令牌端点校验 PKCE
+type fakeIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]issuedCode
+	claims jwt.MapClaims
+}
+
+// issuedCode 授权码对应的 PKCE challenge 与 nonce
+type issuedCode struct {
+	challenge string
+	nonce     string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIssuer{key: key, codes: make(map[string]issuedCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/authorize",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize 模拟用户在 IdP 登录后带着授权码跳转回来
+func (idp *fakeIssuer) authorize(t *testing.T, authURL string, claims jwt.MapClaims) (state, code string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("authorization URL has no PKCE challenge: %s", authURL)
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code = "code-" + q.Get("state")
+	idp.codes[code] = issuedCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	idp.claims = claims
+	return q.Get("state"), code
+}
+
+func (idp *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	idp.mu.Lock()
+	issued, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	claims := idp.claims
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != issued.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idClaims := jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"aud":   "radius-admin",
+		"sub":   "subject-1",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": issued.nonce,
+	}
+	for k, v := range claims {
+		idClaims[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, idClaims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func newTestClient(t *testing.T, idp *fakeIssuer, allowedGroups []string) *Client {
+	t.Helper()
+	config.AppConfig = &config.Config{
+		JWTSecret:         "test-secret-with-at-least-32-bytes!",
+		OIDCIssuer:        idp.server.URL,
+		OIDCClientID:      "radius-admin",
+		OIDCClientSecret:  "secret",
+		OIDCRedirectURL:   "http://localhost/api/v1/auth/oidc/callback",
+		OIDCScopes:        []string{"openid", "profile", "email", "groups"},
+		OIDCUsernameClaim: "preferred_username",
+		OIDCEmailClaim:    "email",
+		OIDCGroupsClaim:   "groups",
+		OIDCAdminGroups:   []string{"admins"},
+		OIDCAllowedGroups: allowedGroups,
+	}
+	if err := Init(); err != nil {
+		t.Fatal(err)
+	}
+	return Default
+}
+
+func TestExchangeProvisionsUser(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIssuer(t)
+	client := newTestClient(t, idp, nil)
+
+	authURL, cookie, err := client.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "carol",
+		"email":              "carol@example.com",
+		"groups":             []string{"staff", "admins"},
+	})
+
+	user, err := client.Exchange(context.Background(), cookie, state, code)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if user.Username != "carol" || user.Email != "carol@example.com" || !user.IsAdmin {
+		t.Fatalf("user = %+v, want admin carol@example.com", user)
+	}
+
+	// 授权码只能使用一次
+	if _, err := client.Exchange(context.Background(), cookie, state, code); err == nil {
+		t.Fatal("replayed authorization code was accepted")
+	}
+}
+
+func TestExchangeRequiresLoginCookieOfSameBrowser(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIssuer(t)
+	client := newTestClient(t, idp, nil)
+	claims := jwt.MapClaims{"preferred_username": "carol"}
+
+	// 攻击者自己发起登录拿到授权码，再诱导受害者浏览器访问回调
+	attackerURL, _, err := client.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, attackerURL, claims)
+	_, victimCookie, err := client.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, _, _ := strings.Cut(victimCookie, ".")
+	for name, cookie := range map[string]string{
+		"missing":        "",
+		"other browser":  victimCookie,
+		"forged":         payload + "." + base64.RawURLEncoding.EncodeToString([]byte("signature")),
+		"unsigned":       payload,
+		"wrong key":      signedWith(t, "another-secret", loginState{State: state, ExpiresAt: time.Now().Add(time.Minute).Unix()}),
+		"expired cookie": signedWith(t, config.AppConfig.JWTSecret, loginState{State: state, ExpiresAt: time.Now().Add(-time.Minute).Unix()}),
+	} {
+		if _, err := client.Exchange(context.Background(), cookie, state, code); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("%s: Exchange error = %v, want %v", name, err, ErrInvalidState)
+		}
+	}
+}
+
+// signedWith 用指定的 JWT 密钥签发状态 Cookie
+func signedWith(t *testing.T, secret string, ls loginState) string {
+	t.Helper()
+	saved := config.AppConfig.JWTSecret
+	defer func() { config.AppConfig.JWTSecret = saved }()
+	config.AppConfig.JWTSecret = secret
+	cookie, err := encodeLoginState(ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cookie
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIssuer(t)
+	client := newTestClient(t, idp, nil)
+
+	authURL, cookie, err := client.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "carol",
+		"nonce":              "forged",
+	})
+
+	if _, err := client.Exchange(context.Background(), cookie, state, code); err == nil || !strings.Contains(err.Error(), "nonce mismatch") {
+		t.Fatalf("Exchange error = %v, want a nonce mismatch", err)
+	}
+}
+
+func TestExchangeRejectsUserOutsideAllowedGroups(t *testing.T) {
+	dbtest.Open(t)
+	idp := newFakeIssuer(t)
+	client := newTestClient(t, idp, []string{"staff"})
+
+	authURL, cookie, err := client.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, code := idp.authorize(t, authURL, jwt.MapClaims{
+		"preferred_username": "mallory",
+		"groups":             "contractors",
+	})
+
+	if _, err := client.Exchange(context.Background(), cookie, state, code); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("Exchange error = %v, want %v", err, ErrNotAllowed)
+	}
+	if _, err := database.DAO.User.GetByUsername(context.Background(), "mallory"); err == nil {
+		t.Fatal("a user outside OIDC_ALLOWED_GROUPS was provisioned")
+	}
+}
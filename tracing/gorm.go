@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	gormSpanKey  = "tracing:span"
+	daoPackage   = "github.com/Gaojianli/raduis_mgnt/dao."
+	maxStatement = 2048
+)
+
+// GormPlugin 为每条 SQL 创建 span，以发起查询的 DAO 方法命名（如 userDAO.GetByUsername），
+// 事务中的多条 SQL 各自成为一个 span
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, hook := range []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"select", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	} {
+		if err := hook.before("tracing:before_"+hook.operation, p.before(hook.operation)); err != nil {
+			return err
+		}
+		if err := hook.after("tracing:after_"+hook.operation, p.after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (GormPlugin) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			// 启动任务等没有上游 span 的查询不单独成链，避免产生大量孤立的 trace
+			return
+		}
+		name := daoCaller()
+		if name == "" {
+			name = "gorm." + operation
+		}
+		_, span := Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("db.system", db.Dialector.Name()),
+				attribute.String("db.operation.name", operation),
+			),
+		)
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func (GormPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	statement := db.Statement.SQL.String()
+	if len(statement) > maxStatement {
+		statement = statement[:maxStatement]
+	}
+	span.SetAttributes(
+		attribute.String("db.collection.name", db.Statement.Table),
+		attribute.String("db.query.text", statement),
+		attribute.Int64("db.response.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && db.Error != gorm.ErrRecordNotFound {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
+
+// daoCaller 从调用栈中找到最近的 DAO 方法，返回如 userDAO.GetByUsername；不是由 DAO 发起时返回空
+func daoCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if name, ok := strings.CutPrefix(frame.Function, daoPackage); ok {
+			// dao.(*userDAOImpl).GetByUsername.func1 -> userDAO.GetByUsername
+			name = strings.NewReplacer("(*", "", "Impl)", "").Replace(name)
+			if parts := strings.SplitN(name, ".", 3); len(parts) >= 2 {
+				return parts[0] + "." + parts[1]
+			}
+			return name
+		}
+		if !more {
+			return ""
+		}
+	}
+}
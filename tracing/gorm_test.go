@@ -0,0 +1,101 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/Gaojianli/raduis_mgnt/dao"
+	"github.com/Gaojianli/raduis_mgnt/database/dbtest"
+	"github.com/Gaojianli/raduis_mgnt/models"
+	"github.com/Gaojianli/raduis_mgnt/tracing"
+)
+
+func setup(t *testing.T) (*tracetest.InMemoryExporter, *sdktrace.TracerProvider, dao.UserDAO) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.Install(exporter)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	// dbtest 经 database.Open 建立连接，已注册 tracing.GormPlugin
+	db := dbtest.Open(t)
+	return exporter, provider, dao.NewUserDAO(db)
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	out := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
+
+func TestGormPluginExportsDAOSpans(t *testing.T) {
+	exporter, provider, users := setup(t)
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+	users.Create(ctx, &models.User{Username: "alice", Email: "alice@example.com"})
+	users.GetByUsername(ctx, "nobody")
+	parent.End()
+	provider.ForceFlush(context.Background())
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+	for _, name := range []string{"userDAO.Create", "userDAO.GetByUsername"} {
+		span, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span in %v", name, exporter.GetSpans().Snapshots())
+		}
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", name)
+		}
+		attrs := attributes(span)
+		if attrs["db.system"].AsString() != "sqlite" || attrs["db.collection.name"].AsString() != "users" {
+			t.Errorf("%s attributes = %v", name, span.Attributes)
+		}
+	}
+	// 查询不到记录不算错误
+	if status := spans["userDAO.GetByUsername"].Status.Code; status == codes.Error {
+		t.Errorf("record not found was reported as a span error")
+	}
+}
+
+func TestGormPluginRecordsErrors(t *testing.T) {
+	exporter, provider, users := setup(t)
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+	users.Create(ctx, &models.User{Username: "alice", Email: "alice@example.com"})
+	err := users.Create(ctx, &models.User{Username: "alice", Email: "alice@example.com"})
+	parent.End()
+	provider.ForceFlush(context.Background())
+	if err == nil {
+		t.Fatal("duplicate username was accepted")
+	}
+
+	var failed int
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "userDAO.Create" && span.Status.Code == codes.Error {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Fatalf("%d failed userDAO.Create spans, want 1", failed)
+	}
+}
+
+func TestGormPluginSkipsQueriesWithoutParent(t *testing.T) {
+	exporter, provider, users := setup(t)
+
+	users.GetByUsername(context.Background(), "alice")
+	provider.ForceFlush(context.Background())
+
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("exported %d spans for a query without a parent span", len(spans))
+	}
+}
@@ -0,0 +1,82 @@
+// Package tracing 配置 OpenTelemetry 链路追踪：通过 OTLP/HTTP 导出，按 W3C Trace Context 从请求头继续上游链路。
+// 未配置 OTEL_EXPORTER_OTLP_ENDPOINT 时使用默认的空实现，埋点几乎没有开销。
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Gaojianli/raduis_mgnt/config"
+)
+
+const instrumentationName = "github.com/Gaojianli/raduis_mgnt"
+
+// Tracer 每次从全局 TracerProvider 获取，Install 之后创建的 span 即使用新的导出器
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Init 按配置注册 OTLP 导出器，返回的函数在退出前调用以导出剩余的 span
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	cfg := config.AppConfig
+	if cfg.OTelEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpoint, err := url.Parse(cfg.OTelEndpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_ENDPOINT %q, expected http(s)://host:port", cfg.OTelEndpoint)
+	}
+	// 与 OpenTelemetry 规范一致，基础地址后追加 /v1/traces
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/v1/traces"
+
+	headers := make(map[string]string)
+	for _, item := range cfg.OTelHeaders {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS entry %q, expected key=value", item)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(endpoint.String()),
+		otlptracehttp.WithHeaders(headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	provider := Install(exporter, sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.OTelSampleRatio))))
+	return provider.Shutdown, nil
+}
+
+// Install 以给定的导出器创建并注册全局 TracerProvider。测试中可传入 tracetest.NewInMemoryExporter()，
+// 调用返回值的 ForceFlush 后读取已结束的 span
+func Install(exporter sdktrace.SpanExporter, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	serviceName := "radius-manager"
+	if config.AppConfig != nil {
+		serviceName = config.AppConfig.OTelServiceName
+	}
+	res := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	}, opts...)
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	return provider
+}